@@ -0,0 +1,648 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+type cli struct {
+	project string
+	logger  *slog.Logger
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	clients map[string]storage.Storage
+}
+
+func newCLI(project string, stdin io.Reader, stdout io.Writer, stderr io.Writer) *cli {
+	return &cli{
+		project: project,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		clients: map[string]storage.Storage{},
+	}
+}
+
+// storageFor returns a (cached) client for the provider/account of loc
+func (c *cli) storageFor(loc location) (storage.Storage, error) {
+	key := string(loc.storageType) + "/" + loc.account
+	if s, ok := c.clients[key]; ok {
+		return s, nil
+	}
+
+	if loc.storageType == storage.TypeGCP && c.project == "" {
+		return nil, fmt.Errorf("GCP project must be provided with -project or $GOOGLE_CLOUD_PROJECT")
+	}
+
+	s, err := storage.New(storage.Options{
+		StorageType:          loc.storageType,
+		GCP_ProjectId:        c.project,
+		Azure_StorageAccount: loc.account,
+		Logger:               c.logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.clients[key] = s
+	return s, nil
+}
+
+// parse parses the command flags and returns the n expected locations
+func (c *cli) parse(flags *flag.FlagSet, args []string, n int) ([]location, error) {
+	flags.SetOutput(c.stderr)
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if flags.NArg() != n {
+		return nil, errUsage
+	}
+
+	locs := []location{}
+	for _, arg := range flags.Args() {
+		loc, err := parseLocation(arg)
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+func requireRemote(loc location, needBucket bool, needKey bool) error {
+	switch {
+	case loc.isLocal():
+		return fmt.Errorf("'%s' is not a bucket URL", loc)
+	case needBucket && loc.bucket == "":
+		return fmt.Errorf("'%s': bucket name must be provided", loc)
+	case needKey && loc.isPrefix():
+		return fmt.Errorf("'%s': object key must be provided", loc)
+	}
+	return nil
+}
+
+func (c *cli) mb(args []string) error {
+	flags := flag.NewFlagSet("mb", flag.ContinueOnError)
+	loc := flags.String("location", "", "bucket location/region")
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	bucket := locs[0]
+	if err := requireRemote(bucket, true, false); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(bucket)
+	if err != nil {
+		return err
+	}
+
+	opts := []storage.CreateBucketOption{}
+	if *loc != "" {
+		opts = append(opts, storage.WithLocation(*loc))
+	}
+	return s.CreateBucket(bucket.bucket, opts...)
+}
+
+func (c *cli) rb(args []string) error {
+	flags := flag.NewFlagSet("rb", flag.ContinueOnError)
+	force := flags.Bool("force", false, "delete all objects in the bucket first")
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	bucket := locs[0]
+	if err := requireRemote(bucket, true, false); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(bucket)
+	if err != nil {
+		return err
+	}
+
+	if !*force {
+		return s.DeleteBucket(bucket.bucket)
+	}
+
+	deleted := 0
+	err = s.DeleteBucket(bucket.bucket, storage.WithForce(), storage.WithDeleteProgress(func(n int) {
+		deleted = n
+	}))
+	fmt.Fprintf(c.stderr, "deleted %d objects\n", deleted)
+	return err
+}
+
+func (c *cli) ls(args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	long := flags.Bool("l", false, "show size and modification time")
+	dirs := flags.Bool("d", false, "list one level, with the prefixes below it as directories")
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	loc := locs[0]
+	if err := requireRemote(loc, false, false); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return err
+	}
+
+	if loc.bucket == "" {
+		buckets, err := s.ListBuckets()
+		if err != nil {
+			return err
+		}
+		for _, bucket := range buckets {
+			fmt.Fprintln(c.stdout, bucket)
+		}
+		return nil
+	}
+
+	opts := []storage.ListOption{storage.WithPrefix(loc.key)}
+	if *dirs {
+		opts = append(opts, storage.WithDelimiter("/"))
+	}
+	return s.WalkBucketContent(loc.bucket, func(object storage.ObjectInfo) error {
+		if object.IsPrefix && *long {
+			fmt.Fprintf(c.stdout, "%12s  %20s  %s\n", "DIR", "", object.Key)
+		} else if *long {
+			fmt.Fprintf(c.stdout, "%12d  %s  %s\n", object.Size, object.LastModified.Format(time.RFC3339), object.Key)
+		} else {
+			fmt.Fprintln(c.stdout, object.Key)
+		}
+		return nil
+	}, opts...)
+}
+
+func (c *cli) cp(args []string) error {
+	flags := flag.NewFlagSet("cp", flag.ContinueOnError)
+	storageClass := flags.String("storage-class", "", "STANDARD, INFREQUENT_ACCESS, ARCHIVE or DEEP_ARCHIVE")
+	contentType := flags.String("content-type", "", "content type, guessed from the extension by default")
+	showProgress := flags.Bool("progress", false, "show transfer progress")
+	kmsKey := flags.String("kms-key", "", "encrypt uploads with this KMS key (AWS key ARN/id, GCP key name, Azure encryption scope)")
+	locs, err := c.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+
+	opts := []storage.PutOption{}
+	if *kmsKey != "" {
+		opts = append(opts, storage.WithEncryption(storage.EncryptionOptions{KMSKey: *kmsKey}))
+	}
+	if *storageClass != "" {
+		opts = append(opts, storage.WithStorageClass(storage.StorageClass(*storageClass)))
+	}
+	if *contentType != "" {
+		opts = append(opts, storage.WithContentType(*contentType))
+	}
+
+	var progress storage.ProgressFunc
+	if *showProgress {
+		progress = c.printProgress
+	}
+	_, err = c.copy(locs[0], locs[1], opts, progress)
+	return err
+}
+
+func (c *cli) mv(args []string) error {
+	flags := flag.NewFlagSet("mv", flag.ContinueOnError)
+	locs, err := c.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	src := locs[0]
+	if src.isStd() {
+		return fmt.Errorf("can't move from stdin")
+	}
+
+	_, err = c.copy(src, locs[1], nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.remove(src)
+}
+
+func (c *cli) rm(args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "delete all objects with the URL key as prefix")
+	dryRun := flags.Bool("dry-run", false, "only list the objects that would be deleted")
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	loc := locs[0]
+	if err := requireRemote(loc, true, !*recursive); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return err
+	}
+
+	opts := []storage.DeleteMatchingOption{}
+	if *dryRun {
+		opts = append(opts, storage.WithDryRun())
+	}
+
+	var report *storage.DeleteReport
+	switch {
+	case *recursive:
+		report, err = storage.DeleteByPrefix(s, loc.bucket, loc.key, opts...)
+	case strings.ContainsAny(loc.key, `*?[\`):
+		report, err = storage.DeleteMatching(s, loc.bucket, loc.key, opts...)
+	case *dryRun:
+		_, err = s.StatObject(loc.bucket, loc.key)
+		report = &storage.DeleteReport{DryRun: true, Results: []storage.DeleteResult{{Key: loc.key}}}
+	default:
+		report, err = s.DeleteObjects(loc.bucket, []string{loc.key})
+	}
+
+	if report != nil {
+		for _, result := range report.Results {
+			object := loc
+			object.key = result.Key
+			switch {
+			case result.Err != nil:
+				fmt.Fprintf(c.stderr, "failed to delete %s: %v\n", object, result.Err)
+			case report.DryRun:
+				fmt.Fprintf(c.stdout, "would delete %s\n", object)
+			default:
+				fmt.Fprintf(c.stdout, "deleted %s\n", object)
+			}
+		}
+	}
+	return err
+}
+
+func (c *cli) cat(args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	reader, err := c.open(locs[0])
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(c.stdout, reader)
+	return err
+}
+
+func (c *cli) stat(args []string) error {
+	flags := flag.NewFlagSet("stat", flag.ContinueOnError)
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	loc := locs[0]
+	if err := requireRemote(loc, true, true); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.StatObject(loc.bucket, loc.key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "URL:           %s\n", loc)
+	fmt.Fprintf(c.stdout, "Size:          %d\n", info.Size)
+	fmt.Fprintf(c.stdout, "Last modified: %s\n", info.LastModified.Format(time.RFC3339))
+	fmt.Fprintf(c.stdout, "ETag:          %s\n", info.ETag)
+	fmt.Fprintf(c.stdout, "Content type:  %s\n", info.ContentType)
+	if info.ContentEncoding != "" {
+		fmt.Fprintf(c.stdout, "Encoding:      %s\n", info.ContentEncoding)
+	}
+	fmt.Fprintf(c.stdout, "Storage class: %s\n", info.StorageClass)
+	switch info.Encryption.Type {
+	case "":
+	case storage.EncryptionKMS:
+		fmt.Fprintf(c.stdout, "Encryption:    %s (%s)\n", info.Encryption.Type, info.Encryption.KMSKey)
+	default:
+		fmt.Fprintf(c.stdout, "Encryption:    %s\n", info.Encryption.Type)
+	}
+	for k, v := range info.Metadata {
+		fmt.Fprintf(c.stdout, "Metadata:      %s=%s\n", k, v)
+	}
+	return nil
+}
+
+func (c *cli) sync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the files/objects that would be copied")
+	showProgress := flags.Bool("progress", false, "show transfer progress")
+	locs, err := c.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	src, dst := locs[0], locs[1]
+	if src.isStd() || dst.isStd() {
+		return fmt.Errorf("can't sync with stdin/stdout")
+	}
+
+	srcFiles, err := c.listSizes(src)
+	if err != nil {
+		return err
+	}
+	dstFiles, err := c.listSizes(dst)
+	if err != nil {
+		return err
+	}
+
+	changed := map[string]int64{}
+	for rel, size := range srcFiles {
+		if dstSize, ok := dstFiles[rel]; !ok || dstSize != size {
+			changed[rel] = size
+		}
+	}
+
+	var tracker *storage.ProgressTracker
+	if *showProgress && !*dryRun {
+		tracker = storage.NewProgressTracker(c.printProgress)
+		for _, size := range changed {
+			tracker.Expect(1, size)
+		}
+	}
+
+	for rel := range changed {
+		from, to := asDir(src).child(rel), asDir(dst).child(rel)
+		if *dryRun {
+			fmt.Fprintf(c.stdout, "would copy %s -> %s\n", from, to)
+			continue
+		}
+		var progress storage.ProgressFunc
+		if tracker != nil {
+			progress = tracker.Transfer()
+		}
+		_, err := c.copy(from, to, nil, progress)
+		if tracker != nil {
+			tracker.ObjectDone()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *cli) presign(args []string) error {
+	flags := flag.NewFlagSet("presign", flag.ContinueOnError)
+	method := flags.String("method", "GET", "HTTP method: GET, PUT or DELETE")
+	expires := flags.Duration("expires", time.Hour, "validity of the URL")
+	locs, err := c.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	loc := locs[0]
+	if err := requireRemote(loc, true, true); err != nil {
+		return err
+	}
+
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return err
+	}
+
+	url, err := s.PresignURL(loc.bucket, loc.key, strings.ToUpper(*method), *expires)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout, url)
+	return nil
+}
+
+// open returns a reader for the content of a local file, stdin or an object
+func (c *cli) open(loc location, opts ...storage.GetOption) (io.ReadCloser, error) {
+	if loc.isStd() {
+		return io.NopCloser(c.stdin), nil
+	}
+	if loc.isLocal() {
+		return os.Open(loc.path)
+	}
+
+	if err := requireRemote(loc, true, true); err != nil {
+		return nil, err
+	}
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetObject(loc.bucket, loc.key, opts...)
+}
+
+// copy copies a single file/object and returns the final destination;
+// if dst is a directory/prefix, the source name is appended to it.
+// progress, if not nil, follows downloading a remote src, uploading to a remote dst or copying local files.
+func (c *cli) copy(src location, dst location, opts []storage.PutOption, progress storage.ProgressFunc) (location, error) {
+	name := path.Base(src.key)
+	if src.isLocal() {
+		name = filepath.Base(src.path)
+	}
+	if isDir(dst) {
+		if src.isStd() {
+			return dst, fmt.Errorf("destination '%s' must be a file or object name when copying from stdin", dst)
+		}
+		dst = dst.child(name)
+	}
+	// the destination would be truncated before the source is read, and a move would delete it
+	if src.sameAs(dst) {
+		return dst, fmt.Errorf("'%s' and '%s' are the same file or object", src, dst)
+	}
+
+	getOpts := []storage.GetOption{}
+	if progress != nil && !src.isLocal() {
+		getOpts = append(getOpts, storage.WithDownloadProgress(progress))
+	} else if progress != nil && !dst.isLocal() {
+		opts = append(opts, storage.WithUploadProgress(progress))
+	}
+
+	reader, err := c.open(src, getOpts...)
+	if err != nil {
+		return dst, err
+	}
+	defer reader.Close()
+	if progress != nil && src.isLocal() && dst.isLocal() {
+		reader = storage.NewProgressReadCloser(reader, -1, progress)
+	}
+
+	switch {
+	case dst.isStd():
+		_, err = io.Copy(c.stdout, reader)
+	case dst.isLocal():
+		err = writeFile(dst.path, reader)
+	default:
+		var s storage.Storage
+		s, err = c.storageFor(dst)
+		if err != nil {
+			return dst, err
+		}
+		if contentType := mime.TypeByExtension(path.Ext(dst.key)); contentType != "" {
+			// explicit content type in opts overrides the guess
+			opts = append([]storage.PutOption{storage.WithContentType(contentType)}, opts...)
+		}
+		err = s.PutObject(dst.bucket, dst.key, reader, opts...)
+	}
+	if err != nil {
+		return dst, err
+	}
+
+	if progress == nil {
+		// the progress line reports the copy otherwise
+		fmt.Fprintf(c.stderr, "copied %s -> %s\n", src, dst)
+	}
+	return dst, nil
+}
+
+func (c *cli) remove(loc location) error {
+	if loc.isLocal() {
+		return os.Remove(loc.path)
+	}
+
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return err
+	}
+	return s.DeleteObject(loc.bucket, []string{loc.key})
+}
+
+// printProgress shows the progress of a transfer on a single line of stderr, ending it when done
+func (c *cli) printProgress(p storage.Progress) {
+	line := formatBytes(p.Bytes)
+	if p.Total >= 0 {
+		line += " / " + formatBytes(p.Total)
+	}
+	line += fmt.Sprintf(" (%s/s", formatBytes(int64(p.Rate)))
+	if p.ETA >= 0 {
+		line += ", ETA " + p.ETA.Round(time.Second).String()
+	}
+	line += ")"
+	if p.Objects > 0 {
+		line = fmt.Sprintf("%d/%d objects, %s", p.ObjectsDone, p.Objects, line)
+	}
+
+	done := p.Total >= 0 && p.Bytes >= p.Total && p.ObjectsDone >= p.Objects
+	if done {
+		fmt.Fprintf(c.stderr, "\r%s\n", line)
+	} else {
+		fmt.Fprintf(c.stderr, "\r%s", line)
+	}
+}
+
+// formatBytes formats n bytes in binary units, as in 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// listSizes returns the sizes of all files/objects below a directory/prefix, by relative path
+func (c *cli) listSizes(loc location) (map[string]int64, error) {
+	sizes := map[string]int64{}
+
+	if loc.isLocal() {
+		err := filepath.WalkDir(loc.path, func(p string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) && p == loc.path {
+				return fs.SkipAll // nothing synced yet
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(loc.path, p)
+			if err != nil {
+				return err
+			}
+			sizes[filepath.ToSlash(rel)] = info.Size()
+			return nil
+		})
+		return sizes, err
+	}
+
+	if err := requireRemote(loc, true, false); err != nil {
+		return nil, err
+	}
+	s, err := c.storageFor(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := asDir(loc).key
+	err = s.WalkBucketContent(loc.bucket, func(object storage.ObjectInfo) error {
+		sizes[strings.TrimPrefix(object.Key, prefix)] = object.Size
+		return nil
+	}, storage.WithPrefix(prefix))
+	return sizes, err
+}
+
+// asDir treats a remote location as prefix ("dir" -> "dir/")
+func asDir(loc location) location {
+	if !loc.isLocal() && !loc.isPrefix() {
+		loc.key += "/"
+	}
+	return loc
+}
+
+func isDir(loc location) bool {
+	if !loc.isLocal() {
+		return loc.isPrefix()
+	}
+	if strings.HasSuffix(loc.path, "/") || strings.HasSuffix(loc.path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(loc.path)
+	return err == nil && info.IsDir()
+}
+
+func writeFile(name string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
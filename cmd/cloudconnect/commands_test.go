@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, strings.NewReader(stdin), stdout, stderr)
+	if code != 0 {
+		t.Logf("stderr: %s", stderr)
+	}
+	return stdout.String(), code
+}
+
+func TestLocalCopyAndCat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("some data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// copying into a directory keeps the file name
+	if _, code := runCLI(t, "", "cp", src, filepath.Join(dir, "copy")+"/"); code != 0 {
+		t.Fatalf("cp exit code %d", code)
+	}
+	out, code := runCLI(t, "", "cat", filepath.Join(dir, "copy", "src.txt"))
+	if code != 0 || out != "some data" {
+		t.Fatalf("cat = '%s' (exit code %d), want 'some data'", out, code)
+	}
+
+	if _, code := runCLI(t, "from stdin", "cp", "-", filepath.Join(dir, "stdin.txt")); code != 0 {
+		t.Fatalf("cp from stdin exit code %d", code)
+	}
+	out, _ = runCLI(t, "", "cat", filepath.Join(dir, "stdin.txt"))
+	if out != "from stdin" {
+		t.Fatalf("cat = '%s', want 'from stdin'", out)
+	}
+}
+
+func TestSameSourceAndDestination(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("some data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"cp", file, file},
+		{"mv", file, file},
+		{"mv", file, dir + "/"},
+		{"cp", file, filepath.Join(dir, ".", "a.txt")},
+	} {
+		if _, code := runCLI(t, "", args...); code == 0 {
+			t.Errorf("%v succeeded, want an error", args)
+		}
+		if data, err := os.ReadFile(file); err != nil || string(data) != "some data" {
+			t.Fatalf("after %v: file = '%s', %v, want it unchanged", args, data, err)
+		}
+	}
+}
+
+func TestLocalSync(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "dst")
+	os.MkdirAll(filepath.Join(src, "sub"), 0o755)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("bb"), 0o644)
+
+	out, code := runCLI(t, "", "sync", "-dry-run", src, dst)
+	if code != 0 || strings.Count(out, "would copy") != 2 {
+		t.Fatalf("sync -dry-run = '%s' (exit code %d), want 2 copies", out, code)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Fatalf("dry run created destination")
+	}
+
+	if _, code := runCLI(t, "", "sync", src, dst); code != 0 {
+		t.Fatalf("sync exit code %d", code)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	if err != nil || string(data) != "bb" {
+		t.Fatalf("synced file = '%s', %v", data, err)
+	}
+
+	out, _ = runCLI(t, "", "sync", "-dry-run", src, dst)
+	if out != "" {
+		t.Fatalf("second sync should copy nothing, got '%s'", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{{}, {"unknown"}, {"cp", "only-one-arg"}, {"ls", "-bad-flag", "s3://"}} {
+		if _, code := runCLI(t, "", args...); code != 2 {
+			t.Errorf("%v: exit code %d, want 2", args, code)
+		}
+	}
+	if _, code := runCLI(t, "", "stat", "/not/a/url"); code != 1 {
+		t.Errorf("stat of local path: exit code %d, want 1", code)
+	}
+}
+
+func TestSyncProgress(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "dst")
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(src, "b.txt"), []byte("bb"), 0o644)
+
+	stderr := &bytes.Buffer{}
+	if code := run([]string{"sync", "-progress", src, dst}, strings.NewReader(""), &bytes.Buffer{}, stderr); code != 0 {
+		t.Fatalf("sync -progress exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr.String(), "2/2 objects, 3 B / 3 B (") {
+		t.Errorf("stderr = %q, want final progress of 2 objects and 3 bytes", stderr)
+	}
+	if strings.Contains(stderr.String(), "copied") {
+		t.Errorf("stderr = %q, want progress instead of copied lines", stderr)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 40: "3.0 TiB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = '%s', want '%s'", n, got, want)
+		}
+	}
+}
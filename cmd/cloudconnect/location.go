@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// location is either a local path (or "-" for stdin/stdout), or an object/prefix in a bucket:
+//
+//	s3://bucket/key
+//	gs://bucket/key
+//	az://account/container/key
+type location struct {
+	storageType storage.StorageType // empty for local paths
+	account     string              // Azure storage account
+	bucket      string
+	key         string
+	path        string
+}
+
+func parseLocation(s string) (location, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return location{path: s}, nil
+	}
+
+	loc := location{}
+	switch scheme {
+	case "s3":
+		loc.storageType = storage.TypeS3
+	case "gs":
+		loc.storageType = storage.TypeGCP
+	case "az":
+		loc.storageType = storage.TypeAzure
+		loc.account, rest, _ = strings.Cut(rest, "/")
+		if loc.account == "" {
+			return loc, fmt.Errorf("'%s': storage account must be provided, as in az://account/container/key", s)
+		}
+	default:
+		return loc, fmt.Errorf("'%s': unsupported scheme '%s', use s3://, gs:// or az://", s, scheme)
+	}
+
+	loc.bucket, loc.key, _ = strings.Cut(rest, "/")
+	if loc.bucket == "" && loc.key != "" {
+		return loc, fmt.Errorf("'%s': bucket name must be provided", s)
+	}
+
+	return loc, nil
+}
+
+func (l location) isLocal() bool {
+	return l.storageType == ""
+}
+
+func (l location) isStd() bool {
+	return l.isLocal() && l.path == "-"
+}
+
+// isPrefix is true for locations naming a "directory" rather than a single object
+func (l location) isPrefix() bool {
+	return l.key == "" || strings.HasSuffix(l.key, "/")
+}
+
+// child returns the location of name, a '/' separated relative key, inside the (prefix) location l
+func (l location) child(name string) location {
+	if l.isLocal() {
+		l.path = filepath.Join(l.path, filepath.FromSlash(name))
+		return l
+	}
+	if l.key != "" && !strings.HasSuffix(l.key, "/") {
+		l.key += "/"
+	}
+	l.key += name
+	return l
+}
+
+// sameAs reports whether l and other name the same local file or object
+func (l location) sameAs(other location) bool {
+	if l.isLocal() != other.isLocal() || l.isStd() || other.isStd() {
+		return false
+	}
+	if !l.isLocal() {
+		return l.storageType == other.storageType && l.account == other.account && l.bucket == other.bucket && l.key == other.key
+	}
+
+	// links and different spellings of a path name the same file
+	info, err := os.Stat(l.path)
+	otherInfo, otherErr := os.Stat(other.path)
+	if err == nil && otherErr == nil {
+		return os.SameFile(info, otherInfo)
+	}
+	abs, err := filepath.Abs(l.path)
+	otherAbs, otherErr := filepath.Abs(other.path)
+	return err == nil && otherErr == nil && abs == otherAbs
+}
+
+func (l location) String() string {
+	switch l.storageType {
+	case storage.TypeS3:
+		return "s3://" + l.bucket + "/" + l.key
+	case storage.TypeGCP:
+		return "gs://" + l.bucket + "/" + l.key
+	case storage.TypeAzure:
+		return "az://" + l.account + "/" + l.bucket + "/" + l.key
+	default:
+		return l.path
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want location
+	}{
+		{"s3://bucket/dir/key.txt", location{storageType: storage.TypeS3, bucket: "bucket", key: "dir/key.txt"}},
+		{"s3://bucket", location{storageType: storage.TypeS3, bucket: "bucket"}},
+		{"s3://", location{storageType: storage.TypeS3}},
+		{"gs://bucket/key", location{storageType: storage.TypeGCP, bucket: "bucket", key: "key"}},
+		{"az://account/container/dir/", location{storageType: storage.TypeAzure, account: "account", bucket: "container", key: "dir/"}},
+		{"az://account", location{storageType: storage.TypeAzure, account: "account"}},
+		{"./local/file.txt", location{path: "./local/file.txt"}},
+		{"-", location{path: "-"}},
+	}
+
+	for _, test := range tests {
+		got, err := parseLocation(test.in)
+		if err != nil {
+			t.Errorf("parseLocation(%s): %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseLocation(%s) = %+v, want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseLocationErrors(t *testing.T) {
+	for _, in := range []string{"ftp://host/file", "az://", "s3:///key"} {
+		_, err := parseLocation(in)
+		if err == nil {
+			t.Errorf("parseLocation(%s) should fail", in)
+		}
+	}
+}
+
+func TestLocationChild(t *testing.T) {
+	loc, _ := parseLocation("s3://bucket/dir")
+	if got := loc.child("file.txt").String(); got != "s3://bucket/dir/file.txt" {
+		t.Errorf("child = %s, want s3://bucket/dir/file.txt", got)
+	}
+
+	loc, _ = parseLocation("gs://bucket")
+	if got := loc.child("file.txt").String(); got != "gs://bucket/file.txt" {
+		t.Errorf("child = %s, want gs://bucket/file.txt", got)
+	}
+
+	loc, _ = parseLocation("/tmp/dir")
+	if got, want := loc.child("a/file.txt").String(), filepath.Join("/tmp/dir", "a", "file.txt"); got != want {
+		t.Errorf("child = %s, want %s", got, want)
+	}
+}
+
+func TestLocationSameAs(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		same bool
+	}{
+		{"s3://bucket/key", "s3://bucket/key", true},
+		{"s3://bucket/key", "gs://bucket/key", false},
+		{"az://one/container/key", "az://two/container/key", false},
+		{"dir/file", "dir/./file", true},
+		{"-", "-", false},
+	} {
+		a, _ := parseLocation(test.a)
+		b, _ := parseLocation(test.b)
+		if same := a.sameAs(b); same != test.same {
+			t.Errorf("%s sameAs %s = %t, want %t", test.a, test.b, same, test.same)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Command cloudconnect works with buckets and objects of any supported cloud provider.
+//
+// Objects are addressed with URLs:
+//
+//	s3://bucket/key
+//	gs://bucket/key
+//	az://account/container/key
+//
+// Anything else is a local path, or "-" for stdin/stdout.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	_ "github.com/pbreedt/cloud-connect/storage/all"
+)
+
+const usage = `Usage: cloudconnect [-project id] [-v] <command> [flags] [args]
+
+Commands:
+  mb      [-location loc] URL             create bucket
+  rb      [-force] URL                    delete bucket (-force: and all its content)
+  ls      [-l] URL                        list buckets (s3://, gs://, az://account), or objects with the URL key as prefix
+  cp      [-storage-class c] [-content-type t] [-kms-key k] [-progress] SRC DST
+                                          copy between local files and/or buckets
+  mv      SRC DST                         copy, then delete SRC
+  rm      [-r] [-dry-run] URL             delete object, or all objects with the URL key as prefix (-r)
+                                          or matching the URL key as glob pattern
+  cat     URL                             write object content to stdout
+  stat    URL                             show object details
+  sync    [-dry-run] [-progress] SRC DST  copy objects/files missing or differing in size from SRC to DST
+  presign [-method GET] [-expires 1h] URL create a presigned URL
+
+URLs: s3://bucket/key, gs://bucket/key, az://account/container/key
+GCP project defaults to $GOOGLE_CLOUD_PROJECT.
+-v logs every storage call to stderr.
+`
+
+// errUsage is returned for invalid command lines; usage is printed instead of the error.
+var errUsage = errors.New("invalid usage")
+
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"mb":      (*cli).mb,
+	"rb":      (*cli).rb,
+	"ls":      (*cli).ls,
+	"cp":      (*cli).cp,
+	"mv":      (*cli).mv,
+	"rm":      (*cli).rm,
+	"cat":     (*cli).cat,
+	"stat":    (*cli).stat,
+	"sync":    (*cli).sync,
+	"presign": (*cli).presign,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cloudconnect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	project := flags.String("project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "GCP project id")
+	verbose := flags.Bool("v", false, "log storage calls")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "cloudconnect: unknown command '%s'\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	c := newCLI(*project, stdin, stdout, stderr)
+	if *verbose {
+		c.logger = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	err := cmd(c, flags.Args()[1:])
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cloudconnect %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	return 0
+}
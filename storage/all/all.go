@@ -0,0 +1,13 @@
+// Package all registers all built-in storage providers (S3, GCP, Azure, local files and memory)
+// with the storage package. Import it for its side effects:
+//
+//	import _ "github.com/pbreedt/cloud-connect/storage/all"
+package all
+
+import (
+	_ "github.com/pbreedt/cloud-connect/storage/aws"
+	_ "github.com/pbreedt/cloud-connect/storage/azure"
+	_ "github.com/pbreedt/cloud-connect/storage/gcp"
+	_ "github.com/pbreedt/cloud-connect/storage/local"
+	_ "github.com/pbreedt/cloud-connect/storage/memory"
+)
@@ -0,0 +1,29 @@
+package aws
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+// TestS3Conformance runs the conformance tests against an S3 compatible service, as in MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	export AWS_ACCESS_KEY_ID=minioadmin AWS_SECRET_ACCESS_KEY=minioadmin
+//	CLOUDCONNECT_TEST_S3_ENDPOINT=http://localhost:9000 go test ./storage/aws -run Conformance
+func TestS3Conformance(t *testing.T) {
+	endpoint := os.Getenv("CLOUDCONNECT_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("CLOUDCONNECT_TEST_S3_ENDPOINT not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s3c, err := NewS3ClientFromConfig(Config{Endpoint: endpoint, UsePathStyle: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s3c
+	})
+}
@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// DeleteObjects deletes the keys in chunks of 1000, the DeleteObjects limit, using concurrent requests.
+// The returned error is non-nil if any key could not be deleted; the report holds the per-key results.
+func (s3Client *S3Client) DeleteObjects(bucketName string, objectKeys []string) (*common.DeleteReport, error) {
+	report := common.NewDeleteReport(objectKeys)
+
+	ctx := context.TODO()
+	g := errgroup.Group{}
+	g.SetLimit(common.DeleteConcurrency)
+
+	for i, chunk := range common.Chunk(objectKeys, maxDeleteKeys) {
+		offset, chunk := i*maxDeleteKeys, chunk
+		g.Go(func() error {
+			results := report.Results[offset : offset+len(chunk)]
+
+			objectIds := []types.ObjectIdentifier{}
+			for _, key := range chunk {
+				objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
+			}
+			output, err := s3Client.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &types.Delete{Objects: objectIds, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				for j := range results {
+					results[j].Err = err
+				}
+				return nil
+			}
+
+			// in quiet mode only the failed keys are returned
+			failed := map[string]error{}
+			for _, e := range output.Errors {
+				failed[aws.ToString(e.Key)] = errors.New(aws.ToString(e.Code) + ": " + aws.ToString(e.Message))
+			}
+			for j := range results {
+				results[j].Err = failed[results[j].Key]
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return report, report.Err()
+}
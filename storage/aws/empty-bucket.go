@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// DeleteObjects accepts at most 1000 keys per request
+const maxDeleteKeys = 1000
+
+// emptyBucket deletes all object versions, delete markers and incomplete multipart uploads in the bucket.
+func (s3Client *S3Client) emptyBucket(ctx context.Context, bucketName string, o common.DeleteBucketOptions) error {
+	counter := common.NewDeleteCounter(o.Progress)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.Concurrency)
+
+	deleteBatch := func(objectIds []types.ObjectIdentifier) {
+		g.Go(func() error {
+			result, err := s3Client.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &types.Delete{Objects: objectIds, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return err
+			}
+			if len(result.Errors) > 0 {
+				e := result.Errors[0]
+				return fmt.Errorf("%d objects not deleted, first error: %s: %s", len(result.Errors), aws.ToString(e.Key), aws.ToString(e.Message))
+			}
+			counter.Add(len(objectIds))
+			return nil
+		})
+	}
+
+	// ListObjectVersions includes the objects of unversioned buckets, with version "null"
+	batch := []types.ObjectIdentifier{}
+	versions := s3.NewListObjectVersionsPaginator(s3Client.Client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	})
+	for versions.HasMorePages() {
+		page, err := versions.NextPage(ctx)
+		if err != nil {
+			g.Wait()
+			return err
+		}
+
+		for _, v := range page.Versions {
+			batch = append(batch, types.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range page.DeleteMarkers {
+			batch = append(batch, types.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+		for len(batch) >= maxDeleteKeys {
+			deleteBatch(batch[:maxDeleteKeys])
+			batch = batch[maxDeleteKeys:]
+		}
+	}
+	if len(batch) > 0 {
+		deleteBatch(batch)
+	}
+
+	uploads := s3.NewListMultipartUploadsPaginator(s3Client.Client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucketName),
+	})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if err != nil {
+			g.Wait()
+			return err
+		}
+
+		for _, upload := range page.Uploads {
+			upload := upload
+			g.Go(func() error {
+				_, err := s3Client.Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+					Bucket:   aws.String(bucketName),
+					Key:      upload.Key,
+					UploadId: upload.UploadId,
+				})
+				return err
+			})
+		}
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// sseCustomer is the SSE-C algorithm, key and key MD5 of a request, all nil without a customer key
+type sseCustomer struct {
+	algorithm *string
+	key       *string
+	keyMD5    *string
+}
+
+func toSSECustomer(e common.EncryptionOptions) sseCustomer {
+	if len(e.CustomerKey) == 0 {
+		return sseCustomer{}
+	}
+	return sseCustomer{
+		algorithm: aws.String("AES256"),
+		key:       aws.String(e.CustomerKeyBase64()),
+		keyMD5:    aws.String(e.CustomerKeyMD5()),
+	}
+}
+
+func fromS3Encryption(sse types.ServerSideEncryption, kmsKeyId *string, customerAlgorithm *string, customerKeyMD5 *string) common.EncryptionInfo {
+	switch {
+	case aws.ToString(customerAlgorithm) != "":
+		return common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: aws.ToString(customerKeyMD5)}
+	case sse == types.ServerSideEncryptionAwsKms || sse == types.ServerSideEncryptionAwsKmsDsse:
+		return common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: aws.ToString(kmsKeyId)}
+	case sse == types.ServerSideEncryptionAes256:
+		return common.EncryptionInfo{Type: common.EncryptionProviderManaged}
+	default:
+		return common.EncryptionInfo{}
+	}
+}
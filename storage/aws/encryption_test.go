@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// sseServer records the request headers, reports the SSE-C or SSE-KMS encryption of the request on HEAD, and
+// answers copies
+func sseServer(t *testing.T) (*S3Client, map[string]http.Header) {
+	headers := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers[r.Method] = r.Header.Clone()
+		for _, h := range []string{
+			"X-Amz-Server-Side-Encryption-Customer-Algorithm",
+			"X-Amz-Server-Side-Encryption-Customer-Key-Md5",
+		} {
+			if v := r.Header.Get(h); v != "" {
+				w.Header().Set(h, v)
+			}
+		}
+		if r.Method == http.MethodHead && r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == "" {
+			w.Header().Set("X-Amz-Server-Side-Encryption", "aws:kms")
+			w.Header().Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", "arn:aws:kms:eu-west-1:1:key/k")
+		}
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+			return
+		}
+		w.Header().Set("Content-Length", "0")
+	}))
+	t.Cleanup(server.Close)
+
+	s3c, err := NewS3ClientFromConfig(Config{
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s3c, headers
+}
+
+func TestS3CustomerKey(t *testing.T) {
+	s3c, headers := sseServer(t)
+	encryption := common.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+
+	err := s3c.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(encryption))
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := headers[http.MethodPut]
+	if put.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "AES256" ||
+		put.Get("X-Amz-Server-Side-Encryption-Customer-Key") != encryption.CustomerKeyBase64() ||
+		put.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5") != encryption.CustomerKeyMD5() {
+		t.Errorf("PutObject headers = %v, want SSE-C headers", put)
+	}
+
+	info, err := s3c.StatObject("bucket", "key", common.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: encryption.CustomerKeyMD5()}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+}
+
+func TestS3KMSKey(t *testing.T) {
+	s3c, headers := sseServer(t)
+
+	err := s3c.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(common.EncryptionOptions{KMSKey: "alias/app"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := headers[http.MethodPut]
+	if put.Get("X-Amz-Server-Side-Encryption") != "aws:kms" || put.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "alias/app" {
+		t.Errorf("PutObject headers = %v, want SSE-KMS headers", put)
+	}
+
+	info, err := s3c.StatObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: "arn:aws:kms:eu-west-1:1:key/k"}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+
+	err = s3c.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(common.EncryptionOptions{KMSKey: "alias/app", CustomerKey: make([]byte, 32)}))
+	if err == nil {
+		t.Error("PutObject with KMS and customer key should fail")
+	}
+}
+
+func TestS3StorageClassEncryption(t *testing.T) {
+	s3c, headers := sseServer(t)
+
+	if err := s3c.SetStorageClass("bucket", "key", common.StorageClassInfrequentAccess); err != nil {
+		t.Fatal(err)
+	}
+	copied := headers[http.MethodPut]
+	if copied.Get("X-Amz-Server-Side-Encryption") != "aws:kms" ||
+		copied.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "arn:aws:kms:eu-west-1:1:key/k" {
+		t.Errorf("SetStorageClass copy headers = %v, want the SSE-KMS headers of the object", copied)
+	}
+
+	encryption := common.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+	if err := s3c.SetStorageClass("bucket", "key", common.StorageClassInfrequentAccess, common.WithCustomerKey(encryption.CustomerKey)); err != nil {
+		t.Fatal(err)
+	}
+	copied = headers[http.MethodPut]
+	if copied.Get("X-Amz-Server-Side-Encryption-Customer-Key") != encryption.CustomerKeyBase64() ||
+		copied.Get("X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key") != encryption.CustomerKeyBase64() {
+		t.Errorf("SetStorageClass copy headers = %v, want SSE-C headers for the source and the copy", copied)
+	}
+}
+
+func TestS3CreateBucketEncryptionFailure(t *testing.T) {
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete:
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Query().Has("encryption"):
+			w.WriteHeader(http.StatusNotImplemented)
+			w.Write([]byte(`<Error><Code>NotImplemented</Code><Message>KMS is not configured</Message></Error>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	s3c, err := NewS3ClientFromConfig(Config{
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s3c.CreateBucket("bucket", common.WithDefaultEncryption(common.EncryptionOptions{KMSKey: "alias/app"}))
+	if err == nil || !deleted {
+		t.Errorf("CreateBucket() = %v, deleted %t, want an error and the bucket deleted", err, deleted)
+	}
+}
@@ -0,0 +1,185 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func notFound(err error) error {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	var apiErr smithy.APIError
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) || (errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotFound") {
+		return fmt.Errorf("%w: %w", common.ErrNotFound, err)
+	}
+	return err
+}
+
+// notModified marks the error of a conditional read of an unchanged object
+func notModified(err error) error {
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified {
+		return fmt.Errorf("%w: %w", common.ErrNotModified, err)
+	}
+	return notFound(err)
+}
+
+// PutObject uploads the content of reader, in parts if it is large.
+func (s3Client *S3Client) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...common.PutOption) error {
+	o := common.NewPutOptions(opts...)
+	storageClass, err := toS3StorageClass(o.StorageClass)
+	if err != nil {
+		return err
+	}
+	if err := o.Encryption.Validate(); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+	if o.Progress != nil {
+		reader = common.NewProgressReader(reader, -1, o.Progress)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:       aws.String(bucketName),
+		Key:          aws.String(objectKey),
+		Body:         reader,
+		StorageClass: storageClass,
+		Metadata:     o.Metadata,
+	}
+	if o.ContentType != "" {
+		input.ContentType = aws.String(o.ContentType)
+	}
+	if o.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(o.ContentEncoding)
+	}
+	if o.Encryption.KMSKey != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(o.Encryption.KMSKey)
+	}
+	sse := toSSECustomer(o.Encryption)
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = sse.algorithm, sse.key, sse.keyMD5
+
+	uploader := manager.NewUploader(s3Client.Client)
+	_, err = uploader.Upload(context.TODO(), input)
+	return classNotSupported(err)
+}
+
+// GetObject returns a reader for the object content, which must be closed by the caller.
+func (s3Client *S3Client) GetObject(bucketName string, objectKey string, opts ...common.GetOption) (io.ReadCloser, error) {
+	o := common.NewGetOptions(opts...)
+	sse := toSSECustomer(o.Encryption)
+	input := &s3.GetObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		SSECustomerAlgorithm: sse.algorithm,
+		SSECustomerKey:       sse.key,
+		SSECustomerKeyMD5:    sse.keyMD5,
+	}
+	if o.IfNoneMatch != "" {
+		input.IfNoneMatch = aws.String(o.IfNoneMatch)
+	}
+	if o.Ranged() {
+		input.Range = aws.String(httpRange(o.Offset, o.Length))
+	}
+	result, err := s3Client.Client.GetObject(context.TODO(), input)
+	if err != nil {
+		return nil, notModified(err)
+	}
+
+	if o.Info != nil {
+		*o.Info = common.ObjectInfo{
+			Key:             objectKey,
+			Size:            common.ContentRangeSize(aws.ToString(result.ContentRange), aws.ToInt64(result.ContentLength)),
+			LastModified:    aws.ToTime(result.LastModified),
+			ETag:            aws.ToString(result.ETag),
+			ContentType:     aws.ToString(result.ContentType),
+			ContentEncoding: aws.ToString(result.ContentEncoding),
+			StorageClass:    fromS3StorageClass(result.StorageClass),
+			Metadata:        result.Metadata,
+			Encryption:      fromS3Encryption(result.ServerSideEncryption, result.SSEKMSKeyId, result.SSECustomerAlgorithm, result.SSECustomerKeyMD5),
+		}
+	}
+
+	if o.Progress != nil {
+		return common.NewProgressReadCloser(result.Body, aws.ToInt64(result.ContentLength), o.Progress), nil
+	}
+	return result.Body, nil
+}
+
+// httpRange returns the Range header of length bytes from offset, to the end if length is 0
+func httpRange(offset int64, length int64) string {
+	if length > 0 {
+		return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+	return fmt.Sprintf("bytes=%d-", offset)
+}
+
+func (s3Client *S3Client) StatObject(bucketName string, objectKey string, opts ...common.GetOption) (common.ObjectInfo, error) {
+	sse := toSSECustomer(common.NewGetOptions(opts...).Encryption)
+	head, err := s3Client.Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		SSECustomerAlgorithm: sse.algorithm,
+		SSECustomerKey:       sse.key,
+		SSECustomerKeyMD5:    sse.keyMD5,
+	})
+	if err != nil {
+		return common.ObjectInfo{}, notFound(err)
+	}
+
+	return common.ObjectInfo{
+		Key:             objectKey,
+		Size:            aws.ToInt64(head.ContentLength),
+		LastModified:    aws.ToTime(head.LastModified),
+		ETag:            aws.ToString(head.ETag),
+		ContentType:     aws.ToString(head.ContentType),
+		ContentEncoding: aws.ToString(head.ContentEncoding),
+		StorageClass:    fromS3StorageClass(head.StorageClass),
+		Metadata:        head.Metadata,
+		Encryption:      fromS3Encryption(head.ServerSideEncryption, head.SSEKMSKeyId, head.SSECustomerAlgorithm, head.SSECustomerKeyMD5),
+	}, nil
+}
+
+// PresignURL returns a URL granting method (GET, PUT or DELETE) access to the object until it expires.
+func (s3Client *S3Client) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	presigner := s3.NewPresignClient(s3Client.Client, s3.WithPresignExpires(expiry))
+
+	var err error
+	var request *v4.PresignedHTTPRequest
+	switch method {
+	case http.MethodGet:
+		request, err = presigner.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		})
+	case http.MethodPut:
+		request, err = presigner.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		})
+	case http.MethodDelete:
+		request, err = presigner.PresignDeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		})
+	default:
+		return "", fmt.Errorf("PresignURL(method %s: %w)", method, common.ErrNotSupported)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return request.URL, nil
+}
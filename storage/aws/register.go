@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func init() {
+	storage.Register(storage.TypeS3, newStorage)
+	storage.RegisterScheme("s3", openURL)
+}
+
+func newStorage(opts storage.Options) (storage.Storage, error) {
+	// AWS Location/Region retrieved from ~/.aws/config or env var AWS_DEFAULT_REGION, unless provided
+	s3Client, err := NewS3ClientFromConfig(Config{
+		Region:             opts.Location,
+		CredentialsFile:    opts.CredentialsFile,
+		Timeout:            opts.Timeout,
+		MaxAttempts:        opts.MaxAttempts,
+		RetryPolicy:        opts.RetryPolicy,
+		Endpoint:           opts.Endpoint,
+		UsePathStyle:       opts.UsePathStyle,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		AccessKeyId:        opts.Credentials.AWS_AccessKeyId,
+		SecretAccessKey:    opts.Credentials.AWS_SecretAccessKey,
+		SessionToken:       opts.Credentials.AWS_SessionToken,
+		Profile:            opts.Credentials.AWS_Profile,
+		RoleARN:            opts.Credentials.AWS_RoleARN,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3Client, nil
+}
+
+// openURL opens s3://bucket?region=eu-west-1&profile=prod, or s3://bucket?endpoint=http://localhost:9000&use_path_style=true
+func openURL(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+	if err := storage.CheckQuery(u, "region", "profile", "endpoint", "use_path_style"); err != nil {
+		return nil, "", err
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("bucket name must be provided, as in s3://bucket")
+	}
+
+	query := u.Query()
+	usePathStyle := false
+	if value := query.Get("use_path_style"); value != "" {
+		var err error
+		usePathStyle, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("use_path_style '%s' must be true or false", value)
+		}
+	}
+
+	s3Client, err := NewS3ClientFromConfig(Config{
+		Region:       query.Get("region"),
+		Profile:      query.Get("profile"),
+		Endpoint:     query.Get("endpoint"),
+		UsePathStyle: usePathStyle,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return s3Client, u.Host, nil
+}
@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// newRetryer maps the policy to the AWS standard retryer. The client-side retry quota is disabled,
+// so only the policy decides whether requests are retried.
+func newRetryer(policy common.RetryPolicy) func() aws.Retryer {
+	return func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = policy.MaxAttempts
+			o.MaxBackoff = policy.MaxBackoff
+			o.Backoff = backoff{policy}
+			o.RateLimiter = ratelimit.None
+			if policy.Retryable != nil {
+				o.Retryables = []retry.IsErrorRetryable{
+					retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+						return aws.BoolTernary(policy.Retryable(err, statusCode(err)))
+					}),
+				}
+			}
+		})
+	}
+}
+
+// backoff applies the policy's delays and reports each retry
+type backoff struct {
+	policy common.RetryPolicy
+}
+
+func (b backoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	delay := b.policy.Backoff(attempt)
+	if b.policy.OnRetry != nil {
+		b.policy.OnRetry(common.RetryEvent{Attempt: attempt, Err: err, Delay: delay})
+	}
+	return delay, nil
+}
+
+// statusCode returns the HTTP status of the response that caused err, 0 if there was none
+func statusCode(err error) int {
+	var responseErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode()
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// slowDownServer fails the first failures requests with 503 SlowDown, then lists one bucket
+func slowDownServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`))
+			return
+		}
+		w.Write([]byte(`<ListAllMyBucketsResult><Buckets><Bucket><Name>bucket</Name></Bucket></Buckets></ListAllMyBucketsResult>`))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestS3RetryPolicy(t *testing.T) {
+	server, requests := slowDownServer(t, 2)
+
+	events := []common.RetryEvent{}
+	s3c, err := NewS3ClientFromConfig(Config{
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+		RetryPolicy: &common.RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			OnRetry:     func(event common.RetryEvent) { events = append(events, event) },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := s3c.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || requests.Load() != 3 {
+		t.Errorf("ListBuckets() = %v after %d requests, want [bucket] after 3", buckets, requests.Load())
+	}
+	if len(events) != 2 || events[0].Attempt != 1 || events[1].Attempt != 2 || events[1].Delay != 2*time.Millisecond {
+		t.Errorf("retry events = %+v, want attempts 1 and 2 with doubling delay", events)
+	}
+}
+
+func TestS3RetryPolicyRetryable(t *testing.T) {
+	server, requests := slowDownServer(t, 2)
+
+	statusCodes := []int{}
+	s3c, err := NewS3ClientFromConfig(Config{
+		Endpoint:        server.URL,
+		UsePathStyle:    true,
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+		RetryPolicy: &common.RetryPolicy{
+			BaseBackoff: time.Millisecond,
+			Retryable: func(err error, statusCode int) bool {
+				statusCodes = append(statusCodes, statusCode)
+				return false
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s3c.ListBuckets(); err == nil {
+		t.Fatal("ListBuckets() should fail without retries")
+	}
+	if requests.Load() != 1 || len(statusCodes) != 1 || statusCodes[0] != http.StatusServiceUnavailable {
+		t.Errorf("%d requests, classified status codes %v, want 1 request with 503", requests.Load(), statusCodes)
+	}
+}
@@ -2,14 +2,21 @@ package aws
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pbreedt/cloud-connect/storage/common"
 )
 
 /*
@@ -25,7 +32,6 @@ auth:
 	export AWS_ACCESS_KEY_ID=xxx
 	export AWS_SECRET_ACCESS_KEY=xxx
 	export AWS_DEFAULT_REGION=us-east-2
-	us-east-1 not supported? see github.com/aws/aws-sdk-go-v2/service/s3/types.BucketLocationConstraint
 */
 
 type S3Client struct {
@@ -33,16 +39,90 @@ type S3Client struct {
 	location string
 }
 
+// Config holds the client settings, zero values fall back to the AWS defaults (see auth above).
+type Config struct {
+	Region string
+	// CredentialsFile replaces ~/.aws/credentials
+	CredentialsFile string
+	// Timeout limits connecting and waiting for the response of each request (see common.NewTransport)
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts per request, including the first (AWS default: 3)
+	MaxAttempts int
+	// RetryPolicy replaces the AWS standard retryer settings, with MaxAttempts as default attempts
+	RetryPolicy *common.RetryPolicy
+	// Endpoint replaces the AWS endpoint for S3 compatible services (MinIO, Ceph, Wasabi, R2, ...),
+	// as in http://localhost:9000; Region defaults to us-east-1 when set
+	Endpoint string
+	// UsePathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint, as most S3 compatible services need
+	UsePathStyle bool
+	// InsecureSkipVerify disables TLS certificate verification, for local services only
+	InsecureSkipVerify bool
+
+	// AccessKeyId and SecretAccessKey (with optional SessionToken) replace the default credentials
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	// Profile selects a named profile from ~/.aws/config and ~/.aws/credentials
+	Profile string
+	// RoleARN is a role to assume using the other (or default) credentials
+	RoleARN string
+}
+
+// Deprecated: use NewS3ClientFromConfig, which returns an error instead of exiting the process.
 func NewS3Client() *S3Client {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	s3Client, err := NewS3ClientFromConfig(Config{})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return &S3Client{
-		Client:   s3.NewFromConfig(cfg),
-		location: cfg.Region,
+	return s3Client
+}
+
+func NewS3ClientFromConfig(cfg Config) (*S3Client, error) {
+	loadOpts := []func(*config.LoadOptions) error{}
+	if cfg.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(cfg.Region))
+	} else if cfg.Endpoint != "" {
+		// S3 compatible services mostly ignore the region, but requests are signed with one
+		loadOpts = append(loadOpts, config.WithRegion("us-east-1"))
+	}
+	if cfg.CredentialsFile != "" {
+		loadOpts = append(loadOpts, config.WithSharedCredentialsFiles([]string{cfg.CredentialsFile}))
+	}
+	if cfg.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.AccessKeyId != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyId, cfg.SecretAccessKey, cfg.SessionToken),
+		))
+	}
+	if cfg.Timeout > 0 || cfg.InsecureSkipVerify {
+		loadOpts = append(loadOpts, config.WithHTTPClient(&http.Client{Transport: common.NewTransport(cfg.Timeout, cfg.InsecureSkipVerify)}))
+	}
+	if cfg.RetryPolicy != nil {
+		loadOpts = append(loadOpts, config.WithRetryer(newRetryer(cfg.RetryPolicy.WithDefaults(cfg.MaxAttempts))))
+	} else if cfg.MaxAttempts > 0 {
+		loadOpts = append(loadOpts, config.WithRetryMaxAttempts(cfg.MaxAttempts))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.TODO(), loadOpts...)
+	if err != nil {
+		return nil, err
 	}
+	if cfg.RoleARN != "" {
+		awsCfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsCfg), cfg.RoleARN))
+	}
+
+	return &S3Client{
+		Client: s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			if cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Endpoint)
+			}
+			o.UsePathStyle = cfg.UsePathStyle
+		}),
+		location: awsCfg.Region,
+	}, nil
 }
 
 func (s3Client *S3Client) WithDefaultLocation(location string) *S3Client {
@@ -50,14 +130,113 @@ func (s3Client *S3Client) WithDefaultLocation(location string) *S3Client {
 	return s3Client
 }
 
-func (s3Client *S3Client) CreateBucket(bucketName string) error {
-	_, err := s3Client.Client.CreateBucket(context.TODO(), &s3.CreateBucketInput{
+func (s3Client *S3Client) CreateBucket(bucketName string, opts ...common.CreateBucketOption) error {
+	o := common.NewCreateBucketOptions(opts...)
+	// S3 has no bucket level default storage class, it must be set per object
+	if o.StorageClass != "" {
+		return fmt.Errorf("CreateBucket(default storage class: %w)", common.ErrNotSupported)
+	}
+	if len(o.Encryption.CustomerKey) > 0 {
+		return fmt.Errorf("CreateBucket(default customer key: %w)", common.ErrNotSupported)
+	}
+
+	location := s3Client.location
+	if o.Location != "" {
+		location = o.Location
+	}
+
+	input := &s3.CreateBucketInput{
 		Bucket: aws.String(bucketName),
-		CreateBucketConfiguration: &types.CreateBucketConfiguration{
-			LocationConstraint: types.BucketLocationConstraint(s3Client.location),
-		},
-	})
-	return err
+	}
+	// us-east-1 is the default region and must not be sent as a LocationConstraint
+	if location != "" && location != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(location),
+		}
+	}
+
+	ctx := context.TODO()
+	inLocation := func(so *s3.Options) {
+		if location != "" {
+			so.Region = location
+		}
+	}
+	_, err := s3Client.Client.CreateBucket(ctx, input, inLocation)
+	if err != nil {
+		return err
+	}
+
+	if err := s3Client.configureBucket(ctx, bucketName, o); err != nil {
+		// the bucket is new and empty, a half-configured bucket (e.g. public or unencrypted) isn't kept
+		if _, deleteErr := s3Client.Client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucketName)}, inLocation); deleteErr != nil {
+			return fmt.Errorf("CreateBucket(%w, bucket '%s' was created and could not be deleted: %w)", err, bucketName, deleteErr)
+		}
+		return fmt.Errorf("CreateBucket(%w)", err)
+	}
+	return nil
+}
+
+// configureBucket applies the settings of o to a new bucket
+func (s3Client *S3Client) configureBucket(ctx context.Context, bucketName string, o common.CreateBucketOptions) error {
+	if o.BlockPublicAccess {
+		_, err := s3Client.Client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+			Bucket: aws.String(bucketName),
+			PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(true),
+				IgnorePublicAcls:      aws.Bool(true),
+				RestrictPublicBuckets: aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("block public access: %w", err)
+		}
+	}
+
+	if o.Encryption.KMSKey != "" {
+		_, err := s3Client.Client.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+			Bucket: aws.String(bucketName),
+			ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+				Rules: []types.ServerSideEncryptionRule{{
+					ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   types.ServerSideEncryptionAwsKms,
+						KMSMasterKeyID: aws.String(o.Encryption.KMSKey),
+					},
+				}},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("encryption: %w", err)
+		}
+	}
+
+	if len(o.Labels) > 0 {
+		tags := []types.Tag{}
+		for k, v := range o.Labels {
+			tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		_, err := s3Client.Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+			Bucket:  aws.String(bucketName),
+			Tagging: &types.Tagging{TagSet: tags},
+		})
+		if err != nil {
+			return fmt.Errorf("tagging: %w", err)
+		}
+	}
+
+	if o.Versioning {
+		_, err := s3Client.Client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+			Bucket: aws.String(bucketName),
+			VersioningConfiguration: &types.VersioningConfiguration{
+				Status: types.BucketVersioningStatusEnabled,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("versioning: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (s3Client *S3Client) ListBuckets() ([]string, error) {
@@ -92,39 +271,37 @@ func (s3Client *S3Client) ListBucketContent(bucketName string) ([]string, error)
 	return objects, nil
 }
 
-func (s3Client *S3Client) DeleteBucket(bucketName string) error {
+// DeleteBucket deletes an empty bucket, or any bucket if WithForce() is provided.
+func (s3Client *S3Client) DeleteBucket(bucketName string, opts ...common.DeleteBucketOption) error {
+	o := common.NewDeleteBucketOptions(opts...)
+	if o.Force {
+		err := s3Client.emptyBucket(context.TODO(), bucketName, o)
+		if err != nil {
+			return fmt.Errorf("DeleteBucket(%w)", err)
+		}
+	}
+
 	_, err := s3Client.Client.DeleteBucket(context.TODO(), &s3.DeleteBucketInput{
 		Bucket: aws.String(bucketName)})
 	return err
 }
 
-// For large files, use github.com/aws/aws-sdk-go-v2/feature/s3/manager.NewUploader
-func (s3Client *S3Client) StoreObject(bucketName string, objectKey string, fileName string) error {
+func (s3Client *S3Client) StoreObject(bucketName string, objectKey string, fileName string, opts ...common.PutOption) error {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	_, err = s3Client.Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectKey),
-		Body:   file,
-	})
-	return err
+	return s3Client.PutObject(bucketName, objectKey, file, opts...)
 }
 
-// For large files, use github.com/aws/aws-sdk-go-v2/feature/s3/manager.NewDownloader
-// TODO: return []byte instead if writing to file
-func (s3Client *S3Client) RetrieveObject(bucketName string, objectKey string, fileName string) error {
-	result, err := s3Client.Client.GetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(objectKey),
-	})
+func (s3Client *S3Client) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...common.GetOption) error {
+	body, err := s3Client.GetObject(bucketName, objectKey, opts...)
 	if err != nil {
 		return err
 	}
-	defer result.Body.Close()
+	defer body.Close()
 
 	file, err := os.Create(fileName)
 	if err != nil {
@@ -132,24 +309,11 @@ func (s3Client *S3Client) RetrieveObject(bucketName string, objectKey string, fi
 	}
 	defer file.Close()
 
-	body, err := io.ReadAll(result.Body)
-	if err != nil {
-		return err
-	}
-
-	_, err = file.Write(body)
+	_, err = io.Copy(file, body)
 	return err
 }
 
 func (s3Client *S3Client) DeleteObject(bucketName string, objectKeys []string) error {
-	var objectIds []types.ObjectIdentifier
-	for _, key := range objectKeys {
-		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
-	}
-	_, err := s3Client.Client.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
-		Bucket: aws.String(bucketName),
-		Delete: &types.Delete{Objects: objectIds},
-	})
-
+	_, err := s3Client.DeleteObjects(bucketName, objectKeys)
 	return err
 }
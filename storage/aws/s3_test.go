@@ -1,10 +1,13 @@
 package aws
 
 import (
+	"errors"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/pbreedt/cloud-connect/storage/common"
 )
 
 var bucketName string
@@ -33,6 +36,56 @@ func TestS3Upload(t *testing.T) {
 	t.Logf("Test data successfully uploaded to bucket '%s'.\n", bucketName)
 }
 
+func TestS3StatAndGet(t *testing.T) {
+	s3c := NewS3Client()
+
+	info, err := s3c.StatObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := os.ReadFile("../test_data/testfile.txt")
+	if info.Size != int64(len(expected)) {
+		t.Errorf("Size = %d, want %d", info.Size, len(expected))
+	}
+
+	body, err := s3c.GetObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(expected) {
+		t.Errorf("GetObject returned '%s', want '%s'", data, expected)
+	}
+
+	_, err = s3c.StatObject(bucketName, "no-such-object")
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("StatObject of missing object: %v, want ErrNotFound", err)
+	}
+	t.Log("Test data successfully read back.")
+}
+
+func TestS3SetStorageClass(t *testing.T) {
+	s3c := NewS3Client()
+
+	err := s3c.SetStorageClass(bucketName, "test-object", common.StorageClassInfrequentAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := s3c.RestoreStatus(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available {
+		t.Fatalf("Test object should be available after storage class change: %+v", status)
+	}
+	t.Log("Storage class of test data successfully changed.")
+}
+
 func TestS3ListBuckets(t *testing.T) {
 	s3c := NewS3Client()
 	t.Log("Buckets:")
@@ -94,3 +147,31 @@ func TestS3DeleteBucket(t *testing.T) {
 	}
 	t.Logf("Bucket '%s' successfully deleted.\n", bucketName)
 }
+
+func TestS3ForceDeleteBucket(t *testing.T) {
+	s3c := NewS3Client()
+	forceBucketName := uuid.New().String()
+
+	err := s3c.CreateBucket(forceBucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"test-object-1", "test-object-2"} {
+		err = s3c.StoreObject(forceBucketName, key, "../test_data/testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := 0
+	err = s3c.DeleteBucket(forceBucketName, common.WithForce(), common.WithDeleteProgress(func(n int) {
+		deleted = n
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Progress reported %d deleted objects, want 2", deleted)
+	}
+	t.Logf("Non-empty bucket '%s' successfully deleted.\n", forceBucketName)
+}
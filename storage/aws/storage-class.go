@@ -0,0 +1,280 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+func toS3StorageClass(class common.StorageClass) (types.StorageClass, error) {
+	switch class {
+	case "":
+		return "", nil
+	case common.StorageClassStandard:
+		return types.StorageClassStandard, nil
+	case common.StorageClassInfrequentAccess:
+		return types.StorageClassStandardIa, nil
+	case common.StorageClassArchive:
+		return types.StorageClassGlacier, nil
+	case common.StorageClassDeepArchive:
+		return types.StorageClassDeepArchive, nil
+	default:
+		return "", fmt.Errorf("unknown storage class '%s'", class)
+	}
+}
+
+// classNotSupported marks the error of S3 compatible services without the requested storage class (MinIO, Ceph, ...)
+func classNotSupported(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidStorageClass" {
+		return fmt.Errorf("%w: %w", common.ErrNotSupported, err)
+	}
+	return err
+}
+
+func isArchived(class types.StorageClass) bool {
+	return class == types.StorageClassGlacier || class == types.StorageClassDeepArchive
+}
+
+// maxCopySize is the largest object CopyObject copies, larger objects are copied in parts
+const maxCopySize = 5 << 30
+
+// copyPartSize is the size of the parts of a multipart copy, raised to stay within 10000 parts
+const copyPartSize = 512 << 20
+
+// SetStorageClass changes the storage class of an existing object by copying it onto itself, keeping its
+// metadata and server-side encryption. Objects stored with a customer key need it (storage.WithCustomerKey).
+// Objects larger than 5 GiB are copied in parts, with their metadata, headers and tags.
+func (s3Client *S3Client) SetStorageClass(bucketName string, objectKey string, class common.StorageClass, opts ...common.GetOption) error {
+	s3Class, err := toS3StorageClass(class)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	sse := toSSECustomer(common.NewGetOptions(opts...).Encryption)
+	head, err := s3Client.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		SSECustomerAlgorithm: sse.algorithm,
+		SSECustomerKey:       sse.key,
+		SSECustomerKeyMD5:    sse.keyMD5,
+	})
+	if err != nil {
+		return notFound(err)
+	}
+	if aws.ToInt64(head.ContentLength) > maxCopySize {
+		return classNotSupported(s3Client.copyInParts(ctx, bucketName, objectKey, s3Class, head, sse))
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(bucketName),
+		Key:                            aws.String(objectKey),
+		CopySource:                     aws.String(url.PathEscape(bucketName + "/" + objectKey)),
+		CopySourceIfMatch:              head.ETag,
+		StorageClass:                   s3Class,
+		MetadataDirective:              types.MetadataDirectiveCopy,
+		SSECustomerAlgorithm:           sse.algorithm,
+		SSECustomerKey:                 sse.key,
+		SSECustomerKeyMD5:              sse.keyMD5,
+		CopySourceSSECustomerAlgorithm: sse.algorithm,
+		CopySourceSSECustomerKey:       sse.key,
+		CopySourceSSECustomerKeyMD5:    sse.keyMD5,
+	}
+	// without encryption settings, the copy would be encrypted with the bucket default
+	if sse.algorithm == nil {
+		input.ServerSideEncryption = head.ServerSideEncryption
+		input.SSEKMSKeyId = head.SSEKMSKeyId
+		input.BucketKeyEnabled = head.BucketKeyEnabled
+	}
+	_, err = s3Client.Client.CopyObject(ctx, input)
+	return classNotSupported(err)
+}
+
+// copyInParts copies the object of head onto itself with a multipart upload, in the storage class
+func (s3Client *S3Client) copyInParts(ctx context.Context, bucketName string, objectKey string, class types.StorageClass, head *s3.HeadObjectOutput, sse sseCustomer) error {
+	tagging, err := s3Client.Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return err
+	}
+	tags := url.Values{}
+	for _, tag := range tagging.TagSet {
+		tags.Add(aws.ToString(tag.Key), aws.ToString(tag.Value))
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		StorageClass:         class,
+		Metadata:             head.Metadata,
+		ContentType:          head.ContentType,
+		ContentEncoding:      head.ContentEncoding,
+		ContentDisposition:   head.ContentDisposition,
+		ContentLanguage:      head.ContentLanguage,
+		CacheControl:         head.CacheControl,
+		SSECustomerAlgorithm: sse.algorithm,
+		SSECustomerKey:       sse.key,
+		SSECustomerKeyMD5:    sse.keyMD5,
+	}
+	if len(tags) > 0 {
+		input.Tagging = aws.String(tags.Encode())
+	}
+	if sse.algorithm == nil {
+		input.ServerSideEncryption = head.ServerSideEncryption
+		input.SSEKMSKeyId = head.SSEKMSKeyId
+		input.BucketKeyEnabled = head.BucketKeyEnabled
+	}
+	upload, err := s3Client.Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	partSize := max(copyPartSize, (size+9999)/10000)
+	parts := make([]types.CompletedPart, (size+partSize-1)/partSize)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(4)
+	for i := range parts {
+		i := i
+		start := int64(i) * partSize
+		end := min(start+partSize, size) - 1
+		g.Go(func() error {
+			part, err := s3Client.Client.UploadPartCopy(gctx, &s3.UploadPartCopyInput{
+				Bucket:                         aws.String(bucketName),
+				Key:                            aws.String(objectKey),
+				UploadId:                       upload.UploadId,
+				PartNumber:                     aws.Int32(int32(i + 1)),
+				CopySource:                     aws.String(url.PathEscape(bucketName + "/" + objectKey)),
+				CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+				CopySourceIfMatch:              head.ETag,
+				SSECustomerAlgorithm:           sse.algorithm,
+				SSECustomerKey:                 sse.key,
+				SSECustomerKeyMD5:              sse.keyMD5,
+				CopySourceSSECustomerAlgorithm: sse.algorithm,
+				CopySourceSSECustomerKey:       sse.key,
+				CopySourceSSECustomerKeyMD5:    sse.keyMD5,
+			})
+			if err != nil {
+				return err
+			}
+			parts[i] = types.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: aws.Int32(int32(i + 1))}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		s3Client.abortUpload(bucketName, objectKey, upload.UploadId)
+		return err
+	}
+
+	_, err = s3Client.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(objectKey),
+		UploadId:             upload.UploadId,
+		MultipartUpload:      &types.CompletedMultipartUpload{Parts: parts},
+		SSECustomerAlgorithm: sse.algorithm,
+		SSECustomerKey:       sse.key,
+		SSECustomerKeyMD5:    sse.keyMD5,
+	})
+	if err != nil {
+		s3Client.abortUpload(bucketName, objectKey, upload.UploadId)
+	}
+	return err
+}
+
+// abortUpload aborts a failed multipart upload, so its parts aren't kept (and billed)
+func (s3Client *S3Client) abortUpload(bucketName string, objectKey string, uploadId *string) {
+	s3Client.Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectKey),
+		UploadId: uploadId,
+	})
+}
+
+// RestoreObject starts a restore of an archived (GLACIER/DEEP_ARCHIVE) object.
+// Use RestoreStatus to poll for completion.
+func (s3Client *S3Client) RestoreObject(bucketName string, objectKey string, opts ...common.RestoreOption) error {
+	o := common.NewRestoreOptions(opts...)
+
+	tier := types.TierStandard
+	switch o.Priority {
+	case common.RestorePriorityExpedited:
+		tier = types.TierExpedited
+	case common.RestorePriorityBulk:
+		tier = types.TierBulk
+	}
+
+	_, err := s3Client.Client.RestoreObject(context.TODO(), &s3.RestoreObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 aws.Int32(int32(o.Days)),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: tier},
+		},
+	})
+	return err
+}
+
+// x-amz-restore header: ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+var restoreHeader = regexp.MustCompile(`ongoing-request="(true|false)"(?:,\s*expiry-date="([^"]+)")?`)
+
+func (s3Client *S3Client) RestoreStatus(bucketName string, objectKey string) (common.RestoreStatus, error) {
+	status := common.RestoreStatus{}
+
+	head, err := s3Client.Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return status, notFound(err)
+	}
+
+	status.Archived = isArchived(head.StorageClass)
+	status.Available = !status.Archived
+	if head.Restore == nil {
+		return status, nil
+	}
+
+	m := restoreHeader.FindStringSubmatch(*head.Restore)
+	if m == nil {
+		return status, fmt.Errorf("RestoreStatus(unexpected restore header '%s')", *head.Restore)
+	}
+	status.InProgress = m[1] == "true"
+	status.Available = !status.InProgress
+	if m[2] != "" {
+		status.Expiry, err = time.Parse(time.RFC1123, m[2])
+		if err != nil {
+			return status, fmt.Errorf("RestoreStatus(%w)", err)
+		}
+	}
+
+	return status, nil
+}
+
+// fromS3StorageClass maps back to a neutral storage class, classes without equivalent keep their S3 name.
+func fromS3StorageClass(class types.StorageClass) common.StorageClass {
+	switch class {
+	case "", types.StorageClassStandard:
+		return common.StorageClassStandard
+	case types.StorageClassStandardIa:
+		return common.StorageClassInfrequentAccess
+	case types.StorageClassGlacier:
+		return common.StorageClassArchive
+	case types.StorageClassDeepArchive:
+		return common.StorageClassDeepArchive
+	default:
+		return common.StorageClass(class)
+	}
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// WalkBucketContent calls fn for every object in the bucket, one listing page at a time.
+// With a delimiter, the common prefixes of a page are passed after its objects.
+func (s3Client *S3Client) WalkBucketContent(bucketName string, fn common.WalkFunc, opts ...common.ListOption) error {
+	o := common.NewListOptions(opts...)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	}
+	if o.Prefix != "" {
+		input.Prefix = aws.String(o.Prefix)
+	}
+	if o.Delimiter != "" {
+		input.Delimiter = aws.String(o.Delimiter)
+	}
+
+	pages := s3.NewListObjectsV2Paginator(s3Client.Client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			err = fn(common.ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+				ETag:         aws.ToString(object.ETag),
+			})
+			if err == common.StopWalk {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, prefix := range page.CommonPrefixes {
+			err = fn(common.ObjectInfo{Key: aws.ToString(prefix.Prefix), IsPrefix: true})
+			if err == common.StopWalk {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/pbreedt/cloud-connect/storage/common"
 )
 
 /*
@@ -34,26 +39,109 @@ auth:
 type BlobStorageClient struct {
 	Client         *azblob.Client
 	storageAccount string
+	// sharedKey signs presigned URLs when available, instead of a user delegation key
+	sharedKey *azblob.SharedKeyCredential
+	// sasToken clients can't create presigned URLs
+	sasToken bool
 }
 
-func NewBlobStorageClient(storageAccount string) *BlobStorageClient {
-
-	url := fmt.Sprintf("https://%s.blob.core.windows.net/", storageAccount)
+// Config holds the client settings.
+type Config struct {
+	StorageAccount string
+	// Timeout limits connecting and waiting for the response of each request (see common.NewTransport)
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts per request, including the first (Azure default: 4)
+	MaxAttempts int
+	// RetryPolicy replaces the Azure retry options, with MaxAttempts as default attempts
+	RetryPolicy *common.RetryPolicy
+	// Endpoint replaces the service URL https://<account>.blob.core.windows.net/,
+	// e.g. for Azurite: http://127.0.0.1:10000/devstoreaccount1
+	Endpoint string
+	// InsecureSkipVerify disables TLS certificate verification, for local services only
+	InsecureSkipVerify bool
+
+	// At most one of the following replaces the default credentials (see auth above):
+	// SharedKey is the storage account key
+	SharedKey string
+	// SASToken is a shared access signature for the storage account
+	SASToken string
+	// ConnectionString replaces StorageAccount and Endpoint
+	ConnectionString string
+	// ClientSecret authenticates app registration ClientId in TenantId
+	TenantId     string
+	ClientId     string
+	ClientSecret string
+}
 
-	credential, err := azidentity.NewDefaultAzureCredential(nil)
+// Deprecated: use NewBlobStorageClientFromConfig, which returns an error instead of exiting the process.
+func NewBlobStorageClient(storageAccount string) *BlobStorageClient {
+	az, err := NewBlobStorageClientFromConfig(Config{StorageAccount: storageAccount})
 	if err != nil {
 		log.Fatal(err)
 	}
-	client, err := azblob.NewClient(url, credential, nil)
-	if err != nil {
-		log.Fatal(err)
+
+	return az
+}
+
+func NewBlobStorageClientFromConfig(cfg Config) (*BlobStorageClient, error) {
+	url := fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.StorageAccount)
+	if cfg.Endpoint != "" {
+		url = cfg.Endpoint
 	}
 
-	return &BlobStorageClient{
-		Client:         client,
-		storageAccount: storageAccount,
+	clientOpts := &azblob.ClientOptions{}
+	if cfg.Timeout > 0 || cfg.InsecureSkipVerify {
+		clientOpts.Transport = &http.Client{Transport: common.NewTransport(cfg.Timeout, cfg.InsecureSkipVerify)}
+	}
+	if cfg.RetryPolicy != nil {
+		clientOpts.Retry = retryOptions(cfg.RetryPolicy.WithDefaults(cfg.MaxAttempts))
+	} else {
+		clientOpts.Retry.MaxRetries = maxRetries(cfg.MaxAttempts)
 	}
 
+	az := &BlobStorageClient{
+		storageAccount: cfg.StorageAccount,
+	}
+
+	var err error
+	switch {
+	case cfg.ConnectionString != "":
+		az.Client, err = azblob.NewClientFromConnectionString(cfg.ConnectionString, clientOpts)
+		if err == nil {
+			// keep the account key, if any, to sign presigned URLs
+			settings := parseConnectionString(cfg.ConnectionString)
+			az.storageAccount = settings["AccountName"]
+			if settings["AccountKey"] != "" {
+				az.sharedKey, err = azblob.NewSharedKeyCredential(settings["AccountName"], settings["AccountKey"])
+			}
+		}
+	case cfg.SharedKey != "":
+		az.sharedKey, err = azblob.NewSharedKeyCredential(cfg.StorageAccount, cfg.SharedKey)
+		if err == nil {
+			az.Client, err = azblob.NewClientWithSharedKeyCredential(url, az.sharedKey, clientOpts)
+		}
+	case cfg.SASToken != "":
+		az.sasToken = true
+		az.Client, err = azblob.NewClientWithNoCredential(url+"?"+strings.TrimPrefix(cfg.SASToken, "?"), clientOpts)
+	case cfg.ClientSecret != "":
+		var credential *azidentity.ClientSecretCredential
+		credential, err = azidentity.NewClientSecretCredential(cfg.TenantId, cfg.ClientId, cfg.ClientSecret, nil)
+		if err == nil {
+			az.Client, err = azblob.NewClient(url, credential, clientOpts)
+		}
+	default:
+		var credential *azidentity.DefaultAzureCredential
+		credential, err = azidentity.NewDefaultAzureCredential(nil)
+		if err == nil {
+			az.Client, err = azblob.NewClient(url, credential, clientOpts)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return az, nil
+
 	// alternatively: use client factory
 	// cred, err := azidentity.NewDefaultAzureCredential(nil)
 	// if err != nil {
@@ -71,8 +159,48 @@ func NewBlobStorageClient(storageAccount string) *BlobStorageClient {
 	// }
 }
 
-func (az *BlobStorageClient) CreateBucket(bucketName string) error {
-	_, err := az.Client.CreateContainer(context.Background(), bucketName, nil)
+// parseConnectionString returns the key=value settings of an Azure storage connection string
+func parseConnectionString(connectionString string) map[string]string {
+	settings := map[string]string{}
+	for _, setting := range strings.Split(connectionString, ";") {
+		key, value, ok := strings.Cut(setting, "=")
+		if ok {
+			settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
+
+// Location and versioning are properties of the storage account in Azure and can't be set per container.
+// BlockPublicAccess is the default for new containers (no anonymous access).
+func (az *BlobStorageClient) CreateBucket(bucketName string, opts ...common.CreateBucketOption) error {
+	o := common.NewCreateBucketOptions(opts...)
+	switch {
+	case o.Location != "":
+		return fmt.Errorf("CreateBucket(location: %w)", common.ErrNotSupported)
+	case o.StorageClass != "":
+		return fmt.Errorf("CreateBucket(default storage class: %w)", common.ErrNotSupported)
+	case o.Versioning:
+		return fmt.Errorf("CreateBucket(versioning: %w)", common.ErrNotSupported)
+	case len(o.Encryption.CustomerKey) > 0:
+		return fmt.Errorf("CreateBucket(default customer key: %w)", common.ErrNotSupported)
+	}
+
+	createOpts := &azblob.CreateContainerOptions{}
+	if len(o.Labels) > 0 {
+		createOpts.Metadata = map[string]*string{}
+		for k, v := range o.Labels {
+			createOpts.Metadata[k] = to.Ptr(v)
+		}
+	}
+	if o.Encryption.KMSKey != "" {
+		createOpts.CPKScopeInfo = &container.CPKScopeInfo{
+			DefaultEncryptionScope:         to.Ptr(o.Encryption.KMSKey),
+			PreventEncryptionScopeOverride: to.Ptr(true),
+		}
+	}
+
+	_, err := az.Client.CreateContainer(context.Background(), bucketName, createOpts)
 	return err
 }
 
@@ -115,36 +243,37 @@ func (az *BlobStorageClient) ListBucketContent(bucketName string) ([]string, err
 	return objects, nil
 }
 
-func (az *BlobStorageClient) DeleteBucket(bucketName string) error {
+// DeleteBucket deletes a container and its content. With WithForce(), blobs are deleted individually
+// first, reporting progress, instead of leaving it to the (asynchronous) container delete.
+func (az *BlobStorageClient) DeleteBucket(bucketName string, opts ...common.DeleteBucketOption) error {
+	o := common.NewDeleteBucketOptions(opts...)
+	if o.Force {
+		err := az.emptyBucket(context.TODO(), bucketName, o)
+		if err != nil {
+			return fmt.Errorf("DeleteBucket(%w)", err)
+		}
+	}
+
 	_, err := az.Client.DeleteContainer(context.TODO(), bucketName, nil)
 	return err
 }
 
-func (az *BlobStorageClient) StoreObject(bucketName string, objectKey string, fileName string) error {
-	data, err := os.ReadFile(fileName)
+func (az *BlobStorageClient) StoreObject(bucketName string, objectKey string, fileName string, opts ...common.PutOption) error {
+	file, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	_, err = az.Client.UploadBuffer(context.Background(), bucketName, objectKey, data, nil)
-	return err
+	return az.PutObject(bucketName, objectKey, file, opts...)
 }
 
-// TODO: return []byte instead if writing to file
-func (az *BlobStorageClient) RetrieveObject(bucketName string, objectKey string, fileName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*50)
-	defer cancel()
-
-	ds, err := az.Client.DownloadStream(ctx, bucketName, objectKey, nil)
-	if err != nil {
-		return err
-	}
-
-	retryReader := ds.NewRetryReader(ctx, &azblob.RetryReaderOptions{})
-	err = retryReader.Close()
+func (az *BlobStorageClient) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...common.GetOption) error {
+	body, err := az.GetObject(bucketName, objectKey, opts...)
 	if err != nil {
 		return err
 	}
+	defer body.Close()
 
 	file, err := os.Create(fileName)
 	if err != nil {
@@ -152,27 +281,13 @@ func (az *BlobStorageClient) RetrieveObject(bucketName string, objectKey string,
 	}
 	defer file.Close()
 
-	body, err := io.ReadAll(retryReader)
-	if err != nil {
-		return err
-	}
-
-	_, err = file.Write(body)
+	_, err = io.Copy(file, body)
 	return err
 }
 
 func (az *BlobStorageClient) DeleteObject(bucketName string, objectKeys []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*50)
-	defer cancel()
-
-	for _, objectKey := range objectKeys {
-		_, err := az.Client.DeleteBlob(ctx, bucketName, objectKey, nil)
-		if err != nil {
-			return fmt.Errorf("DeleteObject(%w)", err)
-		}
-	}
-
-	return nil
+	_, err := az.DeleteObjects(bucketName, objectKeys)
+	return err
 }
 
 /*
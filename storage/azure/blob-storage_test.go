@@ -1,10 +1,13 @@
 package azure
 
 import (
+	"errors"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/pbreedt/cloud-connect/storage/common"
 )
 
 var (
@@ -37,6 +40,56 @@ func TestBSUpload(t *testing.T) {
 	t.Logf("Test data successfully uploaded to bucket '%s'.\n", bucketName)
 }
 
+func TestBSStatAndGet(t *testing.T) {
+	az := NewBlobStorageClient(storageAccount)
+
+	info, err := az.StatObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := os.ReadFile("../test_data/testfile.txt")
+	if info.Size != int64(len(expected)) {
+		t.Errorf("Size = %d, want %d", info.Size, len(expected))
+	}
+
+	body, err := az.GetObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(expected) {
+		t.Errorf("GetObject returned '%s', want '%s'", data, expected)
+	}
+
+	_, err = az.StatObject(bucketName, "no-such-object")
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("StatObject of missing object: %v, want ErrNotFound", err)
+	}
+	t.Log("Test data successfully read back.")
+}
+
+func TestBSSetStorageClass(t *testing.T) {
+	az := NewBlobStorageClient(storageAccount)
+
+	err := az.SetStorageClass(bucketName, "test-object", common.StorageClassInfrequentAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := az.RestoreStatus(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available {
+		t.Fatalf("Test object should be available after storage class change: %+v", status)
+	}
+	t.Log("Storage class of test data successfully changed.")
+}
+
 func TestBSListBuckets(t *testing.T) {
 	az := NewBlobStorageClient(storageAccount)
 	t.Logf("Buckets in storage account '%s':\n", storageAccount)
@@ -98,3 +151,31 @@ func TestBSDeleteBucket(t *testing.T) {
 	}
 	t.Logf("Bucket '%s' successfully deleted.\n", bucketName)
 }
+
+func TestBSForceDeleteBucket(t *testing.T) {
+	az := NewBlobStorageClient(storageAccount)
+	forceBucketName := uuid.New().String()
+
+	err := az.CreateBucket(forceBucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"test-object-1", "test-object-2"} {
+		err = az.StoreObject(forceBucketName, key, "../test_data/testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := 0
+	err = az.DeleteBucket(forceBucketName, common.WithForce(), common.WithDeleteProgress(func(n int) {
+		deleted = n
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Progress reported %d deleted objects, want 2", deleted)
+	}
+	t.Logf("Non-empty bucket '%s' successfully deleted.\n", forceBucketName)
+}
@@ -0,0 +1,29 @@
+package azure
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+// TestBSConformance runs the conformance tests against Azurite, using its well-known account key:
+//
+//	docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0
+//	CLOUDCONNECT_TEST_AZURE_CONNECTION_STRING="DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;" \
+//	  go test ./storage/azure -run Conformance
+func TestBSConformance(t *testing.T) {
+	connectionString := os.Getenv("CLOUDCONNECT_TEST_AZURE_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("CLOUDCONNECT_TEST_AZURE_CONNECTION_STRING not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		az, err := NewBlobStorageClientFromConfig(Config{ConnectionString: connectionString})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return az
+	})
+}
@@ -0,0 +1,69 @@
+package azure
+
+import (
+	"context"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// a blob batch request holds at most 256 sub-requests
+const maxBatchSize = 256
+
+// batchTimeout bounds each blob batch request
+const batchTimeout = 50 * time.Second
+
+// DeleteObjects deletes the keys in blob batch requests of 256, sent concurrently.
+// The returned error is non-nil if any key could not be deleted; the report holds the per-key results.
+func (az *BlobStorageClient) DeleteObjects(bucketName string, objectKeys []string) (*common.DeleteReport, error) {
+	report := common.NewDeleteReport(objectKeys)
+
+	containerClient := az.Client.ServiceClient().NewContainerClient(bucketName)
+	g := errgroup.Group{}
+	g.SetLimit(common.DeleteConcurrency)
+
+	for i, chunk := range common.Chunk(objectKeys, maxBatchSize) {
+		results := report.Results[i*maxBatchSize : i*maxBatchSize+len(chunk)]
+		g.Go(func() error {
+			setErr := func(err error) {
+				for j := range results {
+					results[j].Err = err
+				}
+			}
+
+			batch, err := containerClient.NewBatchBuilder()
+			if err != nil {
+				setErr(err)
+				return nil
+			}
+			for _, result := range results {
+				err = batch.Delete(result.Key, nil)
+				if err != nil {
+					setErr(err)
+					return nil
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), batchTimeout)
+			defer cancel()
+			resp, err := containerClient.SubmitBatch(ctx, batch, nil)
+			if err != nil {
+				setErr(err)
+				return nil
+			}
+
+			// ContentID is the index of the sub-request in the batch
+			setErr(common.ErrNotReported)
+			for _, item := range resp.Responses {
+				if item.ContentID != nil && *item.ContentID >= 0 && *item.ContentID < len(results) {
+					results[*item.ContentID].Err = notFound(item.Error)
+				}
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return report, report.Err()
+}
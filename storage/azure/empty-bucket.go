@@ -0,0 +1,59 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// emptyBucket deletes all blobs, including their snapshots and previous versions, in the container.
+// Deleting the current version of a blob in an account with versioning enabled creates a new previous
+// version; those are removed by the container delete that follows.
+func (az *BlobStorageClient) emptyBucket(ctx context.Context, bucketName string, o common.DeleteBucketOptions) error {
+	counter := common.NewDeleteCounter(o.Progress)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.Concurrency)
+
+	pager := az.Client.NewListBlobsFlatPager(bucketName, &azblob.ListBlobsFlatOptions{
+		Include: azblob.ListBlobsInclude{Versions: true},
+	})
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			g.Wait()
+			return err
+		}
+
+		for _, item := range resp.Segment.BlobItems {
+			item := item
+			g.Go(func() error {
+				blobClient := az.blobClient(bucketName, *item.Name)
+				deleteOpts := &blob.DeleteOptions{DeleteSnapshots: to.Ptr(blob.DeleteSnapshotsOptionTypeInclude)}
+
+				isPreviousVersion := item.VersionID != nil && (item.IsCurrentVersion == nil || !*item.IsCurrentVersion)
+				if isPreviousVersion {
+					var err error
+					blobClient, err = blobClient.WithVersionID(*item.VersionID)
+					if err != nil {
+						return err
+					}
+					deleteOpts = nil
+				}
+
+				_, err := blobClient.Delete(ctx, deleteOpts)
+				if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+					return err
+				}
+				counter.Add(1)
+				return nil
+			})
+		}
+	}
+
+	return g.Wait()
+}
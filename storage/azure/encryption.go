@@ -0,0 +1,40 @@
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// toCPKInfo returns the customer-provided key of e, nil without one
+func toCPKInfo(e common.EncryptionOptions) *blob.CPKInfo {
+	if len(e.CustomerKey) == 0 {
+		return nil
+	}
+	return &blob.CPKInfo{
+		EncryptionAlgorithm: to.Ptr(blob.EncryptionAlgorithmTypeAES256),
+		EncryptionKey:       to.Ptr(e.CustomerKeyBase64()),
+		EncryptionKeySHA256: to.Ptr(e.CustomerKeySHA256()),
+	}
+}
+
+// toCPKScopeInfo returns the encryption scope of e, nil without one
+func toCPKScopeInfo(e common.EncryptionOptions) *blob.CPKScopeInfo {
+	if e.KMSKey == "" {
+		return nil
+	}
+	return &blob.CPKScopeInfo{EncryptionScope: to.Ptr(e.KMSKey)}
+}
+
+func fromAzureEncryption(keySHA256 *string, scope *string, serverEncrypted *bool) common.EncryptionInfo {
+	switch {
+	case keySHA256 != nil:
+		return common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: *keySHA256}
+	case scope != nil && *scope != "$account-encryption-key":
+		return common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: *scope}
+	case serverEncrypted != nil && *serverEncrypted:
+		return common.EncryptionInfo{Type: common.EncryptionProviderManaged}
+	default:
+		return common.EncryptionInfo{}
+	}
+}
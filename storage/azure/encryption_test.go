@@ -0,0 +1,88 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// cpkServer records the headers of the last PUT, and reports the customer key of the request or the encryption scope
+// of the last PUT on HEAD
+func cpkServer(t *testing.T) (*BlobStorageClient, http.Header) {
+	put := http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			for k, v := range r.Header {
+				put[k] = v
+			}
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodHead:
+			w.Header().Set("x-ms-server-encrypted", "true")
+			if sha := r.Header.Get("x-ms-encryption-key-sha256"); sha != "" {
+				w.Header().Set("x-ms-encryption-key-sha256", sha)
+			} else if scope := put.Get("x-ms-encryption-scope"); scope != "" {
+				w.Header().Set("x-ms-encryption-scope", scope)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	az, err := NewBlobStorageClientFromConfig(Config{
+		StorageAccount: "account",
+		Endpoint:       server.URL + "/account",
+		SharedKey:      base64.StdEncoding.EncodeToString([]byte("key")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return az, put
+}
+
+func TestBSCustomerKey(t *testing.T) {
+	az, put := cpkServer(t)
+	encryption := common.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+
+	err := az.PutObject("container", "key", strings.NewReader("data"), common.WithEncryption(encryption))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put.Get("x-ms-encryption-key") != encryption.CustomerKeyBase64() || put.Get("x-ms-encryption-algorithm") != "AES256" {
+		t.Errorf("PutObject headers = %v, want customer key headers", put)
+	}
+
+	info, err := az.StatObject("container", "key", common.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: encryption.CustomerKeySHA256()}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+}
+
+func TestBSEncryptionScope(t *testing.T) {
+	az, put := cpkServer(t)
+
+	err := az.PutObject("container", "key", strings.NewReader("data"), common.WithEncryption(common.EncryptionOptions{KMSKey: "scope"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put.Get("x-ms-encryption-scope") != "scope" {
+		t.Errorf("PutObject headers = %v, want encryption scope", put)
+	}
+
+	info, err := az.StatObject("container", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: "scope"}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+}
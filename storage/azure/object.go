@@ -0,0 +1,221 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func notFound(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return fmt.Errorf("%w: %w", common.ErrNotFound, err)
+	}
+	return err
+}
+
+func (az *BlobStorageClient) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...common.PutOption) error {
+	o := common.NewPutOptions(opts...)
+	tier, err := toAzureAccessTier(o.StorageClass)
+	if err != nil {
+		return err
+	}
+	if err := o.Encryption.Validate(); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+
+	uploadOpts := &azblob.UploadStreamOptions{
+		AccessTier:   tier,
+		CPKInfo:      toCPKInfo(o.Encryption),
+		CPKScopeInfo: toCPKScopeInfo(o.Encryption),
+	}
+	if o.ContentType != "" || o.ContentEncoding != "" {
+		uploadOpts.HTTPHeaders = &blob.HTTPHeaders{}
+	}
+	if o.ContentType != "" {
+		uploadOpts.HTTPHeaders.BlobContentType = to.Ptr(o.ContentType)
+	}
+	if o.ContentEncoding != "" {
+		uploadOpts.HTTPHeaders.BlobContentEncoding = to.Ptr(o.ContentEncoding)
+	}
+	if len(o.Metadata) > 0 {
+		uploadOpts.Metadata = map[string]*string{}
+		for k, v := range o.Metadata {
+			uploadOpts.Metadata[k] = to.Ptr(v)
+		}
+	}
+
+	if o.Progress != nil {
+		reader = common.NewProgressReader(reader, -1, o.Progress)
+	}
+
+	_, err = az.Client.UploadStream(context.Background(), bucketName, objectKey, reader, uploadOpts)
+	return err
+}
+
+// GetObject returns a reader for the blob content, which must be closed by the caller.
+// The reader retries (resumes) the download on transient failures.
+// The content is read as stored, blobs with Content-Encoding gzip are not decompressed.
+func (az *BlobStorageClient) GetObject(bucketName string, objectKey string, opts ...common.GetOption) (io.ReadCloser, error) {
+	o := common.NewGetOptions(opts...)
+	// asking for the identity encoding keeps the HTTP transport from decompressing gzip encoded blobs
+	ctx := policy.WithHTTPHeader(context.Background(), http.Header{"Accept-Encoding": {"identity"}})
+
+	downloadOpts := &azblob.DownloadStreamOptions{
+		CPKInfo: toCPKInfo(o.Encryption),
+	}
+	if o.IfNoneMatch != "" {
+		etag := azcore.ETag(o.IfNoneMatch)
+		downloadOpts.AccessConditions = &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etag},
+		}
+	}
+	if o.Ranged() {
+		downloadOpts.Range = blob.HTTPRange{Offset: o.Offset, Count: o.Length}
+	}
+	// the SDK accepts 304 responses to downloads, the status is read from the captured response
+	var resp *http.Response
+	ds, err := az.Client.DownloadStream(policy.WithCaptureResponse(ctx, &resp), bucketName, objectKey, downloadOpts)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		ds.Body.Close()
+		return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, common.ErrNotModified)
+	}
+
+	if o.Info != nil {
+		*o.Info = objectInfo(objectKey, ds.ContentLength, ds.LastModified, ds.ETag, ds.ContentType, ds.ContentEncoding, ds.Metadata)
+		if ds.ContentRange != nil {
+			o.Info.Size = common.ContentRangeSize(*ds.ContentRange, o.Info.Size)
+		}
+		o.Info.Encryption = fromAzureEncryption(ds.EncryptionKeySHA256, ds.EncryptionScope, ds.IsServerEncrypted)
+	}
+
+	rc := ds.NewRetryReader(ctx, &azblob.RetryReaderOptions{})
+	if o.Progress != nil {
+		size := int64(-1)
+		if ds.ContentLength != nil {
+			size = *ds.ContentLength
+		}
+		return common.NewProgressReadCloser(rc, size, o.Progress), nil
+	}
+	return rc, nil
+}
+
+func (az *BlobStorageClient) StatObject(bucketName string, objectKey string, opts ...common.GetOption) (common.ObjectInfo, error) {
+	o := common.NewGetOptions(opts...)
+	props, err := az.blobClient(bucketName, objectKey).GetProperties(context.Background(), &blob.GetPropertiesOptions{
+		CPKInfo: toCPKInfo(o.Encryption),
+	})
+	if err != nil {
+		return common.ObjectInfo{}, notFound(err)
+	}
+
+	info := objectInfo(objectKey, props.ContentLength, props.LastModified, props.ETag, props.ContentType, props.ContentEncoding, props.Metadata)
+	info.StorageClass = fromAzureAccessTier(props.AccessTier)
+	info.Encryption = fromAzureEncryption(props.EncryptionKeySHA256, props.EncryptionScope, props.IsServerEncrypted)
+	return info, nil
+}
+
+// objectInfo returns the details of a blob from the headers of a properties or download response
+func objectInfo(objectKey string, length *int64, lastModified *time.Time, etag *azcore.ETag, contentType *string, contentEncoding *string, metadata map[string]*string) common.ObjectInfo {
+	info := common.ObjectInfo{
+		Key:      objectKey,
+		Metadata: map[string]string{},
+	}
+	if length != nil {
+		info.Size = *length
+	}
+	if lastModified != nil {
+		info.LastModified = *lastModified
+	}
+	if etag != nil {
+		info.ETag = string(*etag)
+	}
+	if contentType != nil {
+		info.ContentType = *contentType
+	}
+	if contentEncoding != nil {
+		info.ContentEncoding = *contentEncoding
+	}
+	// metadata names come back in canonical header case
+	for k, v := range metadata {
+		if v != nil {
+			info.Metadata[strings.ToLower(k)] = *v
+		}
+	}
+	return info
+}
+
+// PresignURL returns a SAS URL granting method (GET, PUT or DELETE) access to the blob until it expires.
+// The SAS is signed with the shared key, if the client has one, or else with a user delegation key,
+// which needs the "Storage Blob Delegator" role and limits expiry to at most 7 days.
+func (az *BlobStorageClient) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	permissions := sas.BlobPermissions{}
+	switch method {
+	case http.MethodGet:
+		permissions.Read = true
+	case http.MethodPut:
+		permissions.Create = true
+		permissions.Write = true
+	case http.MethodDelete:
+		permissions.Delete = true
+	default:
+		return "", fmt.Errorf("PresignURL(method %s: %w)", method, common.ErrNotSupported)
+	}
+
+	if az.sasToken {
+		return "", fmt.Errorf("PresignURL(SAS token credentials: %w)", common.ErrNotSupported)
+	}
+
+	start := time.Now().UTC().Add(-5 * time.Minute) // allow for clock skew
+	end := time.Now().UTC().Add(expiry)
+
+	protocol := sas.ProtocolHTTPS
+	if strings.HasPrefix(az.Client.URL(), "http://") {
+		// local emulator
+		protocol = sas.ProtocolHTTPSandHTTP
+	}
+
+	values := sas.BlobSignatureValues{
+		Protocol:      protocol,
+		StartTime:     start,
+		ExpiryTime:    end,
+		Permissions:   permissions.String(),
+		ContainerName: bucketName,
+		BlobName:      objectKey,
+	}
+
+	var params sas.QueryParameters
+	var err error
+	if az.sharedKey != nil {
+		params, err = values.SignWithSharedKey(az.sharedKey)
+	} else {
+		var credential *service.UserDelegationCredential
+		credential, err = az.Client.ServiceClient().GetUserDelegationCredential(context.Background(), service.KeyInfo{
+			Start:  to.Ptr(start.Format(sas.TimeFormat)),
+			Expiry: to.Ptr(end.Format(sas.TimeFormat)),
+		}, nil)
+		if err == nil {
+			params, err = values.SignWithUserDelegation(credential)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return az.blobClient(bucketName, objectKey).URL() + "?" + params.Encode(), nil
+}
@@ -0,0 +1,56 @@
+package azure
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func TestBSConditionalGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"current"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"current"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "4")
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 10:00:00 GMT")
+		w.Header().Set("x-ms-meta-owner", "me")
+		io.WriteString(w, "data")
+	}))
+	t.Cleanup(server.Close)
+
+	az, err := NewBlobStorageClientFromConfig(Config{
+		StorageAccount: "account",
+		Endpoint:       server.URL + "/account",
+		SharedKey:      base64.StdEncoding.EncodeToString([]byte("key")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = az.GetObject("container", "blob", common.WithIfNoneMatch(`"current"`))
+	if !errors.Is(err, common.ErrNotModified) {
+		t.Errorf("GetObject(IfNoneMatch current ETag) = %v, want ErrNotModified", err)
+	}
+
+	info := common.ObjectInfo{}
+	rc, err := az.GetObject("container", "blob", common.WithIfNoneMatch(`"previous"`), common.WithObjectInfo(&info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil || string(content) != "data" {
+		t.Errorf("GetObject(IfNoneMatch previous ETag) = '%s', %v, want 'data'", content, err)
+	}
+	if info.ETag != `"current"` || info.Size != 4 || info.ContentType != "text/plain" || info.Metadata["owner"] != "me" {
+		t.Errorf("ObjectInfo = %+v, want the blob's properties", info)
+	}
+}
@@ -0,0 +1,59 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func init() {
+	storage.Register(storage.TypeAzure, newStorage)
+	storage.RegisterScheme("azblob", openURL)
+	storage.RegisterScheme("az", openURL)
+}
+
+func newStorage(opts storage.Options) (storage.Storage, error) {
+	if opts.CredentialsFile != "" {
+		return nil, fmt.Errorf("Options.CredentialsFile: %w, use Options.Credentials", common.ErrNotSupported)
+	}
+	az, err := NewBlobStorageClientFromConfig(Config{
+		StorageAccount:     opts.Azure_StorageAccount,
+		SharedKey:          opts.Credentials.Azure_SharedKey,
+		SASToken:           opts.Credentials.Azure_SASToken,
+		ConnectionString:   opts.Credentials.Azure_ConnectionString,
+		TenantId:           opts.Credentials.Azure_TenantId,
+		ClientId:           opts.Credentials.Azure_ClientId,
+		ClientSecret:       opts.Credentials.Azure_ClientSecret,
+		Timeout:            opts.Timeout,
+		MaxAttempts:        opts.MaxAttempts,
+		RetryPolicy:        opts.RetryPolicy,
+		Endpoint:           opts.Endpoint,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return az, nil
+}
+
+// openURL opens azblob://account/container (or az://account/container),
+// with an optional endpoint query parameter replacing the service URL of the account
+func openURL(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+	if err := storage.CheckQuery(u, "endpoint"); err != nil {
+		return nil, "", err
+	}
+	container := strings.Trim(u.Path, "/")
+	if u.Host == "" || container == "" || strings.Contains(container, "/") {
+		return nil, "", fmt.Errorf("storage account and container must be provided, as in azblob://account/container")
+	}
+
+	az, err := NewBlobStorageClientFromConfig(Config{StorageAccount: u.Host, Endpoint: u.Query().Get("endpoint")})
+	if err != nil {
+		return nil, "", err
+	}
+	return az, container, nil
+}
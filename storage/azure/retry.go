@@ -0,0 +1,54 @@
+package azure
+
+import (
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// retryOptions maps the policy to Azure retry options. Azure applies its own jitter.
+func retryOptions(retryPolicy common.RetryPolicy) policy.RetryOptions {
+	retryable := retryPolicy.Retryable
+	if retryable == nil {
+		// same as the Azure default status codes
+		retryable = common.DefaultRetryable
+	}
+
+	return policy.RetryOptions{
+		MaxRetries:    maxRetries(retryPolicy.MaxAttempts),
+		RetryDelay:    retryPolicy.BaseBackoff,
+		MaxRetryDelay: retryPolicy.MaxBackoff,
+		// called for every response, including successful ones
+		ShouldRetry: func(resp *http.Response, err error) bool {
+			statusCode := 0
+			if resp != nil {
+				statusCode = resp.StatusCode
+				if err == nil {
+					if statusCode < http.StatusBadRequest {
+						return false
+					}
+					err = runtime.NewResponseError(resp)
+				}
+			}
+
+			retry := retryable(err, statusCode)
+			if retry && retryPolicy.OnRetry != nil {
+				retryPolicy.OnRetry(common.RetryEvent{Err: err})
+			}
+			return retry
+		},
+	}
+}
+
+// maxRetries converts attempts (including the first) to Azure retries, where 0 means the default and -1 none
+func maxRetries(maxAttempts int) int32 {
+	if maxAttempts <= 0 {
+		return 0
+	}
+	if maxAttempts == 1 {
+		return -1
+	}
+	return int32(maxAttempts - 1)
+}
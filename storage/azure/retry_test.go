@@ -0,0 +1,50 @@
+package azure
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func TestBSRetryPolicy(t *testing.T) {
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.Header().Set("x-ms-error-code", "ServerBusy")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Containers><Container><Name>container</Name></Container></Containers><NextMarker/></EnumerationResults>`))
+	}))
+	defer server.Close()
+
+	events := 0
+	az, err := NewBlobStorageClientFromConfig(Config{
+		StorageAccount: "account",
+		Endpoint:       server.URL + "/account",
+		SharedKey:      base64.StdEncoding.EncodeToString([]byte("key")),
+		RetryPolicy: &common.RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+			OnRetry:     func(event common.RetryEvent) { events++ },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	containers, err := az.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || requests.Load() != 3 || events != 2 {
+		t.Errorf("ListBuckets() = %v after %d requests and %d retry events, want [container] after 3 and 2", containers, requests.Load(), events)
+	}
+}
@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func toAzureAccessTier(class common.StorageClass) (*blob.AccessTier, error) {
+	switch class {
+	case "":
+		return nil, nil
+	case common.StorageClassStandard:
+		return to.Ptr(blob.AccessTierHot), nil
+	case common.StorageClassInfrequentAccess:
+		return to.Ptr(blob.AccessTierCool), nil
+	case common.StorageClassArchive:
+		return to.Ptr(blob.AccessTierCold), nil
+	case common.StorageClassDeepArchive:
+		return to.Ptr(blob.AccessTierArchive), nil
+	default:
+		return nil, fmt.Errorf("unknown storage class '%s'", class)
+	}
+}
+
+func (az *BlobStorageClient) blobClient(bucketName string, objectKey string) *blob.Client {
+	return az.Client.ServiceClient().NewContainerClient(bucketName).NewBlobClient(objectKey)
+}
+
+func (az *BlobStorageClient) SetStorageClass(bucketName string, objectKey string, class common.StorageClass, opts ...common.GetOption) error {
+	tier, err := toAzureAccessTier(class)
+	if err != nil {
+		return err
+	}
+	if tier == nil {
+		return fmt.Errorf("SetStorageClass(storage class must be provided)")
+	}
+
+	_, err = az.blobClient(bucketName, objectKey).SetTier(context.Background(), *tier, nil)
+	return notFound(err)
+}
+
+// RestoreObject rehydrates an archived blob to the Hot tier.
+// Use RestoreStatus to poll for completion.
+func (az *BlobStorageClient) RestoreObject(bucketName string, objectKey string, opts ...common.RestoreOption) error {
+	o := common.NewRestoreOptions(opts...)
+
+	priority := blob.RehydratePriorityStandard
+	if o.Priority == common.RestorePriorityExpedited {
+		priority = blob.RehydratePriorityHigh
+	}
+
+	_, err := az.blobClient(bucketName, objectKey).SetTier(context.Background(), blob.AccessTierHot, &blob.SetTierOptions{
+		RehydratePriority: &priority,
+	})
+	return notFound(err)
+}
+
+func (az *BlobStorageClient) RestoreStatus(bucketName string, objectKey string) (common.RestoreStatus, error) {
+	status := common.RestoreStatus{}
+
+	props, err := az.blobClient(bucketName, objectKey).GetProperties(context.Background(), nil)
+	if err != nil {
+		return status, notFound(err)
+	}
+
+	status.Archived = props.AccessTier != nil && blob.AccessTier(*props.AccessTier) == blob.AccessTierArchive
+	status.InProgress = props.ArchiveStatus != nil && strings.HasPrefix(*props.ArchiveStatus, "rehydrate-pending")
+	status.Available = !status.Archived
+
+	return status, nil
+}
+
+// fromAzureAccessTier maps back to a neutral storage class, tiers without equivalent keep their Azure name.
+func fromAzureAccessTier(tier *string) common.StorageClass {
+	if tier == nil {
+		return common.StorageClassStandard
+	}
+
+	switch blob.AccessTier(*tier) {
+	case blob.AccessTierHot:
+		return common.StorageClassStandard
+	case blob.AccessTierCool:
+		return common.StorageClassInfrequentAccess
+	case blob.AccessTierCold:
+		return common.StorageClassArchive
+	case blob.AccessTierArchive:
+		return common.StorageClassDeepArchive
+	default:
+		return common.StorageClass(*tier)
+	}
+}
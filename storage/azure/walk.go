@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// WalkBucketContent calls fn for every blob (current versions only) in the container, one listing page at a time.
+// With a delimiter, the blob prefixes of a page are passed after its blobs.
+func (az *BlobStorageClient) WalkBucketContent(bucketName string, fn common.WalkFunc, opts ...common.ListOption) error {
+	o := common.NewListOptions(opts...)
+	if o.Delimiter != "" {
+		return az.walkHierarchy(bucketName, fn, o)
+	}
+
+	listOpts := &azblob.ListBlobsFlatOptions{}
+	if o.Prefix != "" {
+		listOpts.Prefix = &o.Prefix
+	}
+
+	pager := az.Client.NewListBlobsFlatPager(bucketName, listOpts)
+	for pager.More() {
+		resp, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		for _, item := range resp.Segment.BlobItems {
+			err = fn(listedObject(item))
+			if err == common.StopWalk {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkHierarchy walks the blobs and blob prefixes of a listing with a delimiter
+func (az *BlobStorageClient) walkHierarchy(bucketName string, fn common.WalkFunc, o common.ListOptions) error {
+	listOpts := &container.ListBlobsHierarchyOptions{}
+	if o.Prefix != "" {
+		listOpts.Prefix = &o.Prefix
+	}
+
+	pager := az.Client.ServiceClient().NewContainerClient(bucketName).NewListBlobsHierarchyPager(o.Delimiter, listOpts)
+	for pager.More() {
+		resp, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+
+		objects := []common.ObjectInfo{}
+		for _, item := range resp.Segment.BlobItems {
+			objects = append(objects, listedObject(item))
+		}
+		for _, prefix := range resp.Segment.BlobPrefixes {
+			objects = append(objects, common.ObjectInfo{Key: *prefix.Name, IsPrefix: true})
+		}
+		for _, object := range objects {
+			err = fn(object)
+			if err == common.StopWalk {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// listedObject returns the details of a blob in a listing
+func listedObject(item *container.BlobItem) common.ObjectInfo {
+	object := common.ObjectInfo{Key: *item.Name}
+	if props := item.Properties; props != nil {
+		if props.ContentLength != nil {
+			object.Size = *props.ContentLength
+		}
+		if props.LastModified != nil {
+			object.LastModified = *props.LastModified
+		}
+		if props.ETag != nil {
+			object.ETag = string(*props.ETag)
+		}
+	}
+	return object
+}
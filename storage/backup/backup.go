@@ -0,0 +1,353 @@
+// Package backup makes deduplicated snapshots of files in any storage.Storage: files are split into chunks with
+// content-defined chunking, and chunks are stored once in a content-addressable store (see storage/cas), so a
+// snapshot only uploads the chunks that changed since earlier snapshots, even when data shifted within a file.
+//
+//	repo := backup.New(cloudStorage, "backups", backup.WithPrefix("vms/"))
+//	snapshot, err := repo.Backup("/var/lib/libvirt/images")
+//	err = repo.Restore(snapshot.ID, "/tmp/restore")
+//	report, err := repo.Prune(backup.WithKeepLast(7))
+//
+// Each snapshot has a manifest listing its files and their chunks, stored as a blob tagged snapshots/<ID>.
+// Files are recorded by their absolute path without the leading separator (and volume), as tar does, and restored
+// below a target directory. Only regular files are backed up.
+//
+// A repository must not be pruned while a backup is running, as the chunks of the running backup aren't
+// referenced by a manifest yet.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/cas"
+	"golang.org/x/sync/errgroup"
+)
+
+// snapshotTagPrefix is the prefix of the tags of the snapshot manifests
+const snapshotTagPrefix = "snapshots/"
+
+// Snapshot is the manifest of a snapshot.
+type Snapshot struct {
+	ID    string    `json:"id"`
+	Time  time.Time `json:"time"`
+	Paths []string  `json:"paths"`
+	Files []File    `json:"files"`
+	// Size is the total size of the files
+	Size int64 `json:"size"`
+	// Uploaded is the size of the chunks that weren't stored yet
+	Uploaded int64 `json:"uploaded"`
+}
+
+// File is a file in a snapshot, its content is the concatenation of its chunks.
+type File struct {
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+	Chunks  []Chunk     `json:"chunks"`
+}
+
+type Chunk struct {
+	Digest cas.Digest `json:"digest"`
+	Size   int64      `json:"size"`
+}
+
+type options struct {
+	prefix                       string
+	minChunk, avgChunk, maxChunk int
+	concurrency                  int
+}
+
+type Option func(*options)
+
+// WithPrefix stores the repository below prefix, e.g. "backups/".
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithChunkSize sets the average chunk size, rounded down to a power of two; chunks are between a quarter and
+// four times the average. Changing the chunk size keeps chunks of earlier snapshots from being reused.
+func WithChunkSize(avg int) Option {
+	return func(o *options) {
+		avg = 1 << (max(bits.Len(uint(avg)), 7) - 1)
+		o.minChunk, o.avgChunk, o.maxChunk = avg/4, avg, avg*4
+	}
+}
+
+// WithConcurrency uploads up to n chunks at the same time, by default 4.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// Repository holds the snapshots and chunks in a bucket.
+type Repository struct {
+	store   *cas.Store
+	options options
+}
+
+// New returns the backup repository in the bucket.
+func New(cloudStorage storage.Storage, bucketName string, opts ...Option) *Repository {
+	o := options{
+		minChunk:    DefaultMinChunkSize,
+		avgChunk:    DefaultAvgChunkSize,
+		maxChunk:    DefaultMaxChunkSize,
+		concurrency: 4,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Repository{store: cas.New(cloudStorage, bucketName, cas.WithPrefix(o.prefix)), options: o}
+}
+
+// newSnapshotID returns a snapshot ID of the time, with a random suffix for snapshots made in the same second
+func newSnapshotID(t time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return t.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// snapshotPath returns the path a file is recorded with, its absolute slash-separated path without volume
+// and leading separator
+func snapshotPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	abs = strings.TrimPrefix(abs, filepath.VolumeName(abs))
+	return strings.TrimPrefix(filepath.ToSlash(abs), "/"), nil
+}
+
+// Backup makes a snapshot of the files and directories (recursively) at paths.
+// Files with the same size and modification time as in the latest snapshot aren't read again.
+func (r *Repository) Backup(paths ...string) (*Snapshot, error) {
+	snapshots, err := r.Snapshots()
+	if err != nil {
+		return nil, fmt.Errorf("Backup(%w)", err)
+	}
+	// chunks of existing snapshots are stored already
+	stored := map[cas.Digest]bool{}
+	previous := map[string]File{}
+	for _, snapshot := range snapshots {
+		for _, file := range snapshot.Files {
+			for _, chunk := range file.Chunks {
+				stored[chunk.Digest] = true
+			}
+			previous[file.Path] = file
+		}
+	}
+
+	now := time.Now()
+	snapshot := &Snapshot{ID: newSnapshotID(now), Time: now, Files: []File{}}
+	for _, path := range paths {
+		snapshot.Paths = append(snapshot.Paths, path)
+		err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			fileInfo, err := d.Info()
+			if err != nil {
+				return err
+			}
+			name, err := snapshotPath(path)
+			if err != nil {
+				return err
+			}
+
+			file := File{Path: name, Size: fileInfo.Size(), Mode: fileInfo.Mode().Perm(), ModTime: fileInfo.ModTime()}
+			if p, ok := previous[name]; ok && p.Size == file.Size && p.ModTime.Equal(file.ModTime) {
+				file.Chunks = p.Chunks
+			} else {
+				uploaded, err := r.backupFile(path, &file, stored)
+				if err != nil {
+					return err
+				}
+				snapshot.Uploaded += uploaded
+			}
+			snapshot.Files = append(snapshot.Files, file)
+			snapshot.Size += file.Size
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Backup(%w)", err)
+		}
+	}
+
+	manifest, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("Backup(%w)", err)
+	}
+	digest, err := r.store.Put(bytes.NewReader(manifest), storage.WithContentType("application/json"))
+	if err != nil {
+		return nil, fmt.Errorf("Backup(%w)", err)
+	}
+	if err := r.store.Tag(snapshotTagPrefix+snapshot.ID, digest); err != nil {
+		return nil, fmt.Errorf("Backup(%w)", err)
+	}
+	return snapshot, nil
+}
+
+// backupFile stores the chunks of a file that aren't stored yet, and returns their size
+func (r *Repository) backupFile(path string, file *File, stored map[cas.Digest]bool) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// the size is that of the content read, in case the file changed since it was stat'ed
+	file.Size = 0
+	uploaded := int64(0)
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(r.options.concurrency)
+	chunker := newChunker(f, r.options.minChunk, r.options.avgChunk, r.options.maxChunk)
+	// reading stops once an upload failed
+	for ctx.Err() == nil {
+		data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			g.Wait()
+			return 0, err
+		}
+
+		digest, _ := cas.ComputeDigest(bytes.NewReader(data))
+		file.Chunks = append(file.Chunks, Chunk{Digest: digest, Size: int64(len(data))})
+		file.Size += int64(len(data))
+		if stored[digest] {
+			continue
+		}
+		stored[digest] = true
+		uploaded += int64(len(data))
+		g.Go(func() error {
+			_, err := r.store.Put(bytes.NewReader(data))
+			return err
+		})
+	}
+	return uploaded, g.Wait()
+}
+
+// Snapshots returns the snapshots, oldest first.
+func (r *Repository) Snapshots() ([]*Snapshot, error) {
+	tags, err := r.store.Tags(snapshotTagPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("Snapshots(%w)", err)
+	}
+	snapshots := []*Snapshot{}
+	for _, digest := range tags {
+		snapshot, err := r.readSnapshot(digest)
+		if err != nil {
+			return nil, fmt.Errorf("Snapshots(%w)", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// Snapshot returns the snapshot with the ID, or ErrNotFound.
+func (r *Repository) Snapshot(id string) (*Snapshot, error) {
+	digest, err := r.store.Resolve(snapshotTagPrefix + id)
+	if err != nil {
+		return nil, fmt.Errorf("Snapshot(%s: %w)", id, err)
+	}
+	snapshot, err := r.readSnapshot(digest)
+	if err != nil {
+		return nil, fmt.Errorf("Snapshot(%s: %w)", id, err)
+	}
+	return snapshot, nil
+}
+
+func (r *Repository) readSnapshot(digest cas.Digest) (*Snapshot, error) {
+	rc, err := r.store.Get(digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(rc).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", digest, err)
+	}
+	return snapshot, nil
+}
+
+// Restore writes the files of the snapshot with the ID below targetDir, with their modes and modification times.
+// The chunks are verified against their digests while restored.
+func (r *Repository) Restore(id string, targetDir string) error {
+	snapshot, err := r.Snapshot(id)
+	if err != nil {
+		return fmt.Errorf("Restore(%w)", err)
+	}
+	for _, file := range snapshot.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			return fmt.Errorf("Restore(%s: invalid path '%s')", id, file.Path)
+		}
+		if err := r.restoreFile(file, filepath.Join(targetDir, filepath.FromSlash(file.Path))); err != nil {
+			return fmt.Errorf("Restore(%s: %w)", id, err)
+		}
+	}
+	return nil
+}
+
+// restoreFile writes a file to a temporary file next to fileName, renamed once complete
+func (r *Repository) restoreFile(file File, fileName string) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = r.writeChunks(tmp, file.Chunks)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", file.Path, err)
+	}
+	if err := os.Chmod(tmp.Name(), file.Mode.Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), file.ModTime, file.ModTime); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+func (r *Repository) writeChunks(w io.Writer, chunks []Chunk) error {
+	for _, chunk := range chunks {
+		rc, err := r.store.Get(chunk.Digest)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(w, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if n != chunk.Size {
+			return fmt.Errorf("chunk %s: read %d bytes, want %d", chunk.Digest, n, chunk.Size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+)
+
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func chunks(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	c := newChunker(bytes.NewReader(data), 1<<10, 4<<10, 16<<10)
+	result := [][]byte{}
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, chunk)
+	}
+}
+
+func TestChunker(t *testing.T) {
+	data := randomData(1, 1<<20)
+	original := chunks(t, data)
+	if joined := bytes.Join(original, nil); !bytes.Equal(joined, data) {
+		t.Fatal("chunks don't add up to the content")
+	}
+	for i, chunk := range original {
+		if len(chunk) > 16<<10 || (len(chunk) < 1<<10 && i < len(original)-1) {
+			t.Errorf("chunk %d has size %d, want 1-16 KiB", i, len(chunk))
+		}
+	}
+	if n := len(original); n < 128 || n > 512 {
+		t.Errorf("%d chunks, want about 256", n)
+	}
+
+	// an insert only changes the chunks around it
+	shifted := append(append(append([]byte{}, data[:5000]...), "inserted"...), data[5000:]...)
+	seen := map[string]bool{}
+	for _, chunk := range original {
+		seen[string(chunk)] = true
+	}
+	changed := 0
+	for _, chunk := range chunks(t, shifted) {
+		if !seen[string(chunk)] {
+			changed++
+		}
+	}
+	if changed > 3 {
+		t.Errorf("%d chunks changed by an insert, want at most 3", changed)
+	}
+}
+
+func newRepository(t *testing.T) (*Repository, storage.Storage) {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("backups"); err != nil {
+		t.Fatal(err)
+	}
+	return New(m, "backups", WithPrefix("repo/"), WithChunkSize(4<<10)), m
+}
+
+func writeFile(t *testing.T, fileName string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileName, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(fileName, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkRestore(t *testing.T, r *Repository, id string, source string, want map[string][]byte) {
+	t.Helper()
+	target := t.TempDir()
+	if err := r.Restore(id, target); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := r.Snapshot(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]File{}
+	for _, file := range snapshot.Files {
+		files[file.Path] = file
+	}
+	rel, _ := snapshotPath(source)
+	for name, data := range want {
+		fileName := filepath.Join(target, filepath.FromSlash(rel), name)
+		restored, err := os.ReadFile(fileName)
+		if err != nil || !bytes.Equal(restored, data) {
+			t.Errorf("restored %s differs: %v", name, err)
+			continue
+		}
+		file := files[rel+"/"+name]
+		fileInfo, _ := os.Stat(fileName)
+		if fileInfo.Mode() != 0o640 || !fileInfo.ModTime().Equal(file.ModTime) {
+			t.Errorf("restored %s has mode %v and time %v, want %v and %v", name, fileInfo.Mode(), fileInfo.ModTime(), os.FileMode(0o640), file.ModTime)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	r, _ := newRepository(t)
+	source := t.TempDir()
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	image := randomData(2, 256<<10)
+	writeFile(t, filepath.Join(source, "disk.img"), image, yesterday)
+	writeFile(t, filepath.Join(source, "conf", "vm.xml"), []byte("<domain/>"), yesterday)
+	writeFile(t, filepath.Join(source, "empty"), nil, yesterday)
+
+	first, err := r.Backup(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Files) != 3 || first.Size != int64(len(image))+9 || first.Uploaded != first.Size {
+		t.Errorf("first snapshot has %d files, size %d, uploaded %d", len(first.Files), first.Size, first.Uploaded)
+	}
+
+	// a few bytes inserted in the image only upload the chunks around the change
+	changed := append(append(append([]byte{}, image[:100<<10]...), "patch"...), image[100<<10:]...)
+	writeFile(t, filepath.Join(source, "disk.img"), changed, time.Now().Truncate(time.Second))
+	second, err := r.Backup(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Uploaded == 0 || second.Uploaded > 64<<10 {
+		t.Errorf("second snapshot uploaded %d bytes, want a few chunks", second.Uploaded)
+	}
+
+	// unchanged files aren't read again
+	third, err := r.Backup(source)
+	if err != nil || third.Uploaded != 0 {
+		t.Errorf("third snapshot uploaded %d bytes, %v, want nothing", third.Uploaded, err)
+	}
+
+	snapshots, err := r.Snapshots()
+	if err != nil || len(snapshots) != 3 || snapshots[0].ID != first.ID || snapshots[2].ID != third.ID {
+		t.Fatalf("Snapshots() = %d snapshots, %v, want 3 in order", len(snapshots), err)
+	}
+	checkRestore(t, r, first.ID, source, map[string][]byte{"disk.img": image, "conf/vm.xml": []byte("<domain/>"), "empty": {}})
+	checkRestore(t, r, second.ID, source, map[string][]byte{"disk.img": changed})
+
+	if err := r.Restore("missing", t.TempDir()); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Restore(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	r, m := newRepository(t)
+	source := t.TempDir()
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		writeFile(t, filepath.Join(source, "data"), randomData(int64(i), 32<<10), time.Now().Add(time.Duration(i)*time.Hour))
+		snapshot, err := r.Backup(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, snapshot.ID)
+	}
+	blobs := func() int {
+		keys, err := m.ListBucketContent("backups")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(keys)
+	}
+	before := blobs()
+
+	report, err := r.Prune(WithKeepLast(1), WithGracePeriod(0), WithDryRun())
+	if err != nil || len(report.Removed) != 2 || !report.Deleted.DryRun || blobs() != before {
+		t.Fatalf("Prune(dry run) = %+v, %v", report, err)
+	}
+
+	report, err = r.Prune(WithKeepLast(1), WithGracePeriod(0))
+	if err != nil || len(report.Removed) != 2 || report.Removed[0] != ids[0] || len(report.Deleted.Deleted()) == 0 {
+		t.Fatalf("Prune() = %+v, %v", report, err)
+	}
+	snapshots, err := r.Snapshots()
+	if err != nil || len(snapshots) != 1 || snapshots[0].ID != ids[2] {
+		t.Fatalf("Snapshots() after prune = %d snapshots, %v", len(snapshots), err)
+	}
+	if after := blobs(); after != before-len(report.Deleted.Deleted())-2 {
+		t.Errorf("%d objects after prune, want %d", after, before-len(report.Deleted.Deleted())-2)
+	}
+	checkRestore(t, r, ids[2], source, map[string][]byte{"data": randomData(2, 32<<10)})
+
+	if err := r.DeleteSnapshot(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Prune(WithGracePeriod(0)); err != nil {
+		t.Fatal(err)
+	}
+	if after := blobs(); after != 0 {
+		t.Errorf("%d objects after deleting all snapshots, want 0", after)
+	}
+}
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// Default chunk sizes: chunks are 1 MiB on average, between 256 KiB and 4 MiB
+const (
+	DefaultMinChunkSize = 256 << 10
+	DefaultAvgChunkSize = 1 << 20
+	DefaultMaxChunkSize = 4 << 20
+)
+
+// gear maps bytes to the random values of the rolling gear hash. It is generated from a fixed seed, as chunk
+// boundaries (and so deduplication against earlier snapshots) depend on it.
+var gear = func() [256]uint64 {
+	table := [256]uint64{}
+	state := uint64(0x636c6f7564636f6e) // splitmix64
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunker splits content at content-defined boundaries (FastCDC with normalized chunking): a boundary is
+// where the gear hash of the preceding bytes has its top bits zero, so an insert or delete only changes the chunks
+// around it. Before the average size a stricter mask is used, after it a looser one, narrowing the size distribution.
+type chunker struct {
+	reader        io.Reader
+	buf           []byte
+	eof           bool
+	min, avg, max int
+	maskS, maskL  uint64
+}
+
+func newChunker(reader io.Reader, min, avg, max int) *chunker {
+	// the number of hash bits that must be zero for a boundary, on average every 2^bits bytes
+	avgBits := bits.Len(uint(avg)) - 1
+	return &chunker{
+		reader: reader,
+		buf:    make([]byte, 0, max),
+		min:    min,
+		avg:    avg,
+		max:    max,
+		maskS:  ^uint64(0) << (64 - (avgBits + 1)),
+		maskL:  ^uint64(0) << (64 - (avgBits - 1)),
+	}
+}
+
+// Next returns the next chunk, or io.EOF after the last one. The chunk remains valid after the next call.
+func (c *chunker) Next() ([]byte, error) {
+	if !c.eof && len(c.buf) < c.max {
+		n, err := io.ReadFull(c.reader, c.buf[len(c.buf):c.max])
+		c.buf = c.buf[:len(c.buf)+n]
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+
+	cut := c.cut(c.buf)
+	chunk := make([]byte, cut)
+	copy(chunk, c.buf)
+	c.buf = c.buf[:copy(c.buf, c.buf[cut:])]
+	return chunk, nil
+}
+
+// cut returns the length of the chunk at the start of data
+func (c *chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.min {
+		return n
+	}
+	normal := min(c.avg, n)
+	fp := uint64(0)
+	i := c.min
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
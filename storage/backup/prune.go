@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/cas"
+)
+
+type PruneOptions struct {
+	// KeepLast keeps the KeepLast most recent snapshots.
+	KeepLast int
+	// KeepWithin keeps the snapshots made within KeepWithin before now.
+	KeepWithin time.Duration
+	// GracePeriod keeps chunks younger than GracePeriod, see cas.GCOptions.
+	GracePeriod time.Duration
+	// DryRun reports what would be removed, without removing anything.
+	DryRun bool
+}
+
+type PruneOption func(*PruneOptions)
+
+func WithKeepLast(n int) PruneOption {
+	return func(o *PruneOptions) {
+		o.KeepLast = n
+	}
+}
+
+func WithKeepWithin(d time.Duration) PruneOption {
+	return func(o *PruneOptions) {
+		o.KeepWithin = d
+	}
+}
+
+func WithGracePeriod(gracePeriod time.Duration) PruneOption {
+	return func(o *PruneOptions) {
+		o.GracePeriod = gracePeriod
+	}
+}
+
+func WithDryRun() PruneOption {
+	return func(o *PruneOptions) {
+		o.DryRun = true
+	}
+}
+
+// PruneReport lists the snapshots and chunks removed by Prune.
+type PruneReport struct {
+	// Removed holds the IDs of the snapshots that weren't kept
+	Removed []string
+	// Deleted reports the chunks and manifests that were deleted, as no kept snapshot refers to them
+	Deleted *storage.DeleteReport
+}
+
+// DeleteSnapshot removes the snapshot with the ID, its chunks are deleted by the next Prune if no other snapshot
+// refers to them.
+func (r *Repository) DeleteSnapshot(id string) error {
+	if _, err := r.store.Resolve(snapshotTagPrefix + id); err != nil {
+		return fmt.Errorf("DeleteSnapshot(%s: %w)", id, err)
+	}
+	if err := r.store.Untag(snapshotTagPrefix + id); err != nil {
+		return fmt.Errorf("DeleteSnapshot(%s: %w)", id, err)
+	}
+	return nil
+}
+
+// Prune removes the snapshots that the keep options (WithKeepLast, WithKeepWithin) don't keep, all snapshots are
+// kept if none is given. Then the chunks and manifests no kept snapshot refers to are deleted.
+func (r *Repository) Prune(opts ...PruneOption) (*PruneReport, error) {
+	o := PruneOptions{GracePeriod: cas.DefaultGracePeriod}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	snapshots, err := r.Snapshots()
+	if err != nil {
+		return nil, fmt.Errorf("Prune(%w)", err)
+	}
+	keepAll := o.KeepLast <= 0 && o.KeepWithin <= 0
+	cutoff := time.Now().Add(-o.KeepWithin)
+	report := &PruneReport{Removed: []string{}}
+	kept := []*Snapshot{}
+	for i, snapshot := range snapshots {
+		// snapshots are sorted oldest first
+		if keepAll || len(snapshots)-i <= o.KeepLast || (o.KeepWithin > 0 && snapshot.Time.After(cutoff)) {
+			kept = append(kept, snapshot)
+			continue
+		}
+		report.Removed = append(report.Removed, snapshot.ID)
+		if !o.DryRun {
+			if err := r.store.Untag(snapshotTagPrefix + snapshot.ID); err != nil {
+				return report, fmt.Errorf("Prune(%w)", err)
+			}
+		}
+	}
+
+	gcOpts := []cas.GCOption{
+		cas.WithGracePeriod(o.GracePeriod),
+		// the chunks of the kept snapshots; in a dry run the manifests of removed snapshots are still tagged
+		cas.WithReferences(func(add func(cas.Digest)) error {
+			for _, snapshot := range kept {
+				for _, file := range snapshot.Files {
+					for _, chunk := range file.Chunks {
+						add(chunk.Digest)
+					}
+				}
+			}
+			return nil
+		}),
+	}
+	if o.DryRun {
+		gcOpts = append(gcOpts, cas.WithDryRun())
+	}
+	report.Deleted, err = r.store.GC(gcOpts...)
+	if err != nil {
+		return report, fmt.Errorf("Prune(%w)", err)
+	}
+	return report, nil
+}
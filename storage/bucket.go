@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"io"
+	"time"
+)
+
+// Bucket is a Storage bound to a single bucket, as returned by Open.
+type Bucket struct {
+	Storage Storage
+	Name    string
+}
+
+func (b *Bucket) ListContent() ([]string, error) {
+	return b.Storage.ListBucketContent(b.Name)
+}
+
+func (b *Bucket) WalkContent(fn WalkFunc, opts ...ListOption) error {
+	return b.Storage.WalkBucketContent(b.Name, fn, opts...)
+}
+
+func (b *Bucket) StoreObject(objectKey string, fileName string, opts ...PutOption) error {
+	return b.Storage.StoreObject(b.Name, objectKey, fileName, opts...)
+}
+
+func (b *Bucket) RetrieveObject(objectKey string, fileName string, opts ...GetOption) error {
+	return b.Storage.RetrieveObject(b.Name, objectKey, fileName, opts...)
+}
+
+func (b *Bucket) PutObject(objectKey string, reader io.Reader, opts ...PutOption) error {
+	return b.Storage.PutObject(b.Name, objectKey, reader, opts...)
+}
+
+func (b *Bucket) GetObject(objectKey string, opts ...GetOption) (io.ReadCloser, error) {
+	return b.Storage.GetObject(b.Name, objectKey, opts...)
+}
+
+func (b *Bucket) StatObject(objectKey string, opts ...GetOption) (ObjectInfo, error) {
+	return b.Storage.StatObject(b.Name, objectKey, opts...)
+}
+
+func (b *Bucket) PresignURL(objectKey string, method string, expiry time.Duration) (string, error) {
+	return b.Storage.PresignURL(b.Name, objectKey, method, expiry)
+}
+
+func (b *Bucket) DeleteObject(objectKeys []string) error {
+	return b.Storage.DeleteObject(b.Name, objectKeys)
+}
+
+func (b *Bucket) DeleteObjects(objectKeys []string) (*DeleteReport, error) {
+	return b.Storage.DeleteObjects(b.Name, objectKeys)
+}
+
+func (b *Bucket) SetStorageClass(objectKey string, class StorageClass, opts ...GetOption) error {
+	return b.Storage.SetStorageClass(b.Name, objectKey, class, opts...)
+}
@@ -0,0 +1,342 @@
+// Package bucketfs exposes a bucket as an io/fs file system, for html/template.ParseFS, http.FileServer(http.FS(...)),
+// fs.WalkDir, fs.Glob and the like.
+//
+//	fsys := bucketfs.New(cloudStorage, "website")
+//	http.Handle("/", http.FileServer(http.FS(fsys)))
+//	templates, err := template.ParseFS(fsys, "templates/*.html")
+//
+// Object keys are paths with "/" separators: directories are the common prefixes of a delimiter listing
+// (storage.WithDelimiter), files are stat'ed with StatObject. Keys that aren't valid fs paths (see fs.ValidPath),
+// such as keys with a leading "/" or "//", can't be opened; directory marker objects (keys ending with "/") are
+// listed as their directory. The file system is read-only.
+package bucketfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// FS is a read-only file system of the objects in a bucket.
+type FS struct {
+	storage    storage.Storage
+	bucketName string
+}
+
+var (
+	_ fs.FS         = &FS{}
+	_ fs.ReadDirFS  = &FS{}
+	_ fs.StatFS     = &FS{}
+	_ fs.ReadFileFS = &FS{}
+)
+
+// New returns the file system of the objects in the bucket, see fs.Sub for the file system of a prefix.
+func New(cloudStorage storage.Storage, bucketName string) *FS {
+	return &FS{storage: cloudStorage, bucketName: bucketName}
+}
+
+// pathError returns err as returned by the fs functions, with ErrNotFound mapped to fs.ErrNotExist
+func pathError(op string, name string, err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// dirPrefix returns the listing prefix of the directory name
+func dirPrefix(name string) string {
+	if name == "." {
+		return ""
+	}
+	return name + "/"
+}
+
+// Open opens the object or directory name. Files implement io.Seeker, seeking reads the object again from
+// the offset on (storage.WithRange).
+func (fsys *FS) Open(name string) (fs.File, error) {
+	info, err := fsys.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{fsys: fsys, name: name, info: info}, nil
+	}
+	return &file{fsys: fsys, name: name, info: info}, nil
+}
+
+// Stat returns the file info of the object or directory name.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	return fsys.stat("stat", name)
+}
+
+func (fsys *FS) stat(op string, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathError(op, name, fs.ErrInvalid)
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	object, err := fsys.storage.StatObject(fsys.bucketName, name)
+	if err == nil {
+		return &fileInfo{name: path.Base(name), object: object}, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, pathError(op, name, err)
+	}
+
+	// a directory has at least one object below it
+	found := false
+	err = fsys.storage.WalkBucketContent(fsys.bucketName, func(object storage.ObjectInfo) error {
+		found = true
+		return storage.StopWalk
+	}, storage.WithPrefix(dirPrefix(name)))
+	if err != nil {
+		return nil, pathError(op, name, err)
+	}
+	if !found {
+		return nil, pathError(op, name, fs.ErrNotExist)
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir returns the files and directories in the directory name, sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathError("readdir", name, fs.ErrInvalid)
+	}
+
+	prefix := dirPrefix(name)
+	entries := []fs.DirEntry{}
+	found := false
+	err := fsys.storage.WalkBucketContent(fsys.bucketName, func(object storage.ObjectInfo) error {
+		found = true
+		entryName := strings.TrimSuffix(strings.TrimPrefix(object.Key, prefix), "/")
+		// the directory marker, or keys that aren't valid paths
+		if entryName == "" || !fs.ValidPath(entryName) {
+			return nil
+		}
+		if object.IsPrefix {
+			entries = append(entries, &fileInfo{name: entryName, dir: true})
+		} else if !strings.HasSuffix(object.Key, "/") {
+			entries = append(entries, &fileInfo{name: entryName, object: object})
+		}
+		return nil
+	}, storage.WithPrefix(prefix), storage.WithDelimiter("/"))
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+	if !found && name != "." {
+		if _, err := fsys.storage.StatObject(fsys.bucketName, name); err == nil {
+			return nil, pathError("readdir", name, errors.New("not a directory"))
+		}
+		return nil, pathError("readdir", name, fs.ErrNotExist)
+	}
+
+	// an object "a" hides the directory of the objects "a/...", as in Stat
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name() == entries[j].Name() {
+			return !entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+	unique := []fs.DirEntry{}
+	for _, entry := range entries {
+		if n := len(unique); n == 0 || unique[n-1].Name() != entry.Name() {
+			unique = append(unique, entry)
+		}
+	}
+	return unique, nil
+}
+
+// ReadFile returns the content of the object name.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathError("readfile", name, fs.ErrInvalid)
+	}
+	rc, err := fsys.storage.GetObject(fsys.bucketName, name)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	return content, nil
+}
+
+// fileInfo is the fs.FileInfo and fs.DirEntry of an object or directory
+type fileInfo struct {
+	name   string
+	dir    bool
+	object storage.ObjectInfo
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.object.Size
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// ModTime is truncated to seconds, the precision of StatObject on S3, to match the more precise listings
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.object.LastModified.Truncate(time.Second)
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+// Sys returns the storage.ObjectInfo of an object, as listed or stat'ed
+func (fi *fileInfo) Sys() any {
+	if fi.dir {
+		return nil
+	}
+	return fi.object
+}
+
+func (fi *fileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+func (fi *fileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+func (fi *fileInfo) String() string {
+	return fs.FormatFileInfo(fi)
+}
+
+// file reads an object, from the first Read on
+type file struct {
+	fsys   *FS
+	name   string
+	info   *fileInfo
+	rc     io.ReadCloser
+	pos    int64 // position of rc
+	offset int64 // position of the next Read
+	closed bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, pathError("read", f.name, fs.ErrClosed)
+	}
+	if f.rc != nil && f.pos != f.offset {
+		f.rc.Close()
+		f.rc = nil
+	}
+	if f.rc == nil {
+		// a read ends at the end of the object's content, the stat'ed size may differ (wrappers, replaced objects);
+		// it only keeps reads seeked past the end from requesting a range providers reject
+		if f.offset > 0 && f.offset >= f.info.Size() {
+			return 0, io.EOF
+		}
+		rc, err := f.fsys.storage.GetObject(f.fsys.bucketName, f.name, storage.WithRange(f.offset, 0))
+		if err != nil {
+			return 0, pathError("read", f.name, err)
+		}
+		f.rc, f.pos = rc, f.offset
+	}
+
+	n, err := f.rc.Read(p)
+	f.pos += int64(n)
+	f.offset = f.pos
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, pathError("seek", f.name, fs.ErrClosed)
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, pathError("seek", f.name, fs.ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, pathError("seek", f.name, fs.ErrInvalid)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return pathError("close", f.name, fs.ErrClosed)
+	}
+	f.closed = true
+	if f.rc != nil {
+		return f.rc.Close()
+	}
+	return nil
+}
+
+// dir lists a directory on the first ReadDir
+type dir struct {
+	fsys    *FS
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	listed  bool
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, pathError("read", d.name, errors.New("is a directory"))
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.listed = entries, true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = d.entries[len(d.entries):]
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
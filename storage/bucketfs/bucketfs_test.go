@@ -0,0 +1,151 @@
+package bucketfs
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+)
+
+func newFS(t *testing.T, keys ...string) *FS {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if err := m.PutObject("bucket", key, strings.NewReader("content of "+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(m, "bucket")
+}
+
+func TestFS(t *testing.T) {
+	fsys := newFS(t, "index.html", "css/site.css", "docs/a/1.txt", "docs/a/2.txt", "docs/b.txt", "empty/")
+	if err := fstest.TestFS(fsys, "index.html", "css/site.css", "docs/a/1.txt", "docs/a/2.txt", "docs/b.txt", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := fs.Sub(fsys, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(sub, "a/1.txt", "a/2.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotExist(t *testing.T) {
+	fsys := newFS(t, "dir/file")
+
+	if _, err := fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing) = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.ReadFile("dir/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile(missing) = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.ReadDir("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir(missing) = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.ReadDir("dir/file"); err == nil {
+		t.Error("ReadDir(file) should fail")
+	}
+	if _, err := fsys.Stat("/dir"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Stat(/dir) = %v, want fs.ErrInvalid", err)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	fsys := newFS(t, "file")
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	seeker := f.(io.ReadSeeker)
+	if size, err := seeker.Seek(0, io.SeekEnd); err != nil || size != int64(len("content of file")) {
+		t.Errorf("Seek(end) = %d, %v", size, err)
+	}
+	seeker.Seek(-4, io.SeekEnd)
+	if content, err := io.ReadAll(seeker); err != nil || string(content) != "file" {
+		t.Errorf("read from end-4 = '%s', %v, want 'file'", content, err)
+	}
+	seeker.Seek(0, io.SeekStart)
+	if content, err := io.ReadAll(seeker); err != nil || string(content) != "content of file" {
+		t.Errorf("read from start = '%s', %v", content, err)
+	}
+}
+
+// shortStat reports objects 4 bytes shorter than their content, as a wrapper may
+type shortStat struct {
+	storage.Wrapper
+}
+
+func (s shortStat) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := s.Next.StatObject(bucketName, objectKey, opts...)
+	info.Size -= 4
+	return info, err
+}
+
+func TestReadToContentEnd(t *testing.T) {
+	fsys := New(shortStat{storage.Wrapper{Next: newFS(t, "file").storage}}, "bucket")
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// reads of a byte, so the content isn't read by the first Read
+	content, err := io.ReadAll(iotest.OneByteReader(f))
+	if err != nil || string(content) != "content of file" {
+		t.Errorf("ReadAll = '%s', %v, want the whole content", content, err)
+	}
+}
+
+func TestFileServerAndTemplates(t *testing.T) {
+	fsys := newFS(t, "static/style.css", "templates/page.html")
+
+	server := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/static/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "content of static/style.css" || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/css") {
+		t.Errorf("GET /static/style.css = %d %s '%s'", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	templates, err := template.ParseFS(fsys, "templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if templates.Lookup("page.html") == nil {
+		t.Error("template page.html not parsed")
+	}
+}
+
+// an object and a directory with the same name list as the object
+func TestFileHidesDir(t *testing.T) {
+	fsys := newFS(t, "a", "a/b")
+	entries, err := fsys.ReadDir(".")
+	if err != nil || len(entries) != 1 || entries[0].IsDir() {
+		t.Fatalf("ReadDir(.) = %v, %v, want file a", entries, err)
+	}
+	info, _ := entries[0].Info()
+	if object, ok := info.Sys().(storage.ObjectInfo); !ok || object.Key != "a" {
+		t.Errorf("Sys() = %v, want the storage.ObjectInfo of a", info.Sys())
+	}
+}
@@ -0,0 +1,229 @@
+// Package cache keeps the content of objects read from a storage.Storage on local disk, so objects that didn't
+// change are served locally instead of being downloaded again.
+//
+//	cached := cache.New(cloudStorage, "/var/cache/cloud-connect", cache.WithMaxSize(10<<30), cache.WithTTL(time.Minute))
+//
+// GetObject and RetrieveObject fill the cache while the content is read. Cached objects are validated with a
+// conditional read (storage.WithIfNoneMatch) of their ETag, which only downloads the object if it changed;
+// within the TTL they are served without validation. The least recently used objects are evicted when the cache
+// exceeds its size limit.
+//
+// Writes and deletes through the cache invalidate the objects they change, changes made by other clients are
+// detected on validation. The cache directory must only be used by one process at a time. Reads with a customer
+// key (storage.WithCustomerKey) aren't cached.
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// DefaultMaxSize is the size limit of the cache if WithMaxSize isn't given
+const DefaultMaxSize int64 = 1 << 30
+
+type options struct {
+	maxSize int64
+	ttl     time.Duration
+}
+
+type Option func(*options)
+
+// WithMaxSize limits the total size of the cached objects in bytes, by default DefaultMaxSize.
+// Objects larger than the limit aren't cached.
+func WithMaxSize(maxSize int64) Option {
+	return func(o *options) {
+		o.maxSize = maxSize
+	}
+}
+
+// WithTTL serves cached objects without validating them for ttl after they were last validated.
+// By default objects are validated on every read.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// Cache is a storage.Storage serving objects from a local disk cache when they didn't change in the storage it wraps.
+type Cache struct {
+	storage.Wrapper
+	options options
+	store   *store
+}
+
+var _ storage.Storage = &Cache{}
+
+// New caches the objects read from next in dir, created if it doesn't exist.
+// The cache is best effort: if dir can't be used, objects are read from next.
+func New(next storage.Storage, dir string, opts ...Option) *Cache {
+	o := options{maxSize: DefaultMaxSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Cache{Wrapper: storage.Wrapper{Next: next}, options: o, store: newStore(dir, o.maxSize)}
+}
+
+// Middleware returns a storage.Middleware caching the objects read from the wrapped storage in dir.
+func Middleware(dir string, opts ...Option) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return New(next, dir, opts...)
+	}
+}
+
+// GetObject serves the object from the cache if it is within the TTL or didn't change, otherwise the object
+// is read from the wrapped storage and cached while read. Conditional reads (storage.WithIfNoneMatch), reads of
+// the object info (storage.WithObjectInfo), ranged reads (storage.WithRange), raw reads (storage.WithRawContent)
+// and reads with a customer key go to the wrapped storage.
+func (c *Cache) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	o := storage.NewGetOptions(opts...)
+	if o.IfNoneMatch != "" || o.Info != nil || o.Ranged() || o.RawContent || len(o.Encryption.CustomerKey) > 0 || c.store.load() != nil {
+		return c.Next.GetObject(bucketName, objectKey, opts...)
+	}
+
+	cached, ok := c.store.lookup(bucketName, objectKey)
+	if ok && time.Since(cached.Validated) < c.options.ttl {
+		if rc, ok := c.serve(cached, o.Progress); ok {
+			return rc, nil
+		}
+	}
+
+	info := storage.ObjectInfo{}
+	getOpts := append(opts[:len(opts):len(opts)], storage.WithObjectInfo(&info))
+	if ok {
+		getOpts = append(getOpts, storage.WithIfNoneMatch(cached.ETag))
+	}
+	c.store.beginFill(bucketName, objectKey)
+	rc, err := c.Next.GetObject(bucketName, objectKey, getOpts...)
+	if errors.Is(err, storage.ErrNotModified) {
+		c.store.endFill(bucketName, objectKey, "", "", 0)
+		c.store.validated(bucketName, objectKey, cached.ETag)
+		if rc, ok := c.serve(cached, o.Progress); ok {
+			return rc, nil
+		}
+		// evicted or invalidated meanwhile
+		return c.Next.GetObject(bucketName, objectKey, opts...)
+	}
+	if err != nil {
+		c.store.endFill(bucketName, objectKey, "", "", 0)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.store.invalidate(bucketName, objectKey)
+		}
+		return nil, err
+	}
+	return c.fill(bucketName, objectKey, info, rc), nil
+}
+
+// serve opens the content of a cached object, if it is still cached
+func (c *Cache) serve(cached entry, progress storage.ProgressFunc) (io.ReadCloser, bool) {
+	f, ok := c.store.open(cached.Bucket, cached.Key, cached.ETag)
+	if !ok {
+		return nil, false
+	}
+	if progress != nil {
+		return storage.NewProgressReadCloser(f, cached.Size, progress), true
+	}
+	return f, true
+}
+
+// fill returns a reader of rc, writing the content to a temporary file that is added to the cache once
+// the content is read completely
+func (c *Cache) fill(bucketName string, objectKey string, info storage.ObjectInfo, rc io.ReadCloser) io.ReadCloser {
+	if info.ETag == "" || info.Size > c.options.maxSize {
+		c.store.endFill(bucketName, objectKey, "", "", 0)
+		return rc
+	}
+	tmp, err := os.CreateTemp(c.store.dir, "tmp-*")
+	if err != nil {
+		c.store.endFill(bucketName, objectKey, "", "", 0)
+		return rc
+	}
+	return &fillingReader{ReadCloser: rc, cache: c, bucketName: bucketName, objectKey: objectKey, info: info, tmp: tmp}
+}
+
+// fillingReader copies the content read to the temporary file of a cache fill
+type fillingReader struct {
+	io.ReadCloser
+	cache      *Cache
+	bucketName string
+	objectKey  string
+	info       storage.ObjectInfo
+	tmp        *os.File
+	written    int64
+}
+
+func (r *fillingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if r.tmp == nil {
+		return n, err
+	}
+	if n > 0 {
+		w, writeErr := r.tmp.Write(p[:n])
+		r.written += int64(w)
+		if writeErr != nil || r.written > r.cache.options.maxSize {
+			r.end(false)
+			return n, err
+		}
+	}
+	if err == io.EOF {
+		r.end(r.written == r.info.Size)
+	} else if err != nil {
+		r.end(false)
+	}
+	return n, err
+}
+
+// end ends the fill, adding the temporary file to the cache if complete
+func (r *fillingReader) end(complete bool) {
+	closeErr := r.tmp.Close()
+	if complete && closeErr == nil {
+		r.cache.store.endFill(r.bucketName, r.objectKey, r.info.ETag, r.tmp.Name(), r.written)
+	} else {
+		os.Remove(r.tmp.Name())
+		r.cache.store.endFill(r.bucketName, r.objectKey, "", "", 0)
+	}
+	r.tmp = nil
+}
+
+func (r *fillingReader) Close() error {
+	if r.tmp != nil {
+		r.end(false)
+	}
+	return r.ReadCloser.Close()
+}
+
+func (c *Cache) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...storage.GetOption) error {
+	rc, err := c.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(rc, fileName)
+}
+
+func (c *Cache) StoreObject(bucketName string, objectKey string, fileName string, opts ...storage.PutOption) error {
+	defer c.store.invalidate(bucketName, objectKey)
+	return c.Next.StoreObject(bucketName, objectKey, fileName, opts...)
+}
+
+func (c *Cache) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	defer c.store.invalidate(bucketName, objectKey)
+	return c.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func (c *Cache) DeleteObject(bucketName string, objectKeys []string) error {
+	defer c.store.invalidate(bucketName, objectKeys...)
+	return c.Next.DeleteObject(bucketName, objectKeys)
+}
+
+func (c *Cache) DeleteObjects(bucketName string, objectKeys []string) (*storage.DeleteReport, error) {
+	defer c.store.invalidate(bucketName, objectKeys...)
+	return c.Next.DeleteObjects(bucketName, objectKeys)
+}
+
+func (c *Cache) DeleteBucket(bucketName string, opts ...storage.DeleteBucketOption) error {
+	defer c.store.invalidateBucket(bucketName)
+	return c.Next.DeleteBucket(bucketName, opts...)
+}
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New(memory.NewMemoryClient(), t.TempDir())
+	})
+}
+
+// counting counts the downloads and conditional reads answered with ErrNotModified
+type counting struct {
+	storage.Wrapper
+	downloads   int
+	notModified int
+}
+
+func (c *counting) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	rc, err := c.Next.GetObject(bucketName, objectKey, opts...)
+	if err == nil {
+		c.downloads++
+	}
+	if errors.Is(err, storage.ErrNotModified) {
+		c.notModified++
+	}
+	return rc, err
+}
+
+func newCounting(t *testing.T) *counting {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	return &counting{Wrapper: storage.Wrapper{Next: m}}
+}
+
+func put(t *testing.T, s storage.Storage, objectKey string, content string) {
+	t.Helper()
+	if err := s.PutObject("bucket", objectKey, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, s storage.Storage, objectKey string, want string) {
+	t.Helper()
+	rc, err := s.GetObject("bucket", objectKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != want {
+		t.Errorf("GetObject(%s) = '%s', want '%s'", objectKey, content, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	next := newCounting(t)
+	c := New(next, t.TempDir())
+	put(t, c, "object", "data")
+
+	get(t, c, "object", "data")
+	get(t, c, "object", "data")
+	get(t, c, "object", "data")
+	if next.downloads != 1 || next.notModified != 2 {
+		t.Errorf("downloads = %d, not modified = %d, want 1 and 2", next.downloads, next.notModified)
+	}
+
+	// changed by another client
+	put(t, next, "object", "changed")
+	get(t, c, "object", "changed")
+	get(t, c, "object", "changed")
+	if next.downloads != 2 || next.notModified != 3 {
+		t.Errorf("downloads = %d, not modified = %d, want 2 and 3", next.downloads, next.notModified)
+	}
+
+	// deleted by another client
+	if err := next.DeleteObject("bucket", []string{"object"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetObject("bucket", "object"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetObject(deleted) = %v, want ErrNotFound", err)
+	}
+	if _, ok := c.store.lookup("bucket", "object"); ok {
+		t.Error("deleted object is still cached")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	next := newCounting(t)
+	c := New(next, t.TempDir(), WithTTL(time.Hour))
+	put(t, c, "object", "data")
+
+	get(t, c, "object", "data")
+	get(t, c, "object", "data")
+	if next.downloads != 1 || next.notModified != 0 {
+		t.Errorf("downloads = %d, not modified = %d, want 1 and 0", next.downloads, next.notModified)
+	}
+
+	// writes through the cache invalidate the object
+	put(t, c, "object", "changed")
+	get(t, c, "object", "changed")
+	if err := c.DeleteObject("bucket", []string{"object"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetObject("bucket", "object"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetObject(deleted) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPartialRead(t *testing.T) {
+	next := newCounting(t)
+	c := New(next, t.TempDir())
+	put(t, c, "object", "data")
+
+	rc, err := c.GetObject("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Read(make([]byte, 2))
+	rc.Close()
+	if _, ok := c.store.lookup("bucket", "object"); ok {
+		t.Error("partially read object is cached")
+	}
+	if entries, _ := os.ReadDir(c.store.dir); len(entries) != 0 {
+		t.Errorf("cache directory has %d files, want none", len(entries))
+	}
+}
+
+func TestEviction(t *testing.T) {
+	next := newCounting(t)
+	c := New(next, t.TempDir(), WithMaxSize(10))
+	put(t, c, "a", "aaaa")
+	put(t, c, "b", "bbbb")
+	put(t, c, "c", "cccc")
+	put(t, c, "large", "more than ten bytes")
+
+	get(t, c, "a", "aaaa")
+	get(t, c, "b", "bbbb")
+	get(t, c, "a", "aaaa")
+	get(t, c, "c", "cccc")
+	get(t, c, "large", "more than ten bytes")
+
+	for objectKey, want := range map[string]bool{"a": true, "b": false, "c": true, "large": false} {
+		if _, ok := c.store.lookup("bucket", objectKey); ok != want {
+			t.Errorf("%s cached = %v, want %v", objectKey, ok, want)
+		}
+	}
+	if c.store.size != 8 {
+		t.Errorf("cache size = %d, want 8", c.store.size)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	next := newCounting(t)
+	c := New(next, dir, WithTTL(time.Hour))
+	put(t, c, "a", "aaaa")
+	put(t, c, "b", "bbbb")
+	get(t, c, "a", "aaaa")
+	get(t, c, "b", "bbbb")
+
+	// b was used last, leftovers of an interrupted fill are removed
+	past := time.Now().Add(-time.Minute)
+	os.Chtimes(filepath.Join(dir, entryName("bucket", "a")), past, past)
+	os.WriteFile(filepath.Join(dir, "tmp-123"), []byte("partial"), 0o600)
+
+	reloaded := New(next, dir, WithTTL(time.Hour), WithMaxSize(6))
+	get(t, reloaded, "b", "bbbb")
+	if next.downloads != 2 {
+		t.Errorf("downloads = %d, want 2", next.downloads)
+	}
+	if _, ok := reloaded.store.lookup("bucket", "a"); ok {
+		t.Error("least recently used object wasn't evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tmp-123")); !os.IsNotExist(err) {
+		t.Error("temporary file wasn't removed")
+	}
+}
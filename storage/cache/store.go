@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry is a cached object, its metadata is stored next to the content as <name>.json
+type entry struct {
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	ETag      string    `json:"etag"`
+	Size      int64     `json:"size"`
+	Validated time.Time `json:"validated"`
+
+	name    string
+	element *list.Element
+}
+
+// store is the on-disk LRU cache, loaded from its directory on first use
+type store struct {
+	dir     string
+	maxSize int64
+
+	loadOnce sync.Once
+	loadErr  error
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	lru     *list.List // most recently used first
+	size    int64
+	// inflight counts the fills of an object in progress, stale marks those invalidated meanwhile
+	inflight map[string]int
+	stale    map[string]bool
+}
+
+func newStore(dir string, maxSize int64) *store {
+	return &store{
+		dir:      dir,
+		maxSize:  maxSize,
+		entries:  map[string]*entry{},
+		lru:      list.New(),
+		inflight: map[string]int{},
+		stale:    map[string]bool{},
+	}
+}
+
+// entryName returns the file name of the content of an object
+func entryName(bucketName string, objectKey string) string {
+	sum := sha256.Sum256([]byte(bucketName + "\x00" + objectKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *store) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// load reads the entries in the cache directory, ordered by the last access (modification time) of their content.
+// Content without metadata and leftover temporary files are removed.
+func (s *store) load() error {
+	s.loadOnce.Do(func() {
+		s.loadErr = s.loadEntries()
+	})
+	return s.loadErr
+}
+
+func (s *store) loadEntries() error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	type loaded struct {
+		entry   *entry
+		modTime time.Time
+	}
+	found := []loaded{}
+	metadata := map[string]bool{}
+	for _, d := range dirEntries {
+		if name, ok := strings.CutSuffix(d.Name(), ".json"); ok {
+			metadata[name] = true
+		}
+	}
+	for _, d := range dirEntries {
+		name := d.Name()
+		if d.IsDir() || strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if !metadata[name] {
+			os.Remove(s.path(name))
+			continue
+		}
+		e, err := readEntry(s.path(name))
+		fileInfo, statErr := d.Info()
+		if err != nil || statErr != nil || fileInfo.Size() != e.Size {
+			s.removeFiles(name)
+			continue
+		}
+		e.name = name
+		found = append(found, loaded{entry: e, modTime: fileInfo.ModTime()})
+		delete(metadata, name)
+	}
+	for name := range metadata {
+		os.Remove(s.path(name) + ".json")
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.After(found[j].modTime) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range found {
+		l.entry.element = s.lru.PushBack(l.entry)
+		s.entries[l.entry.name] = l.entry
+		s.size += l.entry.Size
+	}
+	s.evict()
+	return nil
+}
+
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil, err
+	}
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// writeEntry replaces the metadata of e
+func (s *store) writeEntry(e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, "tmp-*.json")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(e.name)+".json")
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// lookup returns the entry of an object
+func (s *store) lookup(bucketName string, objectKey string) (entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[entryName(bucketName, objectKey)]
+	if !ok {
+		return entry{}, false
+	}
+	return *e, true
+}
+
+// open opens the content of the entry of an object with the given ETag, marking it as most recently used.
+func (s *store) open(bucketName string, objectKey string, etag string) (*os.File, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[entryName(bucketName, objectKey)]
+	if !ok || e.ETag != etag {
+		return nil, false
+	}
+	f, err := os.Open(s.path(e.name))
+	if err != nil {
+		s.remove(e)
+		return nil, false
+	}
+	s.lru.MoveToFront(e.element)
+	now := time.Now()
+	os.Chtimes(s.path(e.name), now, now)
+	return f, true
+}
+
+// validated records that the entry of an object is still up to date
+func (s *store) validated(bucketName string, objectKey string, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[entryName(bucketName, objectKey)]
+	if !ok || e.ETag != etag {
+		return
+	}
+	e.Validated = time.Now()
+	if err := s.writeEntry(e); err != nil {
+		s.remove(e)
+	}
+}
+
+// beginFill registers a fill of an object, to be ended by endFill
+func (s *store) beginFill(bucketName string, objectKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inflight[entryName(bucketName, objectKey)]++
+}
+
+// endFill ends a fill of an object. If tmpName is given, it holds the complete content of the object and
+// replaces its entry, unless the object was invalidated since the fill began.
+func (s *store) endFill(bucketName string, objectKey string, etag string, tmpName string, size int64) {
+	name := entryName(bucketName, objectKey)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := s.stale[name]
+	if s.inflight[name]--; s.inflight[name] == 0 {
+		delete(s.inflight, name)
+		delete(s.stale, name)
+	}
+	if tmpName == "" {
+		return
+	}
+	if stale || size > s.maxSize {
+		os.Remove(tmpName)
+		return
+	}
+
+	if old, ok := s.entries[name]; ok {
+		s.remove(old)
+	}
+	if err := os.Rename(tmpName, s.path(name)); err != nil {
+		os.Remove(tmpName)
+		return
+	}
+	e := &entry{Bucket: bucketName, Key: objectKey, ETag: etag, Size: size, Validated: time.Now(), name: name}
+	if err := s.writeEntry(e); err != nil {
+		os.Remove(s.path(name))
+		return
+	}
+	e.element = s.lru.PushFront(e)
+	s.entries[name] = e
+	s.size += size
+	s.evict()
+}
+
+// invalidate removes the entries of the objects, and discards the fills in progress
+func (s *store) invalidate(bucketName string, objectKeys ...string) {
+	s.load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, objectKey := range objectKeys {
+		name := entryName(bucketName, objectKey)
+		if _, ok := s.inflight[name]; ok {
+			s.stale[name] = true
+		}
+		if e, ok := s.entries[name]; ok {
+			s.remove(e)
+		}
+	}
+}
+
+// invalidateBucket removes the entries of all objects of a bucket, and discards all fills in progress
+func (s *store) invalidateBucket(bucketName string) {
+	s.load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.Bucket == bucketName {
+			s.remove(e)
+		}
+	}
+	for name := range s.inflight {
+		s.stale[name] = true
+	}
+}
+
+// remove removes an entry and its files, s.mu must be held
+func (s *store) remove(e *entry) {
+	s.lru.Remove(e.element)
+	delete(s.entries, e.name)
+	s.size -= e.Size
+	s.removeFiles(e.name)
+}
+
+func (s *store) removeFiles(name string) {
+	os.Remove(s.path(name) + ".json")
+	os.Remove(s.path(name))
+}
+
+// evict removes the least recently used entries until the cache fits its size limit, s.mu must be held
+func (s *store) evict() {
+	for s.size > s.maxSize && s.lru.Len() > 0 {
+		s.remove(s.lru.Back().Value.(*entry))
+	}
+}
@@ -0,0 +1,205 @@
+// Package cas is a content-addressable store on top of a storage.Storage bucket: blobs are stored under the
+// SHA-256 digest of their content, so identical content is only stored (and uploaded) once.
+//
+//	store := cas.New(cloudStorage, "artifacts")
+//	digest, err := store.PutFile("build/app.tar.gz")
+//	err = store.Tag("app/v1.2.0", digest)
+//	rc, err := store.Get(digest)
+//
+// Blobs are stored as blobs/sha256/<hex digest> and tags (names referring to a digest) as refs/<name>, below an
+// optional prefix (WithPrefix). Blobs that no tag refers to are deleted by GC.
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// ErrDigestMismatch is returned (wrapped) when content doesn't match its digest
+var ErrDigestMismatch = errors.New("content doesn't match digest")
+
+// Digest identifies a blob by the SHA-256 digest of its content, as "sha256:<hex digest>".
+type Digest string
+
+const digestPrefix = "sha256:"
+
+// ParseDigest returns the digest of s, "sha256:" followed by 64 lowercase hex characters.
+func ParseDigest(s string) (Digest, error) {
+	hexDigest, ok := strings.CutPrefix(s, digestPrefix)
+	if !ok || len(hexDigest) != sha256.Size*2 || strings.ToLower(hexDigest) != hexDigest {
+		return "", fmt.Errorf("invalid digest '%s'", s)
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return "", fmt.Errorf("invalid digest '%s'", s)
+	}
+	return Digest(s), nil
+}
+
+// Hex returns the hex encoded SHA-256 digest.
+func (d Digest) Hex() string {
+	return strings.TrimPrefix(string(d), digestPrefix)
+}
+
+func (d Digest) String() string {
+	return string(d)
+}
+
+func digestOf(h hash.Hash) Digest {
+	return Digest(digestPrefix + hex.EncodeToString(h.Sum(nil)))
+}
+
+// ComputeDigest returns the digest of the content of reader.
+func ComputeDigest(reader io.Reader) (Digest, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, reader); err != nil {
+		return "", err
+	}
+	return digestOf(h), nil
+}
+
+type options struct {
+	prefix string
+}
+
+type Option func(*options)
+
+// WithPrefix stores the blobs and tags below prefix, e.g. "cas/".
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// Store is a content-addressable store in a bucket.
+type Store struct {
+	storage    storage.Storage
+	bucketName string
+	options    options
+}
+
+// New returns the content-addressable store in the bucket.
+func New(cloudStorage storage.Storage, bucketName string, opts ...Option) *Store {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Store{storage: cloudStorage, bucketName: bucketName, options: o}
+}
+
+func (s *Store) blobsPrefix() string {
+	return s.options.prefix + "blobs/sha256/"
+}
+
+func (s *Store) refsPrefix() string {
+	return s.options.prefix + "refs/"
+}
+
+func (s *Store) blobKey(digest Digest) string {
+	return s.blobsPrefix() + digest.Hex()
+}
+
+// Put stores the content of reader and returns its digest. The content is only uploaded if no blob with the
+// same digest exists. It is buffered in a temporary file while its digest is computed and uploaded from there, so
+// that the blob matches its digest even if the source of reader changes meanwhile.
+func (s *Store) Put(reader io.Reader, opts ...storage.PutOption) (Digest, error) {
+	tmp, err := os.CreateTemp("", "cas-*")
+	if err != nil {
+		return "", fmt.Errorf("Put(%w)", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), reader); err != nil {
+		return "", fmt.Errorf("Put(%w)", err)
+	}
+	digest := digestOf(h)
+
+	exists, err := s.Has(digest)
+	if err != nil || exists {
+		return digest, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("Put(%w)", err)
+	}
+	if err := s.storage.PutObject(s.bucketName, s.blobKey(digest), tmp, opts...); err != nil {
+		return "", fmt.Errorf("Put(%s: %w)", digest, err)
+	}
+	return digest, nil
+}
+
+// PutFile stores the content of a file and returns its digest, see Put.
+func (s *Store) PutFile(fileName string, opts ...storage.PutOption) (Digest, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return s.Put(f, opts...)
+}
+
+// Has reports whether a blob with the digest exists.
+func (s *Store) Has(digest Digest) (bool, error) {
+	_, err := s.storage.StatObject(s.bucketName, s.blobKey(digest))
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Has(%s: %w)", digest, err)
+	}
+	return true, nil
+}
+
+// Get returns a reader of the blob with the digest, which must be closed by the caller.
+// The content is verified while read: reading it to the end returns ErrDigestMismatch if it doesn't match the digest.
+// Ranges (storage.WithRange) can't be verified and aren't supported.
+func (s *Store) Get(digest Digest, opts ...storage.GetOption) (io.ReadCloser, error) {
+	if _, err := ParseDigest(string(digest)); err != nil {
+		return nil, fmt.Errorf("Get(%w)", err)
+	}
+	if storage.NewGetOptions(opts...).Ranged() {
+		return nil, fmt.Errorf("Get(%s: ranged read: %w)", digest, storage.ErrNotSupported)
+	}
+	rc, err := s.storage.GetObject(s.bucketName, s.blobKey(digest), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{ReadCloser: rc, digest: digest, hash: sha256.New()}, nil
+}
+
+// verifyingReader returns ErrDigestMismatch instead of io.EOF if the content read doesn't match the digest
+type verifyingReader struct {
+	io.ReadCloser
+	digest Digest
+	hash   hash.Hash
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if read := digestOf(r.hash); read != r.digest {
+			return n, fmt.Errorf("Get(%s: read %s: %w)", r.digest, read, ErrDigestMismatch)
+		}
+	}
+	return n, err
+}
+
+// Retrieve writes the blob with the digest to a file, see Get.
+func (s *Store) Retrieve(digest Digest, fileName string, opts ...storage.GetOption) error {
+	rc, err := s.Get(digest, opts...)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(rc, fileName)
+}
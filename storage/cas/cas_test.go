@@ -0,0 +1,225 @@
+package cas
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+)
+
+// counting counts the uploads
+type counting struct {
+	storage.Wrapper
+	puts int
+}
+
+func (c *counting) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	c.puts++
+	return c.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func newStore(t *testing.T, opts ...Option) (*Store, *counting) {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	c := &counting{Wrapper: storage.Wrapper{Next: m}}
+	return New(c, "bucket", opts...), c
+}
+
+const helloDigest = Digest("sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+func read(t *testing.T, s *Store, digest Digest) string {
+	t.Helper()
+	rc, err := s.Get(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestPut(t *testing.T) {
+	s, c := newStore(t, WithPrefix("cas/"))
+
+	// a seekable reader is read from its current position
+	reader := strings.NewReader("> hello")
+	reader.Seek(2, io.SeekStart)
+	digest, err := s.Put(reader)
+	if err != nil || digest != helloDigest {
+		t.Fatalf("Put() = %s, %v, want %s", digest, err, helloDigest)
+	}
+	digest, err = s.Put(iotest.OneByteReader(strings.NewReader("hello")))
+	if err != nil || digest != helloDigest {
+		t.Fatalf("Put(not seekable) = %s, %v, want %s", digest, err, helloDigest)
+	}
+	if c.puts != 1 {
+		t.Errorf("uploads = %d, want 1", c.puts)
+	}
+
+	if _, err := c.StatObject("bucket", "cas/blobs/sha256/"+helloDigest.Hex()); err != nil {
+		t.Errorf("blob not stored under its digest: %v", err)
+	}
+	if exists, err := s.Has(helloDigest); !exists || err != nil {
+		t.Errorf("Has() = %v, %v, want true", exists, err)
+	}
+	if content := read(t, s, helloDigest); content != "hello" {
+		t.Errorf("Get() = '%s', want 'hello'", content)
+	}
+}
+
+// rewound returns other content once seeked back to the start, as a file rewritten while read would
+type rewound struct {
+	*strings.Reader
+	other string
+}
+
+func (r *rewound) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		r.Reader = strings.NewReader(r.other)
+	}
+	return r.Reader.Seek(offset, whence)
+}
+
+func TestPutChanging(t *testing.T) {
+	s, _ := newStore(t)
+	digest, err := s.Put(&rewound{strings.NewReader("hello"), "jello"})
+	if err != nil || digest != helloDigest {
+		t.Fatalf("Put() = %s, %v, want %s", digest, err, helloDigest)
+	}
+	if content := read(t, s, helloDigest); content != "hello" {
+		t.Errorf("Get() = '%s', want the 'hello' of the digest", content)
+	}
+}
+
+func TestGetCorrupted(t *testing.T) {
+	s, c := newStore(t)
+	if _, err := s.Put(bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatal(err)
+	}
+	c.Next.PutObject("bucket", "blobs/sha256/"+helloDigest.Hex(), strings.NewReader("jello"))
+
+	rc, err := s.Get(helloDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if _, err := io.ReadAll(rc); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Get(corrupted) = %v, want ErrDigestMismatch", err)
+	}
+
+	if _, err := s.Get("sha256:abc"); err == nil {
+		t.Error("Get(invalid digest) should fail")
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	s, _ := newStore(t)
+	if _, err := s.Put(strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(helloDigest, storage.WithRange(1, 2)); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("Get(range) = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s, _ := newStore(t)
+	digest, err := s.Put(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"app/v1", "app/latest", "other"} {
+		if err := s.Tag(name, digest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if resolved, err := s.Resolve("app/v1"); err != nil || resolved != digest {
+		t.Errorf("Resolve() = %s, %v, want %s", resolved, err, digest)
+	}
+	tags, err := s.Tags("app/")
+	if err != nil || len(tags) != 2 || tags["app/latest"] != digest {
+		t.Errorf("Tags(app/) = %v, %v, want app/v1 and app/latest", tags, err)
+	}
+
+	if err := s.Untag("app/v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Resolve("app/v1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Resolve(untagged) = %v, want ErrNotFound", err)
+	}
+
+	missing := Digest("sha256:" + strings.Repeat("0", 64))
+	if err := s.Tag("missing", missing); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Tag(missing blob) = %v, want ErrNotFound", err)
+	}
+	for _, name := range []string{"", "/abs", "a//b", "a/../b", "dir/"} {
+		if err := s.Tag(name, digest); err == nil {
+			t.Errorf("Tag('%s') should fail", name)
+		}
+	}
+}
+
+func TestGC(t *testing.T) {
+	s, c := newStore(t)
+	tagged, _ := s.Put(strings.NewReader("tagged"))
+	referenced, _ := s.Put(strings.NewReader("referenced"))
+	unreferenced, _ := s.Put(strings.NewReader("unreferenced"))
+	if err := s.Tag("release", tagged); err != nil {
+		t.Fatal(err)
+	}
+	c.Next.PutObject("bucket", "blobs/sha256/not-a-digest", strings.NewReader("other"))
+
+	// recent blobs are kept
+	report, err := s.GC()
+	if err != nil || len(report.Results) != 0 {
+		t.Fatalf("GC() = %+v, %v, want nothing deleted", report, err)
+	}
+
+	references := WithReferences(func(add func(Digest)) error {
+		add(referenced)
+		return nil
+	})
+	report, err = s.GC(WithGracePeriod(0), references, WithDryRun())
+	if err != nil || !report.DryRun || len(report.Results) != 1 || report.Results[0].Key != s.blobKey(unreferenced) {
+		t.Fatalf("GC(dry run) = %+v, %v, want %s", report, err, unreferenced)
+	}
+	if exists, _ := s.Has(unreferenced); !exists {
+		t.Error("dry run deleted blob")
+	}
+
+	report, err = s.GC(WithGracePeriod(0), references)
+	if err != nil || len(report.Deleted()) != 1 {
+		t.Fatalf("GC() = %+v, %v, want %s deleted", report, err, unreferenced)
+	}
+	for digest, want := range map[Digest]bool{tagged: true, referenced: true, unreferenced: false} {
+		if exists, _ := s.Has(digest); exists != want {
+			t.Errorf("blob %s exists = %v, want %v", digest, exists, want)
+		}
+	}
+	if _, err := c.StatObject("bucket", "blobs/sha256/not-a-digest"); err != nil {
+		t.Errorf("GC deleted an object that isn't a blob: %v", err)
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	if digest, err := ParseDigest(string(helloDigest)); err != nil || digest != helloDigest {
+		t.Errorf("ParseDigest() = %s, %v", digest, err)
+	}
+	for _, s := range []string{"", helloDigest.Hex(), "sha256:" + strings.ToUpper(helloDigest.Hex()), "sha256:" + strings.Repeat("x", 64)} {
+		if _, err := ParseDigest(s); err == nil {
+			t.Errorf("ParseDigest('%s') should fail", s)
+		}
+	}
+}
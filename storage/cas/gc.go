@@ -0,0 +1,120 @@
+package cas
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// gcBatchSize is the number of unreferenced blobs collected before they are deleted in one DeleteObjects call
+const gcBatchSize = 1000
+
+// DefaultGracePeriod is the age blobs must have before GC deletes them, if WithGracePeriod isn't given
+const DefaultGracePeriod = time.Hour
+
+// ReferencesFunc adds the digests of blobs referred to other than by tags (e.g. by the content of tagged blobs),
+// so GC keeps them.
+type ReferencesFunc func(add func(Digest)) error
+
+type GCOptions struct {
+	// DryRun lists the blobs that would be deleted, without deleting them.
+	DryRun bool
+	// GracePeriod keeps unreferenced blobs younger than GracePeriod, which may be tagged by a writer shortly.
+	GracePeriod time.Duration
+	References  []ReferencesFunc
+}
+
+type GCOption func(*GCOptions)
+
+func WithDryRun() GCOption {
+	return func(o *GCOptions) {
+		o.DryRun = true
+	}
+}
+
+func WithGracePeriod(gracePeriod time.Duration) GCOption {
+	return func(o *GCOptions) {
+		o.GracePeriod = gracePeriod
+	}
+}
+
+// WithReferences adds blobs referred to by references to the blobs GC keeps.
+func WithReferences(references ReferencesFunc) GCOption {
+	return func(o *GCOptions) {
+		o.References = append(o.References, references)
+	}
+}
+
+// GC deletes the blobs no tag (or ReferencesFunc) refers to, that were stored longer than the grace period ago.
+// The report lists the keys of the deleted blobs.
+//
+// Put doesn't upload content that is stored already, so a blob older than the grace period can be put and tagged
+// while GC runs and be deleted; GC should run when no such writes are expected.
+func (s *Store) GC(opts ...GCOption) (*storage.DeleteReport, error) {
+	o := GCOptions{GracePeriod: DefaultGracePeriod}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	referenced := map[Digest]bool{}
+	tags, err := s.Tags("")
+	if err != nil {
+		return nil, fmt.Errorf("GC(%w)", err)
+	}
+	for _, digest := range tags {
+		referenced[digest] = true
+	}
+	for _, references := range o.References {
+		if err := references(func(digest Digest) { referenced[digest] = true }); err != nil {
+			return nil, fmt.Errorf("GC(%w)", err)
+		}
+	}
+
+	report := &storage.DeleteReport{DryRun: o.DryRun}
+	batch := []string{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if o.DryRun {
+			for _, key := range batch {
+				report.Results = append(report.Results, storage.DeleteResult{Key: key})
+			}
+		} else {
+			// per-key failures are in the batch report and returned by report.Err()
+			batchReport, err := s.storage.DeleteObjects(s.bucketName, batch)
+			if batchReport == nil {
+				batchReport = storage.NewDeleteReport(batch)
+				for i := range batchReport.Results {
+					batchReport.Results[i].Err = err
+				}
+			}
+			report.Results = append(report.Results, batchReport.Results...)
+		}
+		batch = []string{}
+	}
+
+	cutoff := time.Now().Add(-o.GracePeriod)
+	err = s.storage.WalkBucketContent(s.bucketName, func(object storage.ObjectInfo) error {
+		digest, err := ParseDigest(digestPrefix + strings.TrimPrefix(object.Key, s.blobsPrefix()))
+		// not a blob
+		if err != nil {
+			return nil
+		}
+		if !referenced[digest] && object.LastModified.Before(cutoff) {
+			batch = append(batch, object.Key)
+		}
+		if len(batch) >= gcBatchSize {
+			flush()
+		}
+		return nil
+	}, storage.WithPrefix(s.blobsPrefix()))
+	if err != nil {
+		return report, fmt.Errorf("GC(%w)", err)
+	}
+	flush()
+
+	return report, report.Err()
+}
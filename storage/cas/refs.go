@@ -0,0 +1,107 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// validTagName checks that a tag name is a relative slash-separated path, e.g. "app/v1.2.0"
+func validTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty tag name")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid tag name '%s'", name)
+		}
+	}
+	return nil
+}
+
+// Tag makes name refer to the blob with the digest, replacing the digest it referred to.
+// Tagging a digest without blob returns ErrNotFound.
+func (s *Store) Tag(name string, digest Digest) error {
+	if err := validTagName(name); err != nil {
+		return fmt.Errorf("Tag(%w)", err)
+	}
+	if _, err := ParseDigest(string(digest)); err != nil {
+		return fmt.Errorf("Tag(%w)", err)
+	}
+	exists, err := s.Has(digest)
+	if err != nil {
+		return fmt.Errorf("Tag(%s: %w)", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("Tag(%s: blob %s: %w)", name, digest, storage.ErrNotFound)
+	}
+
+	err = s.storage.PutObject(s.bucketName, s.refsPrefix()+name, strings.NewReader(string(digest)), storage.WithContentType("text/plain"))
+	if err != nil {
+		return fmt.Errorf("Tag(%s: %w)", name, err)
+	}
+	return nil
+}
+
+// Resolve returns the digest name refers to, or ErrNotFound if there is no such tag.
+func (s *Store) Resolve(name string) (Digest, error) {
+	if err := validTagName(name); err != nil {
+		return "", fmt.Errorf("Resolve(%w)", err)
+	}
+	rc, err := s.storage.GetObject(s.bucketName, s.refsPrefix()+name)
+	if err != nil {
+		return "", fmt.Errorf("Resolve(%s: %w)", name, err)
+	}
+	defer rc.Close()
+
+	// a tag holds a digest, anything larger isn't one
+	content, err := io.ReadAll(io.LimitReader(rc, 1024))
+	if err != nil {
+		return "", fmt.Errorf("Resolve(%s: %w)", name, err)
+	}
+	digest, err := ParseDigest(strings.TrimSpace(string(content)))
+	if err != nil {
+		return "", fmt.Errorf("Resolve(%s: %w)", name, err)
+	}
+	return digest, nil
+}
+
+// Untag removes the tag name, the blob it referred to is deleted by GC if no other tag refers to it.
+func (s *Store) Untag(name string) error {
+	if err := validTagName(name); err != nil {
+		return fmt.Errorf("Untag(%w)", err)
+	}
+	if err := s.storage.DeleteObject(s.bucketName, []string{s.refsPrefix() + name}); err != nil {
+		return fmt.Errorf("Untag(%s: %w)", name, err)
+	}
+	return nil
+}
+
+// Tags returns the digests of the tags with names starting with prefix ("" for all tags), by tag name.
+func (s *Store) Tags(prefix string) (map[string]Digest, error) {
+	names := []string{}
+	err := s.storage.WalkBucketContent(s.bucketName, func(object storage.ObjectInfo) error {
+		names = append(names, strings.TrimPrefix(object.Key, s.refsPrefix()))
+		return nil
+	}, storage.WithPrefix(s.refsPrefix()+prefix))
+	if err != nil {
+		return nil, fmt.Errorf("Tags(%w)", err)
+	}
+
+	tags := map[string]Digest{}
+	for _, name := range names {
+		digest, err := s.Resolve(name)
+		// untagged meanwhile
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Tags(%w)", err)
+		}
+		tags[name] = digest
+	}
+	return tags, nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import "github.com/pbreedt/cloud-connect/storage/common"
+
+// Re-exported from the common package, so users only need to import storage.
+
+var (
+	ErrNotFound     = common.ErrNotFound
+	ErrNotSupported = common.ErrNotSupported
+	ErrCustomerKey  = common.ErrCustomerKey
+	ErrNotModified  = common.ErrNotModified
+)
+
+type CreateBucketOptions = common.CreateBucketOptions
+type CreateBucketOption = common.CreateBucketOption
+
+type DeleteBucketOptions = common.DeleteBucketOptions
+type DeleteBucketOption = common.DeleteBucketOption
+
+var (
+	WithLocation            = common.WithLocation
+	WithDefaultStorageClass = common.WithDefaultStorageClass
+	WithBlockPublicAccess   = common.WithBlockPublicAccess
+	WithKMSKey              = common.WithKMSKey
+	WithDefaultEncryption   = common.WithDefaultEncryption
+	WithLabels              = common.WithLabels
+	WithVersioning          = common.WithVersioning
+
+	WithForce          = common.WithForce
+	WithConcurrency    = common.WithConcurrency
+	WithDeleteProgress = common.WithDeleteProgress
+)
+
+type StorageClass = common.StorageClass
+
+const (
+	StorageClassStandard         = common.StorageClassStandard
+	StorageClassInfrequentAccess = common.StorageClassInfrequentAccess
+	StorageClassArchive          = common.StorageClassArchive
+	StorageClassDeepArchive      = common.StorageClassDeepArchive
+)
+
+type PutOptions = common.PutOptions
+type PutOption = common.PutOption
+
+var NewPutOptions = common.NewPutOptions
+
+var (
+	WithStorageClass    = common.WithStorageClass
+	WithContentType     = common.WithContentType
+	WithContentEncoding = common.WithContentEncoding
+	WithMetadata        = common.WithMetadata
+
+	WithUploadProgress = common.WithUploadProgress
+	WithEncryption     = common.WithEncryption
+)
+
+type GetOptions = common.GetOptions
+type GetOption = common.GetOption
+
+var NewGetOptions = common.NewGetOptions
+
+var (
+	WithDownloadProgress = common.WithDownloadProgress
+	WithCustomerKey      = common.WithCustomerKey
+	WithIfNoneMatch      = common.WithIfNoneMatch
+	WithObjectInfo       = common.WithObjectInfo
+	WithRange            = common.WithRange
+	WithRawContent       = common.WithRawContent
+)
+
+type EncryptionOptions = common.EncryptionOptions
+type EncryptionInfo = common.EncryptionInfo
+type EncryptionType = common.EncryptionType
+
+const (
+	EncryptionProviderManaged = common.EncryptionProviderManaged
+	EncryptionKMS             = common.EncryptionKMS
+	EncryptionCustomerKey     = common.EncryptionCustomerKey
+)
+
+type Progress = common.Progress
+type ProgressFunc = common.ProgressFunc
+type ProgressTracker = common.ProgressTracker
+
+var (
+	NewProgressTracker    = common.NewProgressTracker
+	NewProgressReader     = common.NewProgressReader
+	NewProgressReadCloser = common.NewProgressReadCloser
+	NewRangeReadCloser    = common.NewRangeReadCloser
+	ReaderSize            = common.ReaderSize
+	CountReader           = common.CountReader
+	FileTransferSize      = common.FileTransferSize
+	WriteFile             = common.WriteFile
+)
+
+type CountingReader = common.CountingReader
+
+type RetryPolicy = common.RetryPolicy
+type RetryEvent = common.RetryEvent
+
+var (
+	DefaultRetryPolicy = common.DefaultRetryPolicy
+	DefaultRetryable   = common.DefaultRetryable
+)
+
+type RestorePriority = common.RestorePriority
+
+const (
+	RestorePriorityExpedited = common.RestorePriorityExpedited
+	RestorePriorityStandard  = common.RestorePriorityStandard
+	RestorePriorityBulk      = common.RestorePriorityBulk
+)
+
+type RestoreOptions = common.RestoreOptions
+type RestoreOption = common.RestoreOption
+type RestoreStatus = common.RestoreStatus
+
+var (
+	WithRestoreDays     = common.WithRestoreDays
+	WithRestorePriority = common.WithRestorePriority
+)
+
+type DeleteResult = common.DeleteResult
+type DeleteReport = common.DeleteReport
+type DeleteError = common.DeleteError
+
+var NewDeleteReport = common.NewDeleteReport
+
+type ObjectInfo = common.ObjectInfo
+type WalkFunc = common.WalkFunc
+
+var StopWalk = common.StopWalk
+
+type ListOptions = common.ListOptions
+type ListOption = common.ListOption
+
+var (
+	WithPrefix    = common.WithPrefix
+	WithDelimiter = common.WithDelimiter
+)
@@ -0,0 +1,160 @@
+package common
+
+import "sync"
+
+// CreateBucketOptions holds the settings applied when creating a bucket.
+// Zero values leave the provider defaults in place.
+type CreateBucketOptions struct {
+	// Location overrides the client's default location/region.
+	Location string
+	// StorageClass is the default storage class of objects in the bucket.
+	StorageClass StorageClass
+	// BlockPublicAccess enforces uniform, non-public access to the bucket.
+	BlockPublicAccess bool
+	// Encryption is the default encryption of objects in the bucket.
+	// Only Encryption.KMSKey is supported, customer keys can't be bucket defaults.
+	Encryption EncryptionOptions
+	// Labels are attached to the bucket as tags/labels/metadata.
+	Labels map[string]string
+	// Versioning enables object versioning on the bucket.
+	Versioning bool
+}
+
+type CreateBucketOption func(*CreateBucketOptions)
+
+// NewCreateBucketOptions applies opts to an empty CreateBucketOptions.
+func NewCreateBucketOptions(opts ...CreateBucketOption) CreateBucketOptions {
+	o := CreateBucketOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithLocation(location string) CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.Location = location
+	}
+}
+
+func WithDefaultStorageClass(storageClass StorageClass) CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.StorageClass = storageClass
+	}
+}
+
+func WithBlockPublicAccess() CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.BlockPublicAccess = true
+	}
+}
+
+// WithKMSKey encrypts objects in the bucket with key by default.
+// (AWS: KMS key ARN/id, GCP: KMS key resource name, Azure: encryption scope)
+func WithKMSKey(key string) CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.Encryption.KMSKey = key
+	}
+}
+
+// WithDefaultEncryption sets the default encryption of objects in the bucket.
+func WithDefaultEncryption(encryption EncryptionOptions) CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.Encryption = encryption
+	}
+}
+
+func WithLabels(labels map[string]string) CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		if o.Labels == nil {
+			o.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			o.Labels[k] = v
+		}
+	}
+}
+
+func WithVersioning() CreateBucketOption {
+	return func(o *CreateBucketOptions) {
+		o.Versioning = true
+	}
+}
+
+// DeleteBucketOptions holds the settings applied when deleting a bucket.
+type DeleteBucketOptions struct {
+	// Force deletes all objects, versions, delete markers and incomplete multipart
+	// uploads before deleting the bucket.
+	Force bool
+	// Concurrency is the number of concurrent delete requests used when Force is set.
+	Concurrency int
+	// Progress is called with the running total of deleted objects when Force is set.
+	Progress func(deleted int)
+}
+
+type DeleteBucketOption func(*DeleteBucketOptions)
+
+// NewDeleteBucketOptions applies opts to the default DeleteBucketOptions.
+func NewDeleteBucketOptions(opts ...DeleteBucketOption) DeleteBucketOptions {
+	o := DeleteBucketOptions{
+		Concurrency: 10,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
+	return o
+}
+
+func WithForce() DeleteBucketOption {
+	return func(o *DeleteBucketOptions) {
+		o.Force = true
+	}
+}
+
+func WithConcurrency(concurrency int) DeleteBucketOption {
+	return func(o *DeleteBucketOptions) {
+		o.Concurrency = concurrency
+	}
+}
+
+func WithDeleteProgress(progress func(deleted int)) DeleteBucketOption {
+	return func(o *DeleteBucketOptions) {
+		o.Progress = progress
+	}
+}
+
+// DeleteCounter keeps a running total of deleted objects and reports it to a progress function.
+// It is safe for concurrent use.
+type DeleteCounter struct {
+	mu       sync.Mutex
+	deleted  int
+	progress func(deleted int)
+}
+
+func NewDeleteCounter(progress func(deleted int)) *DeleteCounter {
+	return &DeleteCounter{progress: progress}
+}
+
+func (c *DeleteCounter) Add(n int) {
+	if n == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deleted += n
+	if c.progress != nil {
+		c.progress(c.deleted)
+	}
+}
+
+func (c *DeleteCounter) Deleted() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deleted
+}
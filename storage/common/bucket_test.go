@@ -0,0 +1,53 @@
+package common
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNewCreateBucketOptions(t *testing.T) {
+	o := NewCreateBucketOptions(
+		WithLocation("eu-west-1"),
+		WithBlockPublicAccess(),
+		WithLabels(map[string]string{"team": "a"}),
+		WithLabels(map[string]string{"env": "test"}),
+		WithVersioning(),
+	)
+
+	if o.Location != "eu-west-1" {
+		t.Errorf("Location = '%s', want 'eu-west-1'", o.Location)
+	}
+	if !o.BlockPublicAccess || !o.Versioning {
+		t.Errorf("BlockPublicAccess/Versioning not set: %+v", o)
+	}
+	if len(o.Labels) != 2 || o.Labels["team"] != "a" || o.Labels["env"] != "test" {
+		t.Errorf("Labels = %v, want both label sets merged", o.Labels)
+	}
+	if o.StorageClass != "" || !o.Encryption.IsZero() {
+		t.Errorf("unset options should be empty: %+v", o)
+	}
+}
+
+func TestDeleteCounter(t *testing.T) {
+	reported := []int{}
+	counter := NewDeleteCounter(func(deleted int) {
+		reported = append(reported, deleted)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Add(2)
+		}()
+	}
+	wg.Wait()
+
+	if counter.Deleted() != 20 {
+		t.Errorf("Deleted() = %d, want 20", counter.Deleted())
+	}
+	if len(reported) != 10 || reported[9] != 20 {
+		t.Errorf("reported = %v, want 10 increasing totals ending in 20", reported)
+	}
+}
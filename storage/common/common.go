@@ -0,0 +1,18 @@
+// Package common holds the provider-neutral types shared by the storage
+// package and the individual provider implementations.
+//
+// The types are re-exported by the storage package, so users normally don't
+// need to import this package directly.
+package common
+
+import "errors"
+
+// ErrNotFound is returned (wrapped) when an object or bucket does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrNotSupported is returned (wrapped) when a provider has no equivalent for
+// a requested feature.
+var ErrNotSupported = errors.New("not supported by provider")
+
+// ErrNotModified is returned (wrapped) by conditional reads (WithIfNoneMatch) of objects that didn't change.
+var ErrNotModified = errors.New("not modified")
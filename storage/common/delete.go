@@ -0,0 +1,97 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotReported is set for keys a provider returned no result for.
+var ErrNotReported = errors.New("no result returned by provider")
+
+// DeleteConcurrency is the number of concurrent requests used to delete a list of objects.
+const DeleteConcurrency = 10
+
+// DeleteResult is the outcome of deleting a single object.
+type DeleteResult struct {
+	Key string
+	Err error
+}
+
+// DeleteReport holds the per-key results of a batch delete, in the order the keys were provided.
+type DeleteReport struct {
+	Results []DeleteResult
+	// DryRun is set if nothing was deleted; Results lists what would have been deleted.
+	DryRun bool
+}
+
+// NewDeleteReport returns a report with a result slot per key.
+func NewDeleteReport(objectKeys []string) *DeleteReport {
+	report := &DeleteReport{Results: make([]DeleteResult, len(objectKeys))}
+	for i, key := range objectKeys {
+		report.Results[i].Key = key
+	}
+	return report
+}
+
+// Deleted returns the keys that were deleted successfully.
+func (r *DeleteReport) Deleted() []string {
+	deleted := []string{}
+	for _, result := range r.Results {
+		if result.Err == nil {
+			deleted = append(deleted, result.Key)
+		}
+	}
+	return deleted
+}
+
+// Failed returns the results of the keys that could not be deleted.
+func (r *DeleteReport) Failed() []DeleteResult {
+	failed := []DeleteResult{}
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns a *DeleteError if any key could not be deleted, nil otherwise.
+func (r *DeleteReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return &DeleteError{Failed: failed, Total: len(r.Results)}
+}
+
+// DeleteError reports the keys that could not be deleted by a batch delete.
+type DeleteError struct {
+	Failed []DeleteResult
+	Total  int
+}
+
+func (e *DeleteError) Error() string {
+	first := e.Failed[0]
+	return fmt.Sprintf("%d of %d objects not deleted, first error: %s: %v", len(e.Failed), e.Total, first.Key, first.Err)
+}
+
+func (e *DeleteError) Unwrap() []error {
+	errs := []error{}
+	for _, result := range e.Failed {
+		errs = append(errs, result.Err)
+	}
+	return errs
+}
+
+// Chunk splits keys into consecutive chunks of at most size keys.
+func Chunk(keys []string, size int) [][]string {
+	chunks := [][]string{}
+	for len(keys) > size {
+		chunks = append(chunks, keys[:size])
+		keys = keys[size:]
+	}
+	if len(keys) > 0 {
+		chunks = append(chunks, keys)
+	}
+	return chunks
+}
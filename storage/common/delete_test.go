@@ -0,0 +1,44 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	chunks := Chunk(keys, 2)
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[2]) != 1 || chunks[2][0] != "e" {
+		t.Errorf("Chunk(5 keys, 2) = %v", chunks)
+	}
+	if len(Chunk(nil, 2)) != 0 {
+		t.Errorf("Chunk(nil) should be empty")
+	}
+}
+
+func TestDeleteReport(t *testing.T) {
+	errDenied := errors.New("access denied")
+
+	report := NewDeleteReport([]string{"a", "b", "c"})
+	if report.Err() != nil {
+		t.Fatalf("Err() = %v, want nil when nothing failed", report.Err())
+	}
+
+	report.Results[1].Err = errDenied
+	if deleted := report.Deleted(); len(deleted) != 2 || deleted[0] != "a" || deleted[1] != "c" {
+		t.Errorf("Deleted() = %v, want [a c]", deleted)
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Key != "b" {
+		t.Errorf("Failed() = %v, want [b]", failed)
+	}
+
+	err := report.Err()
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) || deleteErr.Total != 3 {
+		t.Fatalf("Err() = %v, want *DeleteError for 3 keys", err)
+	}
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Err() should wrap the per-key errors")
+	}
+}
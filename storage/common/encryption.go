@@ -0,0 +1,101 @@
+package common
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrCustomerKey is returned when reading an object stored with a customer key without the same key.
+var ErrCustomerKey = errors.New("object is encrypted with another customer key")
+
+// EncryptionOptions selects the key a provider encrypts objects with at rest.
+// The zero value leaves the provider's default in place (provider managed keys, or the bucket default).
+type EncryptionOptions struct {
+	// KMSKey encrypts with a key of the provider's key management service
+	// (AWS: SSE-KMS key ARN/id, GCP: CMEK key resource name, Azure: encryption scope)
+	KMSKey string
+	// CustomerKey is a 32 byte AES-256 key sent along with every request, the provider doesn't store it
+	// (AWS: SSE-C, GCP: customer-supplied encryption key, Azure: customer-provided key).
+	// Objects stored with a customer key can only be read and stat'ed with the same key.
+	CustomerKey []byte
+}
+
+// IsZero reports whether no encryption settings are given.
+func (e EncryptionOptions) IsZero() bool {
+	return e.KMSKey == "" && len(e.CustomerKey) == 0
+}
+
+// Validate checks that at most one key is given, and that a customer key is an AES-256 key.
+func (e EncryptionOptions) Validate() error {
+	if e.KMSKey != "" && len(e.CustomerKey) > 0 {
+		return errors.New("encryption: KMSKey and CustomerKey are mutually exclusive")
+	}
+	if len(e.CustomerKey) > 0 && len(e.CustomerKey) != 32 {
+		return fmt.Errorf("encryption: CustomerKey must be 32 bytes, got %d", len(e.CustomerKey))
+	}
+	return nil
+}
+
+// Info returns the EncryptionInfo of objects stored with e, for providers that record the encryption
+// without applying it (memory, local). Customer keys are identified by their SHA-256 digest.
+func (e EncryptionOptions) Info() EncryptionInfo {
+	switch {
+	case len(e.CustomerKey) > 0:
+		return EncryptionInfo{Type: EncryptionCustomerKey, CustomerKeyHash: e.CustomerKeySHA256()}
+	case e.KMSKey != "":
+		return EncryptionInfo{Type: EncryptionKMS, KMSKey: e.KMSKey}
+	default:
+		return EncryptionInfo{}
+	}
+}
+
+// CustomerKeyBase64 returns the base64 encoded customer key, as sent to the providers.
+func (e EncryptionOptions) CustomerKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(e.CustomerKey)
+}
+
+// CustomerKeyMD5 returns the base64 encoded MD5 digest of the customer key (AWS).
+func (e EncryptionOptions) CustomerKeyMD5() string {
+	sum := md5.Sum(e.CustomerKey)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CustomerKeySHA256 returns the base64 encoded SHA-256 digest of the customer key (GCP, Azure).
+func (e EncryptionOptions) CustomerKeySHA256() string {
+	sum := sha256.Sum256(e.CustomerKey)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type EncryptionType string
+
+const (
+	// EncryptionProviderManaged is encryption with keys managed by the provider (AWS SSE-S3, Google/Microsoft managed keys)
+	EncryptionProviderManaged EncryptionType = "PROVIDER_MANAGED"
+	// EncryptionKMS is encryption with a KMS key or encryption scope
+	EncryptionKMS EncryptionType = "KMS"
+	// EncryptionCustomerKey is encryption with a customer-provided key
+	EncryptionCustomerKey EncryptionType = "CUSTOMER_KEY"
+)
+
+// EncryptionInfo describes how an object is encrypted at rest, as reported by StatObject.
+// Type is empty if the provider doesn't report the encryption.
+type EncryptionInfo struct {
+	Type EncryptionType
+	// KMSKey is the KMS key or encryption scope, for EncryptionKMS
+	KMSKey string
+	// CustomerKeyHash identifies the customer key, for EncryptionCustomerKey
+	// (AWS: base64 MD5, GCP and Azure: base64 SHA-256; see EncryptionOptions.CustomerKeyMD5/CustomerKeySHA256)
+	CustomerKeyHash string
+}
+
+// CheckCustomerKey returns ErrCustomerKey if the object, encrypted as info was reported by Info,
+// can't be read with the customer key of e.
+func (info EncryptionInfo) CheckCustomerKey(e EncryptionOptions) error {
+	if info.Type == EncryptionCustomerKey && (len(e.CustomerKey) == 0 || e.CustomerKeySHA256() != info.CustomerKeyHash) {
+		return ErrCustomerKey
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptionOptionsValidate(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	for name, test := range map[string]struct {
+		encryption EncryptionOptions
+		valid      bool
+	}{
+		"zero":           {EncryptionOptions{}, true},
+		"kms":            {EncryptionOptions{KMSKey: "alias/app"}, true},
+		"customer key":   {EncryptionOptions{CustomerKey: key}, true},
+		"short key":      {EncryptionOptions{CustomerKey: key[:16]}, false},
+		"kms & customer": {EncryptionOptions{KMSKey: "alias/app", CustomerKey: key}, false},
+	} {
+		err := test.encryption.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%s: Validate() = %v, want valid %t", name, err, test.valid)
+		}
+	}
+}
+
+func TestEncryptionOptionsCustomerKey(t *testing.T) {
+	e := EncryptionOptions{CustomerKey: []byte("0123456789abcdef0123456789abcdef")}
+
+	if got, want := e.CustomerKeyBase64(), "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="; got != want {
+		t.Errorf("CustomerKeyBase64() = %s, want %s", got, want)
+	}
+	if got, want := e.CustomerKeyMD5(), "hRasmdxgYDKV3nvbahU1MA=="; got != want {
+		t.Errorf("CustomerKeyMD5() = %s, want %s", got, want)
+	}
+	if got, want := e.CustomerKeySHA256(), "PrG9Q5lH63YpmOVmzMLgmceREYsvQFecxPfaK1Bht/k="; got != want {
+		t.Errorf("CustomerKeySHA256() = %s, want %s", got, want)
+	}
+}
+
+func TestBucketEncryptionOptions(t *testing.T) {
+	o := NewCreateBucketOptions(WithKMSKey("alias/app"))
+	if o.Encryption.KMSKey != "alias/app" {
+		t.Errorf("WithKMSKey: Encryption = %+v", o.Encryption)
+	}
+
+	g := NewGetOptions(WithCustomerKey([]byte("k")))
+	if string(g.Encryption.CustomerKey) != "k" {
+		t.Errorf("WithCustomerKey: Encryption = %+v", g.Encryption)
+	}
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ObjectInfo describes an object in a bucket.
+// Listings only fill in Key, Size, LastModified and ETag; StatObject fills in all fields.
+// Listings with a delimiter also return common prefixes, with IsPrefix set and only Key filled in.
+type ObjectInfo struct {
+	Key             string
+	Size            int64
+	LastModified    time.Time
+	ETag            string
+	ContentType     string
+	ContentEncoding string
+	StorageClass    StorageClass
+	Metadata        map[string]string
+	Encryption      EncryptionInfo
+	IsPrefix        bool
+}
+
+// WalkFunc is called for every object visited by WalkBucketContent.
+// Returning StopWalk ends the walk without error; any other error ends the walk and is returned.
+type WalkFunc func(object ObjectInfo) error
+
+// StopWalk can be returned by a WalkFunc to stop walking early.
+var StopWalk = errors.New("stop walk")
+
+type ListOptions struct {
+	// Prefix limits the listing to keys starting with Prefix.
+	Prefix string
+	// Delimiter rolls up the keys containing Delimiter after Prefix into their common prefix (up to and including
+	// the delimiter), listed once, as directories with "/".
+	Delimiter string
+}
+
+type ListOption func(*ListOptions)
+
+// NewListOptions applies opts to an empty ListOptions.
+func NewListOptions(opts ...ListOption) ListOptions {
+	o := ListOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithPrefix(prefix string) ListOption {
+	return func(o *ListOptions) {
+		o.Prefix = prefix
+	}
+}
+
+// WithDelimiter lists one level of a hierarchy: keys with delimiter after the prefix are listed as their
+// common prefix (e.g. "photos/2024/" for "photos/2024/a.jpg" with prefix "photos/" and delimiter "/").
+func WithDelimiter(delimiter string) ListOption {
+	return func(o *ListOptions) {
+		o.Delimiter = delimiter
+	}
+}
+
+// Delimit rolls up the objects of a listing sorted by key into common prefixes, for providers without native
+// delimiter support. The objects are returned as they are if delimiter is empty.
+func Delimit(objects []ObjectInfo, prefix string, delimiter string) []ObjectInfo {
+	if delimiter == "" {
+		return objects
+	}
+	delimited := []ObjectInfo{}
+	for _, object := range objects {
+		i := strings.Index(object.Key[len(prefix):], delimiter)
+		if i < 0 {
+			delimited = append(delimited, object)
+			continue
+		}
+		// keys with the same prefix are adjacent in key order
+		commonPrefix := object.Key[:len(prefix)+i+len(delimiter)]
+		if n := len(delimited); n > 0 && delimited[n-1].IsPrefix && delimited[n-1].Key == commonPrefix {
+			continue
+		}
+		delimited = append(delimited, ObjectInfo{Key: commonPrefix, IsPrefix: true})
+	}
+	return delimited
+}
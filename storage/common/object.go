@@ -0,0 +1,162 @@
+package common
+
+// PutOptions holds the settings applied when storing an object.
+type PutOptions struct {
+	StorageClass StorageClass
+	ContentType  string
+	// ContentEncoding is the encoding of the stored content (gzip, zstd, ...); objects are read back as stored
+	ContentEncoding string
+	Metadata        map[string]string
+	// Progress is reported while uploading, see WithUploadProgress
+	Progress ProgressFunc
+	// Encryption overrides the bucket's default encryption, see WithEncryption
+	Encryption EncryptionOptions
+}
+
+type PutOption func(*PutOptions)
+
+// NewPutOptions applies opts to an empty PutOptions.
+func NewPutOptions(opts ...PutOption) PutOptions {
+	o := PutOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithStorageClass(class StorageClass) PutOption {
+	return func(o *PutOptions) {
+		o.StorageClass = class
+	}
+}
+
+func WithContentType(contentType string) PutOption {
+	return func(o *PutOptions) {
+		o.ContentType = contentType
+	}
+}
+
+func WithContentEncoding(contentEncoding string) PutOption {
+	return func(o *PutOptions) {
+		o.ContentEncoding = contentEncoding
+	}
+}
+
+// WithMetadata adds user-defined metadata to the object.
+// Keys should be lower case, as not all providers preserve case.
+func WithMetadata(metadata map[string]string) PutOption {
+	return func(o *PutOptions) {
+		if o.Metadata == nil {
+			o.Metadata = map[string]string{}
+		}
+		for k, v := range metadata {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// WithUploadProgress reports the progress of the upload to progress.
+func WithUploadProgress(progress ProgressFunc) PutOption {
+	return func(o *PutOptions) {
+		o.Progress = progress
+	}
+}
+
+// WithEncryption encrypts the object with a KMS key or customer-provided key.
+func WithEncryption(encryption EncryptionOptions) PutOption {
+	return func(o *PutOptions) {
+		o.Encryption = encryption
+	}
+}
+
+// GetOptions holds the settings applied when reading an object.
+type GetOptions struct {
+	Progress ProgressFunc
+	// Encryption holds the customer key of objects stored with one, KMSKey is ignored
+	Encryption EncryptionOptions
+	// IfNoneMatch makes the read conditional: ErrNotModified is returned if the object's ETag matches
+	IfNoneMatch string
+	// Info is filled in with the details of the object read, see WithObjectInfo
+	Info *ObjectInfo
+	// Offset and Length select the part of the object read, see WithRange
+	Offset int64
+	Length int64
+	// RawContent reads the content as stored, see WithRawContent
+	RawContent bool
+}
+
+type GetOption func(*GetOptions)
+
+// NewGetOptions applies opts to an empty GetOptions.
+func NewGetOptions(opts ...GetOption) GetOptions {
+	o := GetOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDownloadProgress reports the progress of the download to progress.
+// For GetObject, the download progresses as the returned reader is read.
+func WithDownloadProgress(progress ProgressFunc) GetOption {
+	return func(o *GetOptions) {
+		o.Progress = progress
+	}
+}
+
+// WithCustomerKey reads an object stored with a customer-provided key (see EncryptionOptions.CustomerKey).
+// StatObject and SetStorageClass need the key as well.
+func WithCustomerKey(key []byte) GetOption {
+	return func(o *GetOptions) {
+		o.Encryption.CustomerKey = key
+	}
+}
+
+// WithIfNoneMatch only reads the object if its ETag differs from etag, otherwise ErrNotModified is returned.
+func WithIfNoneMatch(etag string) GetOption {
+	return func(o *GetOptions) {
+		o.IfNoneMatch = etag
+	}
+}
+
+// WithObjectInfo fills in info with the details of the object read, as StatObject would, from the same response
+// where the provider allows (Azure downloads don't return the storage class). The ETag matches the content read.
+func WithObjectInfo(info *ObjectInfo) GetOption {
+	return func(o *GetOptions) {
+		o.Info = info
+	}
+}
+
+// WithRange only reads length bytes from offset on, up to the end of the object if length is 0 or the object is
+// shorter. The offset must be within the object, except for offset 0. The ObjectInfo of WithObjectInfo still
+// describes the whole object.
+func WithRange(offset int64, length int64) GetOption {
+	return func(o *GetOptions) {
+		o.Offset, o.Length = max(offset, 0), max(length, 0)
+	}
+}
+
+// WithRawContent reads the content as stored. GCS decompresses objects stored with Content-Encoding gzip while
+// read (decompressive transcoding) unless read with it; other providers always read the content as stored.
+func WithRawContent() GetOption {
+	return func(o *GetOptions) {
+		o.RawContent = true
+	}
+}
+
+// Ranged reports whether o reads part of the object (WithRange).
+func (o GetOptions) Ranged() bool {
+	return o.Offset > 0 || o.Length > 0
+}
+
+// RangeSize returns the size of the part of an object of size that o reads, or -1 if size is unknown (negative).
+func (o GetOptions) RangeSize(size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	size = max(size-o.Offset, 0)
+	if o.Length > 0 {
+		size = min(size, o.Length)
+	}
+	return size
+}
@@ -0,0 +1,201 @@
+package common
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Progress describes a transfer in progress.
+type Progress struct {
+	// Bytes transferred so far
+	Bytes int64
+	// Total bytes to transfer, -1 if unknown
+	Total int64
+	// Rate is the average transfer rate in bytes per second
+	Rate float64
+	// ETA is the estimated time left, -1 if unknown
+	ETA time.Duration
+	// Objects and ObjectsDone count the objects of a ProgressTracker, 0 for single transfers
+	Objects     int
+	ObjectsDone int
+}
+
+// ProgressFunc is called while transferring, at most every ProgressInterval and once more when done.
+type ProgressFunc func(Progress)
+
+// ProgressInterval is the minimum time between ProgressFunc calls for a transfer.
+var ProgressInterval = 100 * time.Millisecond
+
+// newProgress returns the progress of bytes out of total transferred since start
+func newProgress(bytes int64, total int64, start time.Time) Progress {
+	p := Progress{Bytes: bytes, Total: total, ETA: -1}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		p.Rate = float64(bytes) / elapsed
+	}
+	if total >= 0 && p.Rate > 0 {
+		p.ETA = time.Duration(float64(max(total-bytes, 0)) / p.Rate * float64(time.Second))
+	}
+	return p
+}
+
+// ProgressReader reports the bytes read through it to a ProgressFunc.
+type ProgressReader struct {
+	reader   io.Reader
+	progress ProgressFunc
+	total    int64
+	bytes    int64
+	start    time.Time
+	last     time.Time
+	done     bool
+}
+
+// NewProgressReader reports reading total bytes from reader to progress. A negative total is taken from reader
+// if it knows its size (files, bytes.Reader, strings.Reader), otherwise it is unknown.
+func NewProgressReader(reader io.Reader, total int64, progress ProgressFunc) *ProgressReader {
+	if total < 0 {
+		total = ReaderSize(reader)
+	}
+	now := time.Now()
+	return &ProgressReader{reader: reader, progress: progress, total: total, start: now, last: now}
+}
+
+// ReaderSize returns the bytes left in reader if it knows its size (files, bytes.Reader, strings.Reader), or -1
+func ReaderSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+func (r *ProgressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.bytes += int64(n)
+	if err == io.EOF {
+		r.finish()
+	} else if now := time.Now(); now.Sub(r.last) >= ProgressInterval {
+		r.last = now
+		r.progress(newProgress(r.bytes, r.total, r.start))
+	}
+	return n, err
+}
+
+// finish reports the final progress, once
+func (r *ProgressReader) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	total := r.total
+	if total < 0 {
+		total = r.bytes
+	}
+	r.progress(newProgress(r.bytes, total, r.start))
+}
+
+// ProgressReadCloser is a ProgressReader that reports the final progress when closed, if not reported at EOF.
+type ProgressReadCloser struct {
+	*ProgressReader
+	closer io.Closer
+}
+
+// NewProgressReadCloser reports reading total bytes from rc to progress (see NewProgressReader).
+func NewProgressReadCloser(rc io.ReadCloser, total int64, progress ProgressFunc) *ProgressReadCloser {
+	return &ProgressReadCloser{ProgressReader: NewProgressReader(rc, total, progress), closer: rc}
+}
+
+func (r *ProgressReadCloser) Close() error {
+	err := r.closer.Close()
+	r.finish()
+	return err
+}
+
+// ProgressTracker aggregates the progress of the transfers of several objects, as in a sync or migration.
+//
+//	tracker := common.NewProgressTracker(report)
+//	tracker.Expect(len(objects), totalBytes)
+//	for _, object := range objects {
+//		err := s.StoreObject(bucketName, object.Key, object.File, common.WithUploadProgress(tracker.Transfer()))
+//		tracker.ObjectDone()
+//	}
+type ProgressTracker struct {
+	mu          sync.Mutex
+	progress    ProgressFunc
+	start       time.Time
+	last        time.Time
+	bytes       int64
+	total       int64
+	objects     int
+	objectsDone int
+}
+
+// NewProgressTracker reports the aggregate progress of the tracked transfers to progress.
+func NewProgressTracker(progress ProgressFunc) *ProgressTracker {
+	now := time.Now()
+	return &ProgressTracker{progress: progress, start: now, last: now}
+}
+
+// Expect adds objects and bytes to the totals to transfer.
+func (t *ProgressTracker) Expect(objects int, bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.objects += objects
+	t.total += bytes
+}
+
+// Transfer returns the ProgressFunc of a single transfer, adding its progress to the aggregate.
+// It may be used concurrently with the other transfers.
+func (t *ProgressTracker) Transfer() ProgressFunc {
+	var reported int64
+	return func(p Progress) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.bytes += p.Bytes - reported
+		reported = p.Bytes
+		t.report(false)
+	}
+}
+
+// ObjectDone counts an object as transferred (or skipped, or failed).
+func (t *ProgressTracker) ObjectDone() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.objectsDone++
+	t.report(t.objectsDone >= t.objects)
+}
+
+// Progress returns the aggregate progress.
+func (t *ProgressTracker) Progress() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current()
+}
+
+func (t *ProgressTracker) current() Progress {
+	p := newProgress(t.bytes, max(t.total, t.bytes), t.start)
+	p.Objects = t.objects
+	p.ObjectsDone = t.objectsDone
+	return p
+}
+
+// report reports the progress if ProgressInterval passed since the last report, or if final
+func (t *ProgressTracker) report(final bool) {
+	now := time.Now()
+	if !final && now.Sub(t.last) < ProgressInterval {
+		return
+	}
+	t.last = now
+	t.progress(t.current())
+}
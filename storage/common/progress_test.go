@@ -0,0 +1,86 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressReader(t *testing.T) {
+	reports := []Progress{}
+	r := NewProgressReader(strings.NewReader("some data"), -1, func(p Progress) { reports = append(reports, p) })
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	// reading past EOF doesn't report again
+	r.Read(make([]byte, 1))
+
+	if len(reports) != 1 {
+		t.Fatalf("%d reports, want only the final one: %+v", len(reports), reports)
+	}
+	if p := reports[0]; p.Bytes != 9 || p.Total != 9 || p.ETA != 0 || p.Rate <= 0 {
+		t.Errorf("final progress %+v, want 9 of 9 bytes", p)
+	}
+}
+
+func TestProgressReaderUnknownTotal(t *testing.T) {
+	var last Progress
+	reader := io.MultiReader(strings.NewReader("some "), strings.NewReader("data"))
+	rc := NewProgressReadCloser(io.NopCloser(reader), -1, func(p Progress) { last = p })
+
+	buf := make([]byte, 5)
+	rc.Read(buf)
+	rc.Close()
+	if last.Bytes != 5 || last.Total != 5 {
+		t.Errorf("progress after Close %+v, want 5 bytes, with the total taken from the bytes read", last)
+	}
+}
+
+func TestProgressInterval(t *testing.T) {
+	defer func(interval time.Duration) { ProgressInterval = interval }(ProgressInterval)
+	ProgressInterval = 0
+
+	reports := []Progress{}
+	r := NewProgressReader(bytes.NewReader(make([]byte, 30)), -1, func(p Progress) { reports = append(reports, p) })
+	buf := make([]byte, 10)
+	for i := 0; i < 3; i++ {
+		r.Read(buf)
+	}
+	if len(reports) != 3 || reports[1].Bytes != 20 || reports[1].Total != 30 || reports[1].ETA < 0 {
+		t.Errorf("reports %+v, want one per read with an ETA", reports)
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	var mu sync.Mutex
+	var last Progress
+	tracker := NewProgressTracker(func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		last = p
+	})
+	tracker.Expect(2, 15)
+
+	wg := sync.WaitGroup{}
+	for _, content := range []string{"0123456789", "01234"} {
+		wg.Add(1)
+		go func(content string) {
+			defer wg.Done()
+			io.Copy(io.Discard, NewProgressReader(strings.NewReader(content), -1, tracker.Transfer()))
+			tracker.ObjectDone()
+		}(content)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if last.Bytes != 15 || last.Total != 15 || last.Objects != 2 || last.ObjectsDone != 2 {
+		t.Errorf("final progress %+v, want 15 of 15 bytes and 2 of 2 objects", last)
+	}
+	if p := tracker.Progress(); p.Bytes != last.Bytes || p.ObjectsDone != last.ObjectsDone {
+		t.Errorf("Progress() = %+v, want %+v", p, last)
+	}
+}
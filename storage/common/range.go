@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// rangeReadCloser reads a range of the content of rc, skipping to the offset on the first Read
+type rangeReadCloser struct {
+	rc      io.ReadCloser
+	offset  int64
+	reader  io.Reader
+	skipErr error
+}
+
+// NewRangeReadCloser returns a reader of the part of the content of rc that WithRange(offset, length) selects,
+// for wrappers that can't read a range of the stored content. The content before offset is read and discarded
+// (rc is seeked if it is an io.Seeker) on the first Read. Closing it closes rc.
+func NewRangeReadCloser(rc io.ReadCloser, offset int64, length int64) io.ReadCloser {
+	r := &rangeReadCloser{rc: rc, offset: max(offset, 0)}
+	if length > 0 {
+		r.reader = io.LimitReader(rc, length)
+	} else {
+		r.reader = rc
+	}
+	return r
+}
+
+func (r *rangeReadCloser) Read(p []byte) (int, error) {
+	if r.skipErr != nil {
+		return 0, r.skipErr
+	}
+	if r.offset > 0 {
+		offset := r.offset
+		r.offset = 0
+		if r.skipErr = skip(r.rc, offset); r.skipErr != nil {
+			return 0, r.skipErr
+		}
+	}
+	return r.reader.Read(p)
+}
+
+func (r *rangeReadCloser) Close() error {
+	return r.rc.Close()
+}
+
+// skip moves past the first n bytes of rc, content ending before that is an error
+func skip(rc io.ReadCloser, n int64) error {
+	if seeker, ok := rc.(io.Seeker); ok {
+		size, err := seeker.Seek(0, io.SeekEnd)
+		if err == nil && n > size {
+			return fmt.Errorf("range offset %d past the end of the content (%d bytes)", n, size)
+		}
+		if err == nil {
+			_, err = seeker.Seek(n, io.SeekStart)
+		}
+		return err
+	}
+	skipped, err := io.CopyN(io.Discard, rc, n)
+	if err == io.EOF {
+		return fmt.Errorf("range offset %d past the end of the content (%d bytes)", n, skipped)
+	}
+	return err
+}
+
+// ContentRangeSize returns the object size of the Content-Range header of a ranged read ("bytes 0-9/100"), or
+// contentLength if the read wasn't ranged.
+func ContentRangeSize(contentRange string, contentLength int64) int64 {
+	if _, total, ok := strings.Cut(contentRange, "/"); ok {
+		if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+			return size
+		}
+	}
+	return contentLength
+}
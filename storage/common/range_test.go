@@ -0,0 +1,55 @@
+package common
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRangeReadCloser(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(fileName, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{2, 3, "234"},
+		{7, 0, "789"},
+		{8, 10, "89"},
+		{10, 0, ""},
+	} {
+		f, err := os.Open(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readers := map[string]io.ReadCloser{
+			"seeker": f,
+			"stream": io.NopCloser(strings.NewReader("0123456789")),
+		}
+		for name, rc := range readers {
+			content, err := io.ReadAll(NewRangeReadCloser(rc, test.offset, test.length))
+			if err != nil || string(content) != test.want {
+				t.Errorf("%s range %d, %d = '%s', %v, want '%s'", name, test.offset, test.length, content, err, test.want)
+			}
+		}
+		f.Close()
+	}
+
+	if _, err := io.ReadAll(NewRangeReadCloser(io.NopCloser(strings.NewReader("0123")), 5, 0)); err == nil {
+		t.Error("range past the end should fail")
+	}
+}
+
+func TestContentRangeSize(t *testing.T) {
+	if size := ContentRangeSize("bytes 2-4/10", 3); size != 10 {
+		t.Errorf("ContentRangeSize(bytes 2-4/10) = %d, want 10", size)
+	}
+	if size := ContentRangeSize("", 3); size != 3 {
+		t.Errorf("ContentRangeSize() = %d, want the content length 3", size)
+	}
+}
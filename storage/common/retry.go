@@ -0,0 +1,96 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are retried. It is mapped to each provider's own retry
+// mechanism (AWS retryer, GCS Retryer, Azure RetryOptions), so all providers behave alike.
+// Zero fields use the values of DefaultRetryPolicy, except Jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per request, including the first
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, doubling with every further retry up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Jitter randomizes delays by up to this fraction (0 to 1), so clients don't retry in lockstep:
+	// 0.5 waits between 50% and 100% of the delay. GCS and Azure always apply their own jitter.
+	Jitter float64
+	// Retryable decides whether a failed attempt is retried; statusCode is the HTTP status of the
+	// response, 0 if there was none (network error). nil keeps the provider's own classification.
+	Retryable func(err error, statusCode int) bool
+	// OnRetry is called for each failed attempt that will be retried
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that will be retried.
+type RetryEvent struct {
+	// Attempt is the number of the failed attempt, starting at 1; 0 if the provider doesn't report it (GCS, Azure)
+	Attempt int
+	Err     error
+	// Delay before the retry; 0 if the provider doesn't report it (GCS, Azure)
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy returns 3 attempts with full jitter exponential backoff from 100ms up to 20s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  20 * time.Second,
+		Jitter:      1,
+	}
+}
+
+// WithDefaults returns the policy with zero fields set from DefaultRetryPolicy,
+// or MaxAttempts set to maxAttempts if that is > 0.
+func (p RetryPolicy) WithDefaults(maxAttempts int) RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+		if maxAttempts > 0 {
+			p.MaxAttempts = maxAttempts
+		}
+	}
+	if p.BaseBackoff == 0 {
+		p.BaseBackoff = defaults.BaseBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+	return p
+}
+
+// Backoff returns the delay after failed attempt number attempt (starting at 1).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// DefaultRetryable retries network errors and the HTTP statuses that indicate throttling or a
+// temporary server problem: 408, 429, 500, 502, 503 (S3 SlowDown) and 504.
+// It can be used to extend, rather than replace, the default classification in RetryPolicy.Retryable.
+func DefaultRetryable(err error, statusCode int) bool {
+	switch statusCode {
+	case 0:
+		return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if delay := p.Backoff(attempt); delay != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, delay, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if delay := p.Backoff(3); delay < 200*time.Millisecond || delay > 400*time.Millisecond {
+			t.Fatalf("Backoff(3) with jitter 0.5 = %v, want 200ms-400ms", delay)
+		}
+	}
+}
+
+func TestRetryPolicyWithDefaults(t *testing.T) {
+	p := RetryPolicy{MaxBackoff: time.Second}.WithDefaults(5)
+	if p.MaxAttempts != 5 || p.BaseBackoff != 100*time.Millisecond || p.MaxBackoff != time.Second || p.Jitter != 0 {
+		t.Errorf("WithDefaults(5) = %+v", p)
+	}
+
+	p = RetryPolicy{MaxAttempts: 2}.WithDefaults(5)
+	if p.MaxAttempts != 2 {
+		t.Errorf("WithDefaults(5) replaced MaxAttempts 2 with %d", p.MaxAttempts)
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	someErr := errors.New("failed")
+	for _, test := range []struct {
+		err        error
+		statusCode int
+		want       bool
+	}{
+		{someErr, 503, true},
+		{someErr, 429, true},
+		{someErr, 404, false},
+		{someErr, 0, true},
+		{context.Canceled, 0, false},
+		{nil, 200, false},
+	} {
+		if got := DefaultRetryable(test.err, test.statusCode); got != test.want {
+			t.Errorf("DefaultRetryable(%v, %d) = %v, want %v", test.err, test.statusCode, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import "time"
+
+// StorageClass is a provider-neutral storage class (AWS), access tier (Azure) or class (GCP).
+//
+//	                 AWS           GCP        Azure
+//	Standard         STANDARD      STANDARD   Hot
+//	InfrequentAccess STANDARD_IA   NEARLINE   Cool
+//	Archive          GLACIER       COLDLINE   Cold
+//	DeepArchive      DEEP_ARCHIVE  ARCHIVE    Archive
+//
+// Objects in AWS GLACIER/DEEP_ARCHIVE and Azure Archive must be restored before they can be read.
+type StorageClass string
+
+const (
+	StorageClassStandard         StorageClass = "STANDARD"
+	StorageClassInfrequentAccess StorageClass = "INFREQUENT_ACCESS"
+	StorageClassArchive          StorageClass = "ARCHIVE"
+	StorageClassDeepArchive      StorageClass = "DEEP_ARCHIVE"
+)
+
+// RestorePriority trades restore speed for cost.
+// Azure only distinguishes between High (Expedited) and Standard (Standard, Bulk).
+type RestorePriority string
+
+const (
+	RestorePriorityExpedited RestorePriority = "EXPEDITED"
+	RestorePriorityStandard  RestorePriority = "STANDARD"
+	RestorePriorityBulk      RestorePriority = "BULK"
+)
+
+type RestoreOptions struct {
+	// Days the restored copy is kept available (AWS only). Defaults to 1.
+	Days     int
+	Priority RestorePriority
+}
+
+type RestoreOption func(*RestoreOptions)
+
+// NewRestoreOptions applies opts to the default RestoreOptions.
+func NewRestoreOptions(opts ...RestoreOption) RestoreOptions {
+	o := RestoreOptions{
+		Days:     1,
+		Priority: RestorePriorityStandard,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func WithRestoreDays(days int) RestoreOption {
+	return func(o *RestoreOptions) {
+		o.Days = days
+	}
+}
+
+func WithRestorePriority(priority RestorePriority) RestoreOption {
+	return func(o *RestoreOptions) {
+		o.Priority = priority
+	}
+}
+
+// RestoreStatus describes the availability of a (possibly archived) object.
+type RestoreStatus struct {
+	// Archived is true if the object is in a class that must be restored before reading.
+	Archived bool
+	// InProgress is true while a restore is running.
+	InProgress bool
+	// Available is true if the object content can be read.
+	Available bool
+	// Expiry is when the restored copy is removed again (AWS only).
+	Expiry time.Time
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"io"
+	"os"
+)
+
+// CountingReader counts the bytes read through it.
+type CountingReader struct {
+	io.Reader
+	N int64
+}
+
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.N += int64(n)
+	return n, err
+}
+
+// countingSeeker is a CountingReader that seeks the reader it counts
+type countingSeeker struct {
+	*CountingReader
+	seeker io.Seeker
+}
+
+func (r countingSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
+
+// CountReader returns the reader to upload in place of reader, and a function returning the bytes uploaded, for
+// wrappers that report them. Readers that know their size (see ReaderSize) are returned as they are, so that
+// providers still get their length, and count that size. Other readers are counted as read, seekable readers keep
+// their io.Seeker.
+func CountReader(reader io.Reader) (io.Reader, func() int64) {
+	if size := ReaderSize(reader); size >= 0 {
+		return reader, func() int64 { return size }
+	}
+	counter := &CountingReader{Reader: reader}
+	if seeker, ok := reader.(io.Seeker); ok {
+		return countingSeeker{counter, seeker}, func() int64 { return counter.N }
+	}
+	return counter, func() int64 { return counter.N }
+}
+
+// FileTransferSize returns the size of fileName after a transfer that ended with err, or -1 if it failed.
+func FileTransferSize(err error, fileName string) int64 {
+	if err != nil {
+		return -1
+	}
+	info, statErr := os.Stat(fileName)
+	if statErr != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// WriteFile writes the content of rc, as returned by GetObject, to fileName and closes rc. A partially written file
+// is removed when reading rc fails.
+func WriteFile(rc io.ReadCloser, fileName string) error {
+	defer rc.Close()
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		os.Remove(fileName)
+		return err
+	}
+
+	return f.Close()
+}
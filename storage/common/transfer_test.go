@@ -0,0 +1,46 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCountReader(t *testing.T) {
+	reader, count := CountReader(strings.NewReader("data"))
+	if ReaderSize(reader) != 4 || count() != 4 {
+		t.Errorf("sized reader: size %d, count %d, want 4", ReaderSize(reader), count())
+	}
+
+	reader, count = CountReader(iotest.OneByteReader(strings.NewReader("data")))
+	if _, err := io.ReadAll(reader); err != nil || count() != 4 {
+		t.Errorf("stream: count %d, %v, want 4", count(), err)
+	}
+
+	reader, _ = CountReader(struct{ io.ReadSeeker }{strings.NewReader("data")})
+	if _, ok := reader.(io.Seeker); !ok {
+		t.Error("seekable reader can't seek once counted")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "object")
+	if err := WriteFile(io.NopCloser(strings.NewReader("data")), fileName); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(fileName); err != nil || string(data) != "data" {
+		t.Errorf("read %q, %v, want data", data, err)
+	}
+
+	failing := io.MultiReader(strings.NewReader("da"), iotest.ErrReader(errors.New("read failed")))
+	if err := WriteFile(io.NopCloser(failing), fileName); err == nil {
+		t.Error("failed read written")
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("partial file kept: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewTransport returns an HTTP transport like http.DefaultTransport. A timeout > 0 limits connecting,
+// the TLS handshake and waiting for the response headers of each request, but not the transfer of
+// content, so large uploads and downloads are not cut off.
+// insecureSkipVerify disables TLS certificate verification, only meant for local emulators with
+// self-signed certificates.
+func NewTransport(timeout time.Duration, insecureSkipVerify bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if timeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+	}
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return transport
+}
@@ -0,0 +1,344 @@
+// Package compression compresses objects on store and decompresses them on retrieve, with any provider.
+//
+//	compressed := compression.New(cloudStorage, compression.WithAlgorithm(compression.Zstd))
+//
+// Objects are stored with Content-Encoding set to the algorithm and the algorithm in the object metadata
+// (cc_compression), and read back decompressed. Objects that are already compressed (images, video, archives, ...)
+// are stored as they are, see DefaultSkip and WithSkip.
+//
+// Object sizes listed by ListBucketContent and WalkBucketContent are those of the compressed content. StatObject
+// returns the uncompressed size, recorded on store; the compressed content of readers of unknown size is buffered
+// in a temporary file to count it.
+// Combined with client-side encryption, compression must wrap encryption (come first in
+// storage.Chain), as encrypted content doesn't compress.
+package compression
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+type Algorithm string
+
+const (
+	Gzip Algorithm = "gzip"
+	Zstd Algorithm = "zstd"
+)
+
+// Object metadata keys (with underscores, as Azure metadata names must be identifiers)
+const (
+	// MetadataAlgorithm holds the compression algorithm
+	MetadataAlgorithm = "cc_compression"
+	// MetadataSize holds the uncompressed size
+	MetadataSize = "cc_compression_size"
+)
+
+// SkipFunc reports whether an object is stored uncompressed; contentType is guessed from the key's extension
+// if it isn't given.
+type SkipFunc func(objectKey string, contentType string) bool
+
+type options struct {
+	algorithm Algorithm
+	level     int
+	skip      SkipFunc
+}
+
+type Option func(*options)
+
+// WithAlgorithm sets the compression algorithm, by default Gzip.
+// Objects are decompressed with the algorithm they were stored with.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = algorithm
+	}
+}
+
+// WithLevel sets the compression level of the algorithm: 1 (fastest) to 9 for gzip, 1 to 22 for zstd.
+// By default the algorithm's default level is used.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithSkip replaces DefaultSkip, deciding which objects are stored uncompressed.
+func WithSkip(skip SkipFunc) Option {
+	return func(o *options) {
+		o.skip = skip
+	}
+}
+
+// Compressed is a storage.Storage compressing objects put in, and decompressing objects read from, the storage
+// it wraps.
+type Compressed struct {
+	storage.Wrapper
+	options options
+}
+
+var _ storage.Storage = &Compressed{}
+
+// New compresses the objects of next.
+func New(next storage.Storage, opts ...Option) *Compressed {
+	o := options{algorithm: Gzip, skip: DefaultSkip}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Compressed{Wrapper: storage.Wrapper{Next: next}, options: o}
+}
+
+// Middleware returns a storage.Middleware compressing the objects of the wrapped storage.
+func Middleware(opts ...Option) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return New(next, opts...)
+	}
+}
+
+func (c *Compressed) StoreObject(bucketName string, objectKey string, fileName string, opts ...storage.PutOption) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.PutObject(bucketName, objectKey, f, opts...)
+}
+
+// PutObject compresses the content of reader, unless the skip policy excludes the object or its content is
+// encoded already (storage.WithContentEncoding).
+func (c *Compressed) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	o := storage.NewPutOptions(opts...)
+	contentType := o.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(objectKey))
+	}
+	// content with an encoding is encoded already
+	if o.ContentEncoding != "" || (c.options.skip != nil && c.options.skip(objectKey, contentType)) {
+		return c.Next.PutObject(bucketName, objectKey, reader, opts...)
+	}
+
+	size := storage.ReaderSize(reader)
+
+	// report the progress of the uncompressed content
+	if o.Progress != nil {
+		reader = storage.NewProgressReader(reader, -1, o.Progress)
+		opts = append(opts, storage.WithUploadProgress(nil))
+	}
+
+	counter := &storage.CountingReader{Reader: reader}
+	compressing, err := c.compress(counter)
+	if err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+	defer compressing.Close()
+
+	var content io.Reader = compressing
+	if size < 0 {
+		// the uncompressed size of a stream is known once it is compressed, the compressed content is buffered
+		// in a temporary file
+		tmp, err := os.CreateTemp("", "compression-*")
+		if err != nil {
+			return fmt.Errorf("PutObject(%w)", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, compressing); err != nil {
+			return fmt.Errorf("PutObject(%w)", err)
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("PutObject(%w)", err)
+		}
+		content, size = tmp, counter.N
+	}
+
+	opts = append(opts,
+		storage.WithContentEncoding(string(c.options.algorithm)),
+		storage.WithMetadata(map[string]string{
+			MetadataAlgorithm: string(c.options.algorithm),
+			MetadataSize:      strconv.FormatInt(size, 10),
+		}),
+	)
+	return c.Next.PutObject(bucketName, objectKey, content, opts...)
+}
+
+// compress returns a reader of the compressed content of r, compressed while read
+func (c *Compressed) compress(r io.Reader) (io.ReadCloser, error) {
+	var newWriter func(w io.Writer) (io.WriteCloser, error)
+	switch c.options.algorithm {
+	case Gzip:
+		level := c.options.level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, err
+		}
+		newWriter = func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) }
+	case Zstd:
+		level := zstd.SpeedDefault
+		if c.options.level != 0 {
+			level = zstd.EncoderLevelFromZstd(c.options.level)
+		}
+		newWriter = func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm '%s'", c.options.algorithm)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		zw, err := newWriter(pw)
+		if err == nil {
+			_, err = io.Copy(zw, r)
+			if closeErr := zw.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+func (c *Compressed) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...storage.GetOption) error {
+	rc, err := c.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(rc, fileName)
+}
+
+// GetObject decompresses the content of the object while read, as recorded in the metadata of the read itself.
+// Objects stored uncompressed are read as they are. Ranges (storage.WithRange) of compressed objects are
+// decompressed from the start of the object, the object is stat'ed first to know whether it is compressed.
+func (c *Compressed) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	o := storage.NewGetOptions(opts...)
+	compressed := true
+	if o.Ranged() {
+		info, err := c.Next.StatObject(bucketName, objectKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		compressed = info.Metadata[MetadataAlgorithm] != ""
+	}
+
+	rc, read, err := c.read(bucketName, objectKey, compressed && o.Ranged(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if !compressed && read.Metadata[MetadataAlgorithm] != "" {
+		// replaced by a compressed object since stat'ed
+		rc.Close()
+		compressed = true
+		rc, read, err = c.read(bucketName, objectKey, true, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.Info != nil {
+		*o.Info = uncompressedInfo(read)
+	}
+
+	content := rc
+	size := read.Size
+	if algorithm := Algorithm(read.Metadata[MetadataAlgorithm]); algorithm != "" {
+		content, err = decompress(rc, algorithm)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, err)
+		}
+		size, err = strconv.ParseInt(read.Metadata[MetadataSize], 10, 64)
+		if err != nil {
+			size = -1
+		}
+	}
+	if compressed && o.Ranged() {
+		content = storage.NewRangeReadCloser(content, o.Offset, o.Length)
+	}
+	if o.Progress != nil {
+		return storage.NewProgressReadCloser(content, o.RangeSize(size), o.Progress), nil
+	}
+	return content, nil
+}
+
+// read reads the object from the start if fromStart, rather than the range of opts, with the info of the read.
+// The progress of opts is reported by GetObject, for the decompressed content.
+func (c *Compressed) read(bucketName string, objectKey string, fromStart bool, opts []storage.GetOption) (io.ReadCloser, storage.ObjectInfo, error) {
+	read := storage.ObjectInfo{}
+	opts = append(opts, storage.WithObjectInfo(&read), storage.WithDownloadProgress(nil), storage.WithRawContent())
+	if fromStart {
+		opts = append(opts, storage.WithRange(0, 0))
+	}
+	rc, err := c.Next.GetObject(bucketName, objectKey, opts...)
+	return rc, read, err
+}
+
+// StatObject returns the object without the compression metadata and content encoding, as GetObject returns
+// the decompressed content. The size is the uncompressed size; objects stored without it (by earlier versions, which
+// only recorded known sizes) report the compressed size.
+func (c *Compressed) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := c.Next.StatObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return info, err
+	}
+	return uncompressedInfo(info), nil
+}
+
+// uncompressedInfo returns info of a compressed object as StatObject returns it
+func uncompressedInfo(info storage.ObjectInfo) storage.ObjectInfo {
+	if info.Metadata[MetadataAlgorithm] == "" {
+		return info
+	}
+
+	if size, err := strconv.ParseInt(info.Metadata[MetadataSize], 10, 64); err == nil {
+		info.Size = size
+	}
+	info.ContentEncoding = ""
+	metadata := map[string]string{}
+	for k, v := range info.Metadata {
+		if !strings.HasPrefix(k, "cc_compression") {
+			metadata[k] = v
+		}
+	}
+	info.Metadata = metadata
+	return info
+}
+
+// decompressingReader closes the decompressor and the compressed content
+type decompressingReader struct {
+	io.Reader
+	closeDecompressor func()
+	rc                io.ReadCloser
+}
+
+func (d *decompressingReader) Close() error {
+	d.closeDecompressor()
+	return d.rc.Close()
+}
+
+func decompress(rc io.ReadCloser, algorithm Algorithm) (io.ReadCloser, error) {
+	switch algorithm {
+	case Gzip:
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressingReader{Reader: zr, closeDecompressor: func() { zr.Close() }, rc: rc}, nil
+	case Zstd:
+		zr, err := zstd.NewReader(rc, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &decompressingReader{Reader: zr, closeDecompressor: zr.Close, rc: rc}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm '%s'", algorithm)
+	}
+}
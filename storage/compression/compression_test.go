@@ -0,0 +1,277 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/encryption"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	for _, algorithm := range []Algorithm{Gzip, Zstd} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T) storage.Storage {
+				return New(memory.NewMemoryClient(), WithAlgorithm(algorithm))
+			})
+		})
+	}
+}
+
+func newBucket(t *testing.T) *memory.MemoryClient {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// stored returns the object as stored in m
+func stored(t *testing.T, m *memory.MemoryClient, objectKey string) (storage.ObjectInfo, []byte) {
+	t.Helper()
+	info, err := m.StatObject("bucket", objectKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := m.GetObject("bucket", objectKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info, data
+}
+
+func TestCompress(t *testing.T) {
+	content := strings.Repeat(`{"level":"info","msg":"request served"}`+"\n", 1000)
+
+	for _, algorithm := range []Algorithm{Gzip, Zstd} {
+		m := newBucket(t)
+		c := New(m, WithAlgorithm(algorithm), WithLevel(3))
+
+		// a reader of unknown size
+		err := c.PutObject("bucket", "log.json", io.MultiReader(strings.NewReader(content)), storage.WithMetadata(map[string]string{"owner": "test"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, data := stored(t, m, "log.json")
+		if info.ContentEncoding != string(algorithm) || info.Metadata[MetadataAlgorithm] != string(algorithm) {
+			t.Errorf("%s: stored object = %+v, want content encoding and metadata '%s'", algorithm, info, algorithm)
+		}
+		if len(data)*10 > len(content) {
+			t.Errorf("%s: stored %d bytes of %d, want at least 10x compression", algorithm, len(data), len(content))
+		}
+
+		info, err = c.StatObject("bucket", "log.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ContentEncoding != "" || len(info.Metadata) != 1 || info.Metadata["owner"] != "test" || info.Size != int64(len(content)) {
+			t.Errorf("%s: StatObject = %+v, want the uncompressed size of the stream without compression metadata", algorithm, info)
+		}
+
+		read := storage.ObjectInfo{}
+		rc, err := c.GetObject("bucket", "log.json", storage.WithObjectInfo(&read))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decompressed, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(decompressed) != content {
+			t.Errorf("%s: GetObject = %d bytes, %v, want the %d bytes stored", algorithm, len(decompressed), err, len(content))
+		}
+		if read.Size != int64(len(content)) {
+			t.Errorf("%s: GetObject ObjectInfo size %d, want the uncompressed size %d", algorithm, read.Size, len(content))
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	m := newBucket(t)
+	c := New(m)
+
+	for key, opts := range map[string][]storage.PutOption{
+		"photo.jpg":   nil,
+		"archive.tgz": nil,
+		"video":       {storage.WithContentType("video/mp4")},
+		"encoded.txt": {storage.WithContentEncoding("br")},
+	} {
+		if err := c.PutObject("bucket", key, strings.NewReader("content"), opts...); err != nil {
+			t.Fatal(err)
+		}
+		info, data := stored(t, m, key)
+		if info.Metadata[MetadataAlgorithm] != "" || string(data) != "content" {
+			t.Errorf("%s stored as %+v, want uncompressed", key, info)
+		}
+	}
+
+	c = New(m, WithSkip(func(objectKey string, contentType string) bool { return contentType == "text/plain; charset=utf-8" }))
+	if err := c.PutObject("bucket", "notes.txt", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := stored(t, m, "notes.txt"); info.Metadata[MetadataAlgorithm] != "" {
+		t.Errorf("notes.txt stored as %+v, want skipped by content type guessed from extension", info)
+	}
+}
+
+func TestDefaultSkip(t *testing.T) {
+	for _, test := range []struct {
+		key, contentType string
+		skip             bool
+	}{
+		{"data.json", "application/json", false},
+		{"image.svg", "image/svg+xml", false},
+		{"logs/app.log", "", false},
+		{"backup.tar.gz", "", true},
+		{"report.PDF", "", true},
+		{"image", "image/png", true},
+		{"song", "audio/mpeg", true},
+		{"data", "application/zip; foo=bar", true},
+	} {
+		if skip := DefaultSkip(test.key, test.contentType); skip != test.skip {
+			t.Errorf("DefaultSkip(%s, %s) = %t, want %t", test.key, test.contentType, skip, test.skip)
+		}
+	}
+}
+
+func TestCompressEncrypted(t *testing.T) {
+	keys, err := encryption.NewLocalKeyProvider(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newBucket(t)
+	s := storage.Chain(m, Middleware(), encryption.Middleware(keys))
+
+	content := strings.Repeat("compressible ", 1000)
+	if err := s.PutObject("bucket", "object", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if _, data := stored(t, m, "object"); len(data) > len(content)/10 {
+		t.Errorf("stored %d bytes of %d, want compressed before encryption", len(data), len(content))
+	}
+
+	rc, err := s.GetObject("bucket", "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if data, err := io.ReadAll(rc); err != nil || string(data) != content {
+		t.Errorf("GetObject = %d bytes, %v, want the %d bytes stored", len(data), err, len(content))
+	}
+}
+
+// replacedAfterStat replaces the object with content after it is stat'ed, as a concurrent writer would
+type replacedAfterStat struct {
+	storage.Wrapper
+	replacement storage.Storage
+	content     string
+}
+
+func (r replacedAfterStat) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := r.Next.StatObject(bucketName, objectKey, opts...)
+	if err == nil {
+		err = r.replacement.PutObject(bucketName, objectKey, strings.NewReader(r.content))
+	}
+	return info, err
+}
+
+func TestReplacedObject(t *testing.T) {
+	compressed := strings.Repeat("compressed ", 100)
+	plain := strings.Repeat("plain ", 100)
+
+	m := newBucket(t)
+	for _, test := range []struct {
+		name                string
+		stored, replacement string
+		replaceWith         storage.Storage
+	}{
+		{"compressed by plain", compressed, plain, m},
+		{"plain by compressed", plain, compressed, New(m, WithAlgorithm(Gzip))},
+	} {
+		storedWith := storage.Storage(New(m, WithAlgorithm(Gzip)))
+		if test.stored == plain {
+			storedWith = m
+		}
+		c := New(replacedAfterStat{storage.Wrapper{Next: m}, test.replaceWith, test.replacement})
+
+		for _, opts := range [][]storage.GetOption{nil, {storage.WithRange(6, 5)}} {
+			if err := storedWith.PutObject("bucket", "object", strings.NewReader(test.stored)); err != nil {
+				t.Fatal(err)
+			}
+			// either object may be read, but decoded as stored
+			before, after := test.stored, test.replacement
+			if len(opts) > 0 {
+				before, after = before[6:11], after[6:11]
+			}
+
+			rc, err := c.GetObject("bucket", "object", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil || string(data) != before && string(data) != after {
+				t.Errorf("%s, %d options: GetObject = '%.20s', %v, want '%.20s' or '%.20s'", test.name, len(opts), data, err, before, after)
+			}
+		}
+	}
+}
+
+// transcoding decompresses gzip objects while read unless read raw, as GCS does
+type transcoding struct {
+	storage.Wrapper
+}
+
+func (tr transcoding) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	info, err := tr.Next.StatObject(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := tr.Next.GetObject(bucketName, objectKey, opts...)
+	if err != nil || info.ContentEncoding != "gzip" || storage.NewGetOptions(opts...).RawContent {
+		return rc, err
+	}
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, rc}, nil
+}
+
+func TestTranscodingStorage(t *testing.T) {
+	content := strings.Repeat("compressible ", 100)
+	c := New(transcoding{storage.Wrapper{Next: newBucket(t)}}, WithAlgorithm(Gzip))
+	if err := c.PutObject("bucket", "object", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range [][]storage.GetOption{nil, {storage.WithRange(13, 12)}} {
+		want := content
+		if len(opts) > 0 {
+			want = content[13:25]
+		}
+		rc, err := c.GetObject("bucket", "object", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(data) != want {
+			t.Errorf("%d options: GetObject = '%.20s', %v, want '%.20s'", len(opts), data, err, want)
+		}
+	}
+}
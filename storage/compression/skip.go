@@ -0,0 +1,60 @@
+package compression
+
+import (
+	"path"
+	"strings"
+)
+
+// compressedExtensions are file extensions of formats that are compressed already
+var compressedExtensions = map[string]bool{
+	// archives and compressed files
+	".gz": true, ".tgz": true, ".zip": true, ".zst": true, ".bz2": true, ".xz": true, ".lz4": true,
+	".br": true, ".7z": true, ".rar": true, ".jar": true,
+	// images, audio and video
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".heic": true,
+	".mp3": true, ".aac": true, ".ogg": true, ".flac": true, ".opus": true,
+	".mp4": true, ".mkv": true, ".mov": true, ".avi": true, ".webm": true,
+	// documents and data
+	".pdf": true, ".docx": true, ".xlsx": true, ".pptx": true, ".woff": true, ".woff2": true,
+	".parquet": true, ".orc": true, ".avro": true,
+}
+
+// compressedContentTypes are content types of formats that are compressed already
+var compressedContentTypes = map[string]bool{
+	"application/gzip":               true,
+	"application/x-gzip":             true,
+	"application/zip":                true,
+	"application/zstd":               true,
+	"application/x-bzip2":            true,
+	"application/x-xz":               true,
+	"application/x-7z-compressed":    true,
+	"application/vnd.rar":            true,
+	"application/x-rar-compressed":   true,
+	"application/java-archive":       true,
+	"application/pdf":                true,
+	"application/vnd.apache.parquet": true,
+	"font/woff":                      true,
+	"font/woff2":                     true,
+}
+
+// DefaultSkip skips objects whose extension or content type is that of a compressed format: archives,
+// images (but SVG), audio, video, PDF, office documents, web fonts and columnar data files.
+func DefaultSkip(objectKey string, contentType string) bool {
+	if compressedExtensions[strings.ToLower(path.Ext(objectKey))] {
+		return true
+	}
+
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case compressedContentTypes[mediaType]:
+		return true
+	case strings.HasPrefix(mediaType, "image/"):
+		return mediaType != "image/svg+xml" && mediaType != "image/bmp" && mediaType != "image/tiff"
+	case strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"):
+		return mediaType != "audio/wav" && mediaType != "audio/x-wav"
+	case strings.HasPrefix(mediaType, "application/vnd.openxmlformats-officedocument."):
+		return true
+	}
+	return false
+}
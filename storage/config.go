@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Options can be loaded from a YAML or JSON config file with named profiles:
+
+	default_profile: aws
+	profiles:
+	  aws:
+	    provider: S3
+	    location: eu-west-1
+	    credentials_file: /path/to/aws/credentials
+	    max_attempts: 5
+	    retry:                 # backoff between attempts, see RetryPolicy
+	      base_backoff: 200ms
+	      max_backoff: 10s
+	      jitter: 1
+	  gcp:
+	    provider: GCP
+	    project: my-project
+	    location: EU
+	    credentials_file: /path/to/sa-json.json
+	    timeout: 30s
+	  minio:
+	    provider: S3
+	    endpoint: http://localhost:9000
+	    use_path_style: true
+	  azure:
+	    provider: AZURE
+	    account: mystorageaccount
+	  local:
+	    provider: LOCAL
+	    root_dir: /tmp/buckets
+
+and/or from environment variables, which override the file:
+
+	CLOUDCONNECT_CONFIG                config file
+	CLOUDCONNECT_PROFILE               profile to use from the config file
+	CLOUDCONNECT_PROVIDER              S3, GCP, AZURE, LOCAL, MEMORY or any registered type
+	CLOUDCONNECT_LOCATION              AWS region / GCP location
+	CLOUDCONNECT_PROJECT               GCP project id
+	CLOUDCONNECT_ACCOUNT               Azure storage account
+	CLOUDCONNECT_ROOT_DIR              local root directory
+	CLOUDCONNECT_CREDENTIALS_FILE      AWS shared credentials / GCP service account file
+	CLOUDCONNECT_TIMEOUT               request timeout, as in 30s
+	CLOUDCONNECT_MAX_ATTEMPTS          attempts per request
+	CLOUDCONNECT_RETRY_BASE_BACKOFF    delay before the first retry, as in 100ms
+	CLOUDCONNECT_RETRY_MAX_BACKOFF     maximum delay between retries
+	CLOUDCONNECT_RETRY_JITTER          fraction (0 to 1) of the delay to randomize
+	CLOUDCONNECT_ENDPOINT              service URL of S3 compatible services or emulators
+	CLOUDCONNECT_USE_PATH_STYLE        true for S3 path style bucket addressing
+	CLOUDCONNECT_INSECURE_SKIP_VERIFY  true to skip TLS certificate verification
+	CLOUDCONNECT_<CREDENTIAL>          credentials, as in the credentials section below
+
+Credentials replacing the provider defaults go in a credentials section of the profile (keys) or
+CLOUDCONNECT_<KEY IN UPPERCASE> environment variables, as in CLOUDCONNECT_AWS_ACCESS_KEY_ID:
+
+	  aws-prod:
+	    provider: S3
+	    credentials:
+	      aws_access_key_id: AKIA...          # with aws_secret_access_key, optional aws_session_token
+	      aws_secret_access_key: ...
+	      aws_role_arn: arn:aws:iam::123456789012:role/deploy  # role to assume
+	      # or aws_profile: prod
+	  gcp-prod:
+	    provider: GCP
+	    project: my-project
+	    credentials:
+	      gcp_service_account_json: '{"type": "service_account", ...}'
+	  azure-prod:
+	    provider: AZURE
+	    account: mystorageaccount
+	    credentials:
+	      azure_shared_key: ...               # or one of azure_sas_token, azure_connection_string
+	                                          # or azure_tenant_id, azure_client_id and azure_client_secret
+*/
+
+const envPrefix = "CLOUDCONNECT_"
+
+// Profile is the config file/environment representation of Options.
+type Profile struct {
+	Provider        string `yaml:"provider" json:"provider"`
+	Location        string `yaml:"location" json:"location"`
+	Project         string `yaml:"project" json:"project"`
+	Account         string `yaml:"account" json:"account"`
+	RootDir         string `yaml:"root_dir" json:"root_dir"`
+	CredentialsFile string `yaml:"credentials_file" json:"credentials_file"`
+	Timeout         string `yaml:"timeout" json:"timeout"`
+	MaxAttempts     int    `yaml:"max_attempts" json:"max_attempts"`
+
+	Endpoint           string `yaml:"endpoint" json:"endpoint"`
+	UsePathStyle       bool   `yaml:"use_path_style" json:"use_path_style"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+
+	Retry       ProfileRetry       `yaml:"retry" json:"retry"`
+	Credentials ProfileCredentials `yaml:"credentials" json:"credentials"`
+}
+
+// ProfileRetry is the config file/environment representation of RetryPolicy; MaxAttempts comes from the profile.
+type ProfileRetry struct {
+	BaseBackoff string  `yaml:"base_backoff" json:"base_backoff"`
+	MaxBackoff  string  `yaml:"max_backoff" json:"max_backoff"`
+	Jitter      float64 `yaml:"jitter" json:"jitter"`
+}
+
+// ProfileCredentials is the config file/environment representation of Credentials.
+type ProfileCredentials struct {
+	AWSAccessKeyId        string `yaml:"aws_access_key_id" json:"aws_access_key_id"`
+	AWSSecretAccessKey    string `yaml:"aws_secret_access_key" json:"aws_secret_access_key"`
+	AWSSessionToken       string `yaml:"aws_session_token" json:"aws_session_token"`
+	AWSProfile            string `yaml:"aws_profile" json:"aws_profile"`
+	AWSRoleARN            string `yaml:"aws_role_arn" json:"aws_role_arn"`
+	GCPServiceAccountJSON string `yaml:"gcp_service_account_json" json:"gcp_service_account_json"`
+	AzureSharedKey        string `yaml:"azure_shared_key" json:"azure_shared_key"`
+	AzureSASToken         string `yaml:"azure_sas_token" json:"azure_sas_token"`
+	AzureConnectionString string `yaml:"azure_connection_string" json:"azure_connection_string"`
+	AzureTenantId         string `yaml:"azure_tenant_id" json:"azure_tenant_id"`
+	AzureClientId         string `yaml:"azure_client_id" json:"azure_client_id"`
+	AzureClientSecret     string `yaml:"azure_client_secret" json:"azure_client_secret"`
+}
+
+type configFile struct {
+	DefaultProfile string             `yaml:"default_profile" json:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles" json:"profiles"`
+}
+
+// LoadOptions loads Options from a profile in a YAML (.yaml, .yml) or JSON (.json) config file,
+// overridden by CLOUDCONNECT_* environment variables, and validates them.
+// An empty profile selects $CLOUDCONNECT_PROFILE, the file's default_profile or the only profile, in that order.
+func LoadOptions(fileName string, profile string) (Options, error) {
+	p, err := loadProfile(fileName, profile)
+	if err != nil {
+		return Options{}, err
+	}
+	return profileOptions(p.withEnv())
+}
+
+// OptionsFromEnv loads Options from the config file in $CLOUDCONNECT_CONFIG, if set,
+// overridden by CLOUDCONNECT_* environment variables, and validates them.
+func OptionsFromEnv() (Options, error) {
+	p := Profile{}
+	if fileName := os.Getenv(envPrefix + "CONFIG"); fileName != "" {
+		var err error
+		p, err = loadProfile(fileName, "")
+		if err != nil {
+			return Options{}, err
+		}
+	}
+	return profileOptions(p.withEnv())
+}
+
+func loadProfile(fileName string, profile string) (Profile, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	config := configFile{}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	case ".json":
+		err = json.Unmarshal(data, &config)
+	default:
+		return Profile{}, fmt.Errorf("config file '%s' must be .yaml, .yml or .json", fileName)
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("config file '%s': %w", fileName, err)
+	}
+
+	if profile == "" {
+		profile = os.Getenv(envPrefix + "PROFILE")
+	}
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
+	if profile == "" && len(config.Profiles) == 1 {
+		for name := range config.Profiles {
+			profile = name
+		}
+	}
+
+	p, ok := config.Profiles[profile]
+	if !ok {
+		names := []string{}
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("config file '%s': no profile '%s' (profiles: %s)", fileName, profile, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// withEnv returns the profile with fields replaced by the CLOUDCONNECT_* environment variables that are set,
+// and an error for invalid numbers or booleans
+func (p Profile) withEnv() (Profile, error) {
+	errs := []error{}
+	for name, field := range map[string]*string{
+		"PROVIDER":         &p.Provider,
+		"LOCATION":         &p.Location,
+		"PROJECT":          &p.Project,
+		"ACCOUNT":          &p.Account,
+		"ROOT_DIR":         &p.RootDir,
+		"CREDENTIALS_FILE": &p.CredentialsFile,
+		"TIMEOUT":          &p.Timeout,
+		"ENDPOINT":         &p.Endpoint,
+
+		"RETRY_BASE_BACKOFF": &p.Retry.BaseBackoff,
+		"RETRY_MAX_BACKOFF":  &p.Retry.MaxBackoff,
+
+		"AWS_ACCESS_KEY_ID":        &p.Credentials.AWSAccessKeyId,
+		"AWS_SECRET_ACCESS_KEY":    &p.Credentials.AWSSecretAccessKey,
+		"AWS_SESSION_TOKEN":        &p.Credentials.AWSSessionToken,
+		"AWS_PROFILE":              &p.Credentials.AWSProfile,
+		"AWS_ROLE_ARN":             &p.Credentials.AWSRoleARN,
+		"GCP_SERVICE_ACCOUNT_JSON": &p.Credentials.GCPServiceAccountJSON,
+		"AZURE_SHARED_KEY":         &p.Credentials.AzureSharedKey,
+		"AZURE_SAS_TOKEN":          &p.Credentials.AzureSASToken,
+		"AZURE_CONNECTION_STRING":  &p.Credentials.AzureConnectionString,
+		"AZURE_TENANT_ID":          &p.Credentials.AzureTenantId,
+		"AZURE_CLIENT_ID":          &p.Credentials.AzureClientId,
+		"AZURE_CLIENT_SECRET":      &p.Credentials.AzureClientSecret,
+	} {
+		if value, ok := os.LookupEnv(envPrefix + name); ok {
+			*field = value
+		}
+	}
+	if value, ok := os.LookupEnv(envPrefix + "MAX_ATTEMPTS"); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sMAX_ATTEMPTS '%s' must be a number", envPrefix, value))
+		}
+		p.MaxAttempts = attempts
+	}
+	if value, ok := os.LookupEnv(envPrefix + "RETRY_JITTER"); ok {
+		jitter, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sRETRY_JITTER '%s' must be a number", envPrefix, value))
+		}
+		p.Retry.Jitter = jitter
+	}
+	for name, field := range map[string]*bool{
+		"USE_PATH_STYLE":       &p.UsePathStyle,
+		"INSECURE_SKIP_VERIFY": &p.InsecureSkipVerify,
+	} {
+		if value, ok := os.LookupEnv(envPrefix + name); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s '%s' must be true or false", envPrefix, name, value))
+			}
+			*field = b
+		}
+	}
+	return p, errors.Join(errs...)
+}
+
+// profileOptions converts the profile to Options, reporting all problems (including envErr) at once
+func profileOptions(p Profile, envErr error) (Options, error) {
+	opts := Options{
+		StorageType:          StorageType(strings.ToUpper(p.Provider)),
+		GCP_ProjectId:        p.Project,
+		Azure_StorageAccount: p.Account,
+		Local_RootDir:        p.RootDir,
+		Location:             p.Location,
+		CredentialsFile:      p.CredentialsFile,
+		MaxAttempts:          p.MaxAttempts,
+		Endpoint:             p.Endpoint,
+		UsePathStyle:         p.UsePathStyle,
+		InsecureSkipVerify:   p.InsecureSkipVerify,
+		Credentials: Credentials{
+			AWS_AccessKeyId:        p.Credentials.AWSAccessKeyId,
+			AWS_SecretAccessKey:    p.Credentials.AWSSecretAccessKey,
+			AWS_SessionToken:       p.Credentials.AWSSessionToken,
+			AWS_Profile:            p.Credentials.AWSProfile,
+			AWS_RoleARN:            p.Credentials.AWSRoleARN,
+			Azure_SharedKey:        p.Credentials.AzureSharedKey,
+			Azure_SASToken:         p.Credentials.AzureSASToken,
+			Azure_ConnectionString: p.Credentials.AzureConnectionString,
+			Azure_TenantId:         p.Credentials.AzureTenantId,
+			Azure_ClientId:         p.Credentials.AzureClientId,
+			Azure_ClientSecret:     p.Credentials.AzureClientSecret,
+		},
+	}
+	if p.Credentials.GCPServiceAccountJSON != "" {
+		opts.Credentials.GCP_ServiceAccountJSON = []byte(p.Credentials.GCPServiceAccountJSON)
+	}
+
+	errs := []error{envErr}
+	opts.Timeout = parseDuration("timeout", p.Timeout, &errs)
+	if p.Retry != (ProfileRetry{}) {
+		opts.RetryPolicy = &RetryPolicy{
+			BaseBackoff: parseDuration("retry.base_backoff", p.Retry.BaseBackoff, &errs),
+			MaxBackoff:  parseDuration("retry.max_backoff", p.Retry.MaxBackoff, &errs),
+			Jitter:      p.Retry.Jitter,
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return opts, errors.Join(errs...)
+}
+
+// parseDuration returns the duration in value, 0 if empty, adding an error to errs if it is invalid
+func parseDuration(name string, value string, errs *[]error) time.Duration {
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s '%s' must be a duration, as in 30s", name, value))
+	}
+	return d
+}
+
+// Validate checks the fields required by the built-in storage types, returning an error listing all problems.
+func (opts Options) Validate() error {
+	errs := []error{}
+	if opts.StorageType == "" {
+		errs = append(errs, fmt.Errorf("Options.StorageType must be provided"))
+	}
+
+	switch opts.StorageType {
+	case TypeGCP:
+		if opts.GCP_ProjectId == "" {
+			errs = append(errs, fmt.Errorf("Options.GCP_ProjectId must be provided"))
+		}
+		if opts.CredentialsFile != "" && len(opts.Credentials.GCP_ServiceAccountJSON) > 0 {
+			errs = append(errs, fmt.Errorf("Options.CredentialsFile and Credentials.GCP_ServiceAccountJSON can't be combined"))
+		}
+	case TypeAzure:
+		if opts.Azure_StorageAccount == "" && opts.Credentials.Azure_ConnectionString == "" {
+			errs = append(errs, fmt.Errorf("Options.Azure_StorageAccount must be provided"))
+		}
+	case TypeLocal:
+		if opts.Local_RootDir == "" {
+			errs = append(errs, fmt.Errorf("Options.Local_RootDir must be provided"))
+		}
+	}
+
+	errs = opts.Credentials.validate(opts.StorageType, errs)
+
+	if opts.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("Options.Timeout must not be negative"))
+	}
+	if opts.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("Options.MaxAttempts must not be negative"))
+	}
+	if p := opts.RetryPolicy; p != nil {
+		if p.MaxAttempts < 0 || p.BaseBackoff < 0 || p.MaxBackoff < 0 {
+			errs = append(errs, fmt.Errorf("Options.RetryPolicy attempts and backoffs must not be negative"))
+		}
+		if p.MaxBackoff > 0 && p.BaseBackoff > p.MaxBackoff {
+			errs = append(errs, fmt.Errorf("Options.RetryPolicy.BaseBackoff must not exceed MaxBackoff"))
+		}
+		if p.Jitter < 0 || p.Jitter > 1 {
+			errs = append(errs, fmt.Errorf("Options.RetryPolicy.Jitter must be between 0 and 1"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
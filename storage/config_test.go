@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+default_profile: aws
+profiles:
+  aws:
+    provider: s3
+    location: eu-west-1
+    max_attempts: 5
+    retry:
+      base_backoff: 200ms
+      jitter: 0.5
+  gcp:
+    provider: GCP
+    project: my-project
+    credentials_file: /path/to/sa-json.json
+    timeout: 30s
+  minio:
+    provider: S3
+    endpoint: http://localhost:9000
+    use_path_style: true
+  broken:
+    provider: GCP
+    timeout: soon
+`
+
+func writeConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestLoadOptions(t *testing.T) {
+	t.Setenv("CLOUDCONNECT_PROFILE", "")
+	fileName := writeConfig(t, "config.yaml", testConfig)
+
+	opts, err := LoadOptions(fileName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Options{StorageType: TypeS3, Location: "eu-west-1", MaxAttempts: 5,
+		RetryPolicy: &RetryPolicy{BaseBackoff: 200 * time.Millisecond, Jitter: 0.5},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("default profile = %+v, want %+v", opts, want)
+	}
+
+	opts, err = LoadOptions(fileName, "gcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Options{StorageType: TypeGCP, GCP_ProjectId: "my-project", CredentialsFile: "/path/to/sa-json.json", Timeout: 30 * time.Second}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("gcp profile = %+v, want %+v", opts, want)
+	}
+
+	opts, err = LoadOptions(fileName, "minio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Options{StorageType: TypeS3, Endpoint: "http://localhost:9000", UsePathStyle: true}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("minio profile = %+v, want %+v", opts, want)
+	}
+
+	if _, err := LoadOptions(fileName, "missing"); err == nil || !strings.Contains(err.Error(), "aws, broken, gcp, minio") {
+		t.Errorf("unknown profile error = %v, want list of profiles", err)
+	}
+}
+
+func TestLoadOptionsJSON(t *testing.T) {
+	fileName := writeConfig(t, "config.json", `{"profiles": {"local": {"provider": "LOCAL", "root_dir": "/tmp/buckets"}}}`)
+
+	opts, err := LoadOptions(fileName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.StorageType != TypeLocal || opts.Local_RootDir != "/tmp/buckets" {
+		t.Errorf("only profile = %+v, want local storage in /tmp/buckets", opts)
+	}
+}
+
+func TestLoadOptionsEnv(t *testing.T) {
+	fileName := writeConfig(t, "config.yml", testConfig)
+	t.Setenv("CLOUDCONNECT_CONFIG", fileName)
+	t.Setenv("CLOUDCONNECT_PROFILE", "gcp")
+	t.Setenv("CLOUDCONNECT_LOCATION", "EU")
+	t.Setenv("CLOUDCONNECT_MAX_ATTEMPTS", "2")
+	t.Setenv("CLOUDCONNECT_INSECURE_SKIP_VERIFY", "true")
+
+	opts, err := OptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.StorageType != TypeGCP || opts.Location != "EU" || opts.MaxAttempts != 2 || !opts.InsecureSkipVerify || opts.Timeout != 30*time.Second {
+		t.Errorf("options = %+v, want gcp profile with environment overrides", opts)
+	}
+}
+
+func TestLoadOptionsValidation(t *testing.T) {
+	t.Setenv("CLOUDCONNECT_MAX_ATTEMPTS", "many")
+	t.Setenv("CLOUDCONNECT_USE_PATH_STYLE", "maybe")
+	t.Setenv("CLOUDCONNECT_RETRY_MAX_BACKOFF", "1 minute")
+	fileName := writeConfig(t, "config.yaml", testConfig)
+
+	_, err := LoadOptions(fileName, "broken")
+	if err == nil {
+		t.Fatal("broken profile should fail")
+	}
+	for _, problem := range []string{"MAX_ATTEMPTS", "USE_PATH_STYLE", "retry.max_backoff", "timeout 'soon'", "GCP_ProjectId"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("error '%v' should report %s", err, problem)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := Options{StorageType: TypeAzure, Timeout: -1, MaxAttempts: -1,
+		RetryPolicy: &RetryPolicy{BaseBackoff: time.Minute, MaxBackoff: time.Second, Jitter: 2},
+	}.Validate()
+	if err == nil {
+		t.Fatal("Validate() should fail")
+	}
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != 5 {
+		t.Errorf("Validate() = %v, want 5 problems", lines)
+	}
+
+	if err := (Options{StorageType: TypeMemory}).Validate(); err != nil {
+		t.Errorf("Validate() = %v for memory storage", err)
+	}
+}
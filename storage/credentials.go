@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// Credentials replace the provider's default credential chain (see Setup in the README), so one process
+// can use several accounts. Only the fields of the Options.StorageType provider are used.
+type Credentials struct {
+	// AWS static access keys, with an optional session token
+	AWS_AccessKeyId     string
+	AWS_SecretAccessKey string
+	AWS_SessionToken    string
+	// AWS_Profile selects a named profile from ~/.aws/config and ~/.aws/credentials
+	AWS_Profile string
+	// AWS_RoleARN is a role to assume using the other (or default) credentials
+	AWS_RoleARN string
+
+	// GCP_ServiceAccountJSON is the content of a service account key file
+	GCP_ServiceAccountJSON []byte
+
+	// Azure_SharedKey is the storage account key, also used to sign presigned URLs
+	Azure_SharedKey string
+	// Azure_SASToken is a shared access signature for the storage account
+	Azure_SASToken string
+	// Azure_ConnectionString replaces Options.Azure_StorageAccount and the other credentials
+	Azure_ConnectionString string
+	// Azure app registration (service principal) client secret credentials
+	Azure_TenantId     string
+	Azure_ClientId     string
+	Azure_ClientSecret string
+}
+
+// String lists the credentials that are set, without their values, so Options can be logged safely.
+func (c Credentials) String() string {
+	set := []string{}
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).IsZero() {
+			set = append(set, v.Type().Field(i).Name+":REDACTED")
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(set, " "))
+}
+
+// LogValue logs credentials redacted, like String.
+func (c Credentials) LogValue() slog.Value {
+	return slog.StringValue(c.String())
+}
+
+// validate checks the credentials used by storageType, appending problems to errs
+func (c Credentials) validate(storageType StorageType, errs []error) []error {
+	switch storageType {
+	case TypeS3:
+		if (c.AWS_AccessKeyId == "") != (c.AWS_SecretAccessKey == "") {
+			errs = append(errs, fmt.Errorf("Credentials.AWS_AccessKeyId and Credentials.AWS_SecretAccessKey must be provided together"))
+		}
+		if c.AWS_SessionToken != "" && c.AWS_AccessKeyId == "" {
+			errs = append(errs, fmt.Errorf("Credentials.AWS_SessionToken must be provided with Credentials.AWS_AccessKeyId"))
+		}
+		if c.AWS_AccessKeyId != "" && c.AWS_Profile != "" {
+			errs = append(errs, fmt.Errorf("Credentials.AWS_AccessKeyId and Credentials.AWS_Profile can't be combined"))
+		}
+	case TypeAzure:
+		kinds := 0
+		for _, credential := range []string{c.Azure_SharedKey, c.Azure_SASToken, c.Azure_ConnectionString, c.Azure_ClientSecret} {
+			if credential != "" {
+				kinds++
+			}
+		}
+		if kinds > 1 {
+			errs = append(errs, fmt.Errorf("only one of Credentials.Azure_SharedKey, Azure_SASToken, Azure_ConnectionString and Azure_ClientSecret can be provided"))
+		}
+		if c.Azure_ClientSecret != "" && (c.Azure_TenantId == "" || c.Azure_ClientId == "") {
+			errs = append(errs, fmt.Errorf("Credentials.Azure_TenantId and Credentials.Azure_ClientId must be provided with Credentials.Azure_ClientSecret"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCredentialsString(t *testing.T) {
+	opts := Options{
+		StorageType: TypeS3,
+		Credentials: Credentials{AWS_AccessKeyId: "AKIAEXAMPLE", AWS_SecretAccessKey: "secret"},
+	}
+
+	printed := fmt.Sprintf("%+v", opts)
+	if strings.Contains(printed, "AKIAEXAMPLE") || strings.Contains(printed, "secret") {
+		t.Errorf("printed options '%s' contain credentials", printed)
+	}
+	if !strings.Contains(printed, "AWS_SecretAccessKey:REDACTED") {
+		t.Errorf("printed options '%s' should name the credentials set", printed)
+	}
+}
+
+func TestCredentialsValidate(t *testing.T) {
+	for _, test := range []struct {
+		opts     Options
+		problems int
+	}{
+		{Options{StorageType: TypeS3, Credentials: Credentials{AWS_AccessKeyId: "id", AWS_SecretAccessKey: "secret"}}, 0},
+		{Options{StorageType: TypeS3, Credentials: Credentials{AWS_AccessKeyId: "id", AWS_Profile: "prod"}}, 2},
+		{Options{StorageType: TypeS3, Credentials: Credentials{AWS_SessionToken: "token"}}, 1},
+		{Options{StorageType: TypeGCP, GCP_ProjectId: "p", CredentialsFile: "sa.json", Credentials: Credentials{GCP_ServiceAccountJSON: []byte("{}")}}, 1},
+		{Options{StorageType: TypeAzure, Credentials: Credentials{Azure_ConnectionString: "AccountName=a;AccountKey=k"}}, 0},
+		{Options{StorageType: TypeAzure, Azure_StorageAccount: "a", Credentials: Credentials{Azure_SharedKey: "k", Azure_SASToken: "sv=..."}}, 1},
+		{Options{StorageType: TypeAzure, Azure_StorageAccount: "a", Credentials: Credentials{Azure_ClientSecret: "secret"}}, 1},
+		// credentials of other providers are ignored
+		{Options{StorageType: TypeMemory, Credentials: Credentials{AWS_AccessKeyId: "id"}}, 0},
+	} {
+		err := test.opts.Validate()
+		problems := 0
+		if err != nil {
+			problems = len(strings.Split(err.Error(), "\n"))
+		}
+		if problems != test.problems {
+			t.Errorf("Validate(%+v) = %v, want %d problems", test.opts, err, test.problems)
+		}
+	}
+}
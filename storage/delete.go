@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// deleteBatchSize is the number of listed keys collected before they are deleted in one DeleteObjects call
+const deleteBatchSize = 1000
+
+type DeleteMatchingOptions struct {
+	// DryRun lists the keys that would be deleted, without deleting them.
+	DryRun bool
+}
+
+type DeleteMatchingOption func(*DeleteMatchingOptions)
+
+func WithDryRun() DeleteMatchingOption {
+	return func(o *DeleteMatchingOptions) {
+		o.DryRun = true
+	}
+}
+
+// DeleteByPrefix deletes all objects with keys starting with prefix.
+// The bucket is listed and deleted in batches, so the keys are never all held in memory.
+func DeleteByPrefix(cloudStorage Storage, bucketName string, prefix string, opts ...DeleteMatchingOption) (*DeleteReport, error) {
+	return deleteWalk(cloudStorage, bucketName, prefix, func(string) bool { return true }, opts)
+}
+
+// DeleteMatching deletes all objects with keys matching the glob pattern, as per path.Match
+// ('*' does not match '/'). The literal part of the pattern before the first wildcard is used
+// as listing prefix.
+func DeleteMatching(cloudStorage Storage, bucketName string, pattern string, opts ...DeleteMatchingOption) (*DeleteReport, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+
+	return deleteWalk(cloudStorage, bucketName, prefix, func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	}, opts)
+}
+
+func deleteWalk(cloudStorage Storage, bucketName string, prefix string, match func(key string) bool, opts []DeleteMatchingOption) (*DeleteReport, error) {
+	o := DeleteMatchingOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	report := &DeleteReport{DryRun: o.DryRun}
+	batch := []string{}
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if o.DryRun {
+			for _, key := range batch {
+				report.Results = append(report.Results, DeleteResult{Key: key})
+			}
+		} else {
+			// per-key failures are in the batch report and returned by report.Err()
+			batchReport, err := cloudStorage.DeleteObjects(bucketName, batch)
+			if batchReport == nil {
+				batchReport = common.NewDeleteReport(batch)
+				for i := range batchReport.Results {
+					batchReport.Results[i].Err = err
+				}
+			}
+			report.Results = append(report.Results, batchReport.Results...)
+		}
+		batch = []string{}
+	}
+
+	// deleting while listing is safe, listings continue after the last key returned
+	err := cloudStorage.WalkBucketContent(bucketName, func(object ObjectInfo) error {
+		if match(object.Key) {
+			batch = append(batch, object.Key)
+		}
+		if len(batch) >= deleteBatchSize {
+			flush()
+		}
+		return nil
+	}, WithPrefix(prefix))
+	if err != nil {
+		return report, err
+	}
+	flush()
+
+	return report, report.Err()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// listingStorage implements the listing and batch delete part of Storage over a set of keys
+type listingStorage struct {
+	Storage
+	keys         map[string]bool
+	deleteCalls  int
+	walkedPrefix string
+}
+
+func newListingStorage(keys ...string) *listingStorage {
+	s := &listingStorage{keys: map[string]bool{}}
+	for _, key := range keys {
+		s.keys[key] = true
+	}
+	return s
+}
+
+func (s *listingStorage) WalkBucketContent(bucketName string, fn WalkFunc, opts ...ListOption) error {
+	o := common.NewListOptions(opts...)
+	s.walkedPrefix = o.Prefix
+
+	keys := []string{}
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, o.Prefix) {
+			continue
+		}
+		if err := fn(ObjectInfo{Key: key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *listingStorage) DeleteObjects(bucketName string, objectKeys []string) (*DeleteReport, error) {
+	s.deleteCalls++
+	report := common.NewDeleteReport(objectKeys)
+	for i, key := range objectKeys {
+		if strings.Contains(key, "locked") {
+			report.Results[i].Err = fmt.Errorf("access denied")
+			continue
+		}
+		delete(s.keys, key)
+	}
+	return report, report.Err()
+}
+
+func TestDeleteByPrefix(t *testing.T) {
+	s := newListingStorage("run-1/a.log", "run-1/b/c.log", "run-2/a.log")
+
+	report, err := DeleteByPrefix(s, "bucket", "run-1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted := report.Deleted(); len(deleted) != 2 {
+		t.Errorf("Deleted() = %v, want the 2 run-1 keys", deleted)
+	}
+	if len(s.keys) != 1 || !s.keys["run-2/a.log"] {
+		t.Errorf("remaining keys = %v, want only run-2/a.log", s.keys)
+	}
+}
+
+func TestDeleteByPrefixBatches(t *testing.T) {
+	keys := []string{}
+	for i := 0; i < deleteBatchSize*2+1; i++ {
+		keys = append(keys, fmt.Sprintf("key-%05d", i))
+	}
+	s := newListingStorage(keys...)
+
+	report, err := DeleteByPrefix(s, "bucket", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != len(keys) || s.deleteCalls != 3 {
+		t.Errorf("%d results in %d DeleteObjects calls, want %d in 3", len(report.Results), s.deleteCalls, len(keys))
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	s := newListingStorage("logs/a.log", "logs/b.txt", "logs/sub/c.log", "other/d.log")
+
+	report, err := DeleteMatching(s, "bucket", "logs/*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.walkedPrefix != "logs/" {
+		t.Errorf("listing prefix = '%s', want 'logs/'", s.walkedPrefix)
+	}
+	if deleted := report.Deleted(); len(deleted) != 1 || deleted[0] != "logs/a.log" {
+		t.Errorf("Deleted() = %v, want [logs/a.log]", deleted)
+	}
+}
+
+func TestDeleteMatchingDryRun(t *testing.T) {
+	s := newListingStorage("logs/a.log", "logs/b.log")
+
+	report, err := DeleteMatching(s, "bucket", "logs/*", WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Results) != 2 {
+		t.Errorf("report = %+v, want dry run listing 2 keys", report)
+	}
+	if len(s.keys) != 2 || s.deleteCalls != 0 {
+		t.Errorf("dry run deleted objects")
+	}
+}
+
+func TestDeleteMatchingFailures(t *testing.T) {
+	s := newListingStorage("a", "locked-b", "c")
+
+	report, err := DeleteMatching(s, "bucket", "*")
+	if err == nil {
+		t.Fatal("expected error for locked key")
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0].Key != "locked-b" {
+		t.Errorf("Failed() = %v, want [locked-b]", failed)
+	}
+	if len(report.Deleted()) != 2 {
+		t.Errorf("Deleted() = %v, want the other 2 keys", report.Deleted())
+	}
+}
+
+func TestDeleteMatchingBadPattern(t *testing.T) {
+	_, err := DeleteMatching(newListingStorage(), "bucket", "[")
+	if err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
@@ -0,0 +1,63 @@
+// Package awskms wraps data keys of the encryption package with an AWS KMS key.
+package awskms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/pbreedt/cloud-connect/storage/encryption"
+)
+
+// API is the part of *kms.Client used to wrap keys.
+type API interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KeyProvider wraps data keys with a symmetric KMS key.
+type KeyProvider struct {
+	client API
+	keyId  string
+}
+
+var _ encryption.KeyProvider = &KeyProvider{}
+
+// New wraps data keys with KMS key keyId (key id, key ARN or alias) using client.
+func New(client API, keyId string) *KeyProvider {
+	return &KeyProvider{client: client, keyId: keyId}
+}
+
+// NewFromDefaultConfig wraps data keys with KMS key keyId, using the default AWS credentials and region.
+func NewFromDefaultConfig(ctx context.Context, keyId string) (*KeyProvider, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(kms.NewFromConfig(cfg), keyId), nil
+}
+
+// WrapKey returns the key ARN as key id, so objects can be decrypted after the alias moves to another key.
+func (p *KeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	out, err := p.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(p.keyId),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("WrapKey(%w)", err)
+	}
+	return out.CiphertextBlob, aws.ToString(out.KeyId), nil
+}
+
+func (p *KeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyId string) ([]byte, error) {
+	out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(keyId),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("UnwrapKey(%w)", err)
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,59 @@
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+const keyARN = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd"
+
+// fakeKMS "encrypts" by reversing the plaintext
+type fakeKMS struct{}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func (fakeKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	if aws.ToString(params.KeyId) != "alias/backups" {
+		return nil, fmt.Errorf("unexpected key '%s'", aws.ToString(params.KeyId))
+	}
+	return &kms.EncryptOutput{CiphertextBlob: reverse(params.Plaintext), KeyId: aws.String(keyARN)}, nil
+}
+
+func (fakeKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if aws.ToString(params.KeyId) != keyARN {
+		return nil, fmt.Errorf("unexpected key '%s'", aws.ToString(params.KeyId))
+	}
+	return &kms.DecryptOutput{Plaintext: reverse(params.CiphertextBlob)}, nil
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	p := New(fakeKMS{}, "alias/backups")
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, keyId, err := p.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyId != keyARN {
+		t.Errorf("key id = '%s', want the key ARN", keyId)
+	}
+
+	unwrapped, err := p.UnwrapKey(context.Background(), wrapped, keyId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("unwrapped key differs")
+	}
+}
@@ -0,0 +1,76 @@
+// Package azurekeyvault wraps data keys of the encryption package with an Azure Key Vault RSA key.
+package azurekeyvault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/pbreedt/cloud-connect/storage/encryption"
+)
+
+// API is the part of *azkeys.Client used to wrap keys.
+type API interface {
+	WrapKey(ctx context.Context, name string, version string, parameters azkeys.KeyOperationParameters, options *azkeys.WrapKeyOptions) (azkeys.WrapKeyResponse, error)
+	UnwrapKey(ctx context.Context, name string, version string, parameters azkeys.KeyOperationParameters, options *azkeys.UnwrapKeyOptions) (azkeys.UnwrapKeyResponse, error)
+}
+
+// KeyProvider wraps data keys with RSA-OAEP-256 using a Key Vault key.
+type KeyProvider struct {
+	client  API
+	keyName string
+}
+
+var _ encryption.KeyProvider = &KeyProvider{}
+
+// New wraps data keys with the latest version of key keyName using client.
+func New(client API, keyName string) *KeyProvider {
+	return &KeyProvider{client: client, keyName: keyName}
+}
+
+// NewFromDefaultCredential wraps data keys with key keyName of the vault at vaultURL (https://<vault>.vault.azure.net/),
+// using the default Azure credential.
+func NewFromDefaultCredential(vaultURL string, keyName string) (*KeyProvider, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azkeys.NewClient(vaultURL, credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, keyName), nil
+}
+
+func operation(value []byte) azkeys.KeyOperationParameters {
+	algorithm := azkeys.EncryptionAlgorithmRSAOAEP256
+	return azkeys.KeyOperationParameters{Algorithm: &algorithm, Value: value}
+}
+
+// WrapKey returns the key id of the key version that wrapped, https://<vault>.vault.azure.net/keys/<name>/<version>.
+func (p *KeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	resp, err := p.client.WrapKey(ctx, p.keyName, "", operation(dataKey), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("WrapKey(%w)", err)
+	}
+	keyId := ""
+	if resp.KID != nil {
+		keyId = string(*resp.KID)
+	}
+	return resp.Result, keyId, nil
+}
+
+// UnwrapKey unwraps with the key version of keyId, as key versions are rotated.
+func (p *KeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyId string) ([]byte, error) {
+	name, version := p.keyName, ""
+	if keyId != "" {
+		id := azkeys.ID(keyId)
+		name, version = id.Name(), id.Version()
+	}
+	resp, err := p.client.UnwrapKey(ctx, name, version, operation(wrapped), nil)
+	if err != nil {
+		return nil, fmt.Errorf("UnwrapKey(%w)", err)
+	}
+	return resp.Result, nil
+}
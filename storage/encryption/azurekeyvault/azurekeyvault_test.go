@@ -0,0 +1,59 @@
+package azurekeyvault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+)
+
+const keyId = "https://vault.vault.azure.net/keys/backups/v2"
+
+// fakeVault "wraps" by reversing the key
+type fakeVault struct{}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func (fakeVault) WrapKey(ctx context.Context, name string, version string, parameters azkeys.KeyOperationParameters, options *azkeys.WrapKeyOptions) (azkeys.WrapKeyResponse, error) {
+	if name != "backups" || version != "" || *parameters.Algorithm != azkeys.EncryptionAlgorithmRSAOAEP256 {
+		return azkeys.WrapKeyResponse{}, fmt.Errorf("unexpected wrap with %s/%s", name, version)
+	}
+	kid := azkeys.ID(keyId)
+	return azkeys.WrapKeyResponse{KeyOperationResult: azkeys.KeyOperationResult{KID: &kid, Result: reverse(parameters.Value)}}, nil
+}
+
+func (fakeVault) UnwrapKey(ctx context.Context, name string, version string, parameters azkeys.KeyOperationParameters, options *azkeys.UnwrapKeyOptions) (azkeys.UnwrapKeyResponse, error) {
+	if name != "backups" || version != "v2" {
+		return azkeys.UnwrapKeyResponse{}, fmt.Errorf("unexpected unwrap with %s/%s", name, version)
+	}
+	return azkeys.UnwrapKeyResponse{KeyOperationResult: azkeys.KeyOperationResult{Result: reverse(parameters.Value)}}, nil
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	p := New(fakeVault{}, "backups")
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, id, err := p.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != keyId {
+		t.Errorf("key id = '%s', want '%s'", id, keyId)
+	}
+
+	unwrapped, err := p.UnwrapKey(context.Background(), wrapped, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("unwrapped key differs")
+	}
+}
@@ -0,0 +1,264 @@
+// Package encryption encrypts objects on the client side, before they leave the process, with any provider.
+//
+//	keys, err := encryption.LoadLocalKeyProvider("/etc/cloudconnect/key") // or awskms, gcpkms, azurekeyvault
+//	encrypted := encryption.New(cloudStorage, keys)
+//
+// Every object is encrypted with its own random AES-256 data key, in chunks of AES-256-GCM so objects of any size
+// are streamed (see stream.go). The data key is wrapped by the KeyProvider and stored in the object metadata
+// (cc_enc_* keys), and unwrapped to decrypt on read.
+//
+// Object sizes listed by ListBucketContent and WalkBucketContent are those of the encrypted content;
+// StatObject returns the plaintext size. Presigned GET and PUT URLs are not supported, as they would bypass
+// encryption.
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// Algorithm identifies the encryption format in the object metadata.
+const Algorithm = "AES256GCM-CHUNKED-V1"
+
+// Object metadata keys (with underscores, as Azure metadata names must be identifiers)
+const (
+	MetadataAlgorithm  = "cc_enc_alg"
+	MetadataWrappedKey = "cc_enc_key"
+	MetadataKeyId      = "cc_enc_kid"
+	MetadataChunkSize  = "cc_enc_chunk"
+)
+
+// DefaultChunkSize is the plaintext size of the encrypted chunks.
+const DefaultChunkSize = 64 << 10
+
+// ErrNotEncrypted is returned when reading an object without encryption metadata, unless WithAllowUnencrypted is set.
+var ErrNotEncrypted = errors.New("object is not encrypted")
+
+const dataKeySize = 32
+
+type options struct {
+	chunkSize        int
+	allowUnencrypted bool
+}
+
+type Option func(*options)
+
+// WithChunkSize sets the plaintext size of the encrypted chunks, by default DefaultChunkSize.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = min(max(size, 1), maxChunk)
+	}
+}
+
+// WithAllowUnencrypted reads objects without encryption metadata as they are, e.g. while migrating to encryption.
+func WithAllowUnencrypted() Option {
+	return func(o *options) {
+		o.allowUnencrypted = true
+	}
+}
+
+// Encrypted is a storage.Storage encrypting objects put in, and decrypting objects read from, the storage it wraps.
+type Encrypted struct {
+	storage.Wrapper
+	keys    KeyProvider
+	options options
+}
+
+var _ storage.Storage = &Encrypted{}
+
+// New encrypts the objects of next with data keys wrapped by keys.
+func New(next storage.Storage, keys KeyProvider, opts ...Option) *Encrypted {
+	o := options{chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Encrypted{Wrapper: storage.Wrapper{Next: next}, keys: keys, options: o}
+}
+
+// Middleware returns a storage.Middleware encrypting the objects of the wrapped storage.
+func Middleware(keys KeyProvider, opts ...Option) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return New(next, keys, opts...)
+	}
+}
+
+func (e *Encrypted) StoreObject(bucketName string, objectKey string, fileName string, opts ...storage.PutOption) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return e.PutObject(bucketName, objectKey, f, opts...)
+}
+
+// PutObject encrypts the content of reader with a new data key.
+func (e *Encrypted) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+	wrapped, keyId, err := e.keys.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		return fmt.Errorf("PutObject(wrap key: %w)", err)
+	}
+
+	// report the progress of the plaintext, rather than of the (larger) encrypted content
+	if progress := storage.NewPutOptions(opts...).Progress; progress != nil {
+		reader = storage.NewProgressReader(reader, -1, progress)
+		opts = append(opts, storage.WithUploadProgress(nil))
+	}
+
+	encrypting, err := newEncryptingReader(reader, dataKey, e.options.chunkSize)
+	if err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+
+	opts = append(opts, storage.WithMetadata(map[string]string{
+		MetadataAlgorithm:  Algorithm,
+		MetadataWrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		MetadataKeyId:      keyId,
+		MetadataChunkSize:  strconv.Itoa(e.options.chunkSize),
+	}))
+	return e.Next.PutObject(bucketName, objectKey, encrypting, opts...)
+}
+
+func (e *Encrypted) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...storage.GetOption) error {
+	rc, err := e.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(rc, fileName)
+}
+
+// GetObject unwraps the data key of the object from the metadata of the read itself, then decrypts its content
+// while read. Ranges (storage.WithRange) are decrypted from the start of the object; with WithAllowUnencrypted,
+// the object is stat'ed first to read ranges of unencrypted objects as they are.
+func (e *Encrypted) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	o := storage.NewGetOptions(opts...)
+	encrypted := true
+	if o.Ranged() && e.options.allowUnencrypted {
+		info, err := e.Next.StatObject(bucketName, objectKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		encrypted = info.Metadata[MetadataAlgorithm] != ""
+	}
+
+	rc, read, err := e.read(bucketName, objectKey, encrypted && o.Ranged(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted && read.Metadata[MetadataAlgorithm] != "" {
+		// replaced by an encrypted object since stat'ed
+		rc.Close()
+		encrypted = true
+		rc, read, err = e.read(bucketName, objectKey, true, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	content := rc
+	size := read.Size
+	dataKey, err := e.dataKey(read)
+	switch {
+	case err == ErrNotEncrypted && e.options.allowUnencrypted:
+	case err != nil:
+		rc.Close()
+		return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, err)
+	default:
+		content, err = newDecryptingReader(rc, dataKey)
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, err)
+		}
+		chunkSize, _ := strconv.Atoi(read.Metadata[MetadataChunkSize])
+		size = plaintextSize(read.Size, chunkSize)
+	}
+	if o.Info != nil {
+		*o.Info = plaintextInfo(read)
+	}
+	if encrypted && o.Ranged() {
+		content = storage.NewRangeReadCloser(content, o.Offset, o.Length)
+	}
+	if o.Progress != nil {
+		return storage.NewProgressReadCloser(content, o.RangeSize(size), o.Progress), nil
+	}
+	return content, nil
+}
+
+// read reads the object from the start if fromStart, rather than the range of opts, with the info of the read.
+// The progress of opts is reported by GetObject, for the plaintext.
+func (e *Encrypted) read(bucketName string, objectKey string, fromStart bool, opts []storage.GetOption) (io.ReadCloser, storage.ObjectInfo, error) {
+	read := storage.ObjectInfo{}
+	opts = append(opts, storage.WithObjectInfo(&read), storage.WithDownloadProgress(nil))
+	if fromStart {
+		opts = append(opts, storage.WithRange(0, 0))
+	}
+	rc, err := e.Next.GetObject(bucketName, objectKey, opts...)
+	return rc, read, err
+}
+
+// StatObject returns the plaintext size, without the encryption metadata.
+func (e *Encrypted) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := e.Next.StatObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return info, err
+	}
+	return plaintextInfo(info), nil
+}
+
+// plaintextInfo returns info of an encrypted object with the plaintext size and without the encryption metadata
+func plaintextInfo(info storage.ObjectInfo) storage.ObjectInfo {
+	if info.Metadata[MetadataAlgorithm] == "" {
+		return info
+	}
+
+	chunkSize, _ := strconv.Atoi(info.Metadata[MetadataChunkSize])
+	if size := plaintextSize(info.Size, chunkSize); size >= 0 {
+		info.Size = size
+	}
+	metadata := map[string]string{}
+	for k, v := range info.Metadata {
+		if !strings.HasPrefix(k, "cc_enc_") {
+			metadata[k] = v
+		}
+	}
+	info.Metadata = metadata
+	return info
+}
+
+// PresignURL only supports DELETE, as GET and PUT URLs would bypass encryption.
+func (e *Encrypted) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	if !strings.EqualFold(method, "DELETE") {
+		return "", fmt.Errorf("PresignURL(%s of encrypted objects: %w)", method, storage.ErrNotSupported)
+	}
+	return e.Next.PresignURL(bucketName, objectKey, method, expiry)
+}
+
+// dataKey unwraps the data key of an object from its metadata
+func (e *Encrypted) dataKey(info storage.ObjectInfo) ([]byte, error) {
+	algorithm := info.Metadata[MetadataAlgorithm]
+	if algorithm == "" {
+		return nil, ErrNotEncrypted
+	}
+	if algorithm != Algorithm {
+		return nil, fmt.Errorf("unknown encryption algorithm '%s'", algorithm)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(info.Metadata[MetadataWrappedKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	return e.keys.UnwrapKey(context.Background(), wrapped, info.Metadata[MetadataKeyId])
+}
@@ -0,0 +1,255 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+func newKeys(t *testing.T) *LocalKeyProvider {
+	t.Helper()
+	keys, err := NewLocalKeyProvider(newKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New(memory.NewMemoryClient(), newKeys(t), WithChunkSize(1000))
+	})
+}
+
+// encrypt returns the encryption of plaintext in chunks of chunkSize
+func encrypt(t *testing.T, dataKey []byte, plaintext []byte, chunkSize int) []byte {
+	t.Helper()
+	r, err := newEncryptingReader(bytes.NewReader(plaintext), dataKey, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ciphertext
+}
+
+func decrypt(dataKey []byte, ciphertext []byte) ([]byte, error) {
+	r, err := newDecryptingReader(io.NopCloser(bytes.NewReader(ciphertext)), dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	dataKey := newKey(t)
+	for _, size := range []int{0, 1, 99, 100, 101, 250, 1000} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+
+		ciphertext := encrypt(t, dataKey, plaintext, 100)
+		if got := plaintextSize(int64(len(ciphertext)), 100); got != int64(size) {
+			t.Errorf("plaintextSize of %d bytes encrypted = %d", size, got)
+		}
+		decrypted, err := decrypt(dataKey, ciphertext)
+		if err != nil {
+			t.Errorf("decrypt %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("decrypted %d bytes differ from %d bytes encrypted", len(decrypted), size)
+		}
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	dataKey := newKey(t)
+	plaintext := bytes.Repeat([]byte("0123456789"), 30)
+	ciphertext := encrypt(t, dataKey, plaintext, 100)
+	sealed := 100 + tagSize
+
+	flipped := bytes.Clone(ciphertext)
+	flipped[headerSize+5] ^= 1
+
+	swapped := bytes.Clone(ciphertext)
+	copy(swapped[headerSize:], ciphertext[headerSize+sealed:headerSize+2*sealed])
+	copy(swapped[headerSize+sealed:], ciphertext[headerSize:headerSize+sealed])
+
+	tests := map[string][]byte{
+		"flipped bit":        flipped,
+		"swapped chunks":     swapped,
+		"truncated at chunk": ciphertext[:headerSize+2*sealed],
+		"truncated in chunk": ciphertext[:headerSize+sealed+50],
+		"truncated header":   ciphertext[:headerSize-1],
+		"last chunk dropped": ciphertext[:headerSize+3*sealed],
+		"other key":          encrypt(t, newKey(t), plaintext, 100),
+		"bad magic":          append([]byte("XXXX"), ciphertext[4:]...),
+	}
+	for name, tampered := range tests {
+		if _, err := decrypt(dataKey, tampered); err == nil {
+			t.Errorf("%s: decrypted without error", name)
+		}
+	}
+}
+
+func TestEncryptedAtRest(t *testing.T) {
+	mem := memory.NewMemoryClient()
+	mem.CreateBucket("bucket")
+	s := New(mem, newKeys(t))
+
+	err := s.PutObject("bucket", "key", strings.NewReader("secret data"), storage.WithMetadata(map[string]string{"owner": "me"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := mem.GetObject("bucket", "key")
+	content, _ := io.ReadAll(raw)
+	if bytes.Contains(content, []byte("secret")) {
+		t.Error("plaintext stored")
+	}
+	rawInfo, _ := mem.StatObject("bucket", "key")
+	if rawInfo.Metadata[MetadataAlgorithm] != Algorithm || rawInfo.Metadata[MetadataWrappedKey] == "" ||
+		!strings.HasPrefix(rawInfo.Metadata[MetadataKeyId], "local:") {
+		t.Errorf("stored metadata = %v, want the wrapped key", rawInfo.Metadata)
+	}
+
+	info, err := s.StatObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 11 || len(info.Metadata) != 1 || info.Metadata["owner"] != "me" {
+		t.Errorf("StatObject = %+v, want plaintext size 11 and only the user metadata", info)
+	}
+
+	if _, err := s.PresignURL("bucket", "key", "GET", time.Hour); !errors.Is(err, storage.ErrNotSupported) {
+		t.Errorf("PresignURL(GET) = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestUnencrypted(t *testing.T) {
+	mem := memory.NewMemoryClient()
+	mem.CreateBucket("bucket")
+	mem.PutObject("bucket", "plain", strings.NewReader("plain data"))
+
+	if _, err := New(mem, newKeys(t)).GetObject("bucket", "plain"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("GetObject(plain) = %v, want ErrNotEncrypted", err)
+	}
+
+	rc, err := New(mem, newKeys(t), WithAllowUnencrypted()).GetObject("bucket", "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if content, _ := io.ReadAll(rc); string(content) != "plain data" {
+		t.Errorf("GetObject(plain) = '%s', want 'plain data'", content)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldFile, newFile := filepath.Join(dir, "old.key"), filepath.Join(dir, "new.key")
+	if err := GenerateKeyFile(oldFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateKeyFile(newFile); err != nil {
+		t.Fatal(err)
+	}
+
+	mem := memory.NewMemoryClient()
+	mem.CreateBucket("bucket")
+	oldKeys, err := LoadLocalKeyProvider(oldFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	New(mem, oldKeys).PutObject("bucket", "key", strings.NewReader("data"))
+
+	rotated, err := LoadLocalKeyProvider(newFile, oldFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := New(mem, rotated).GetObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if content, _ := io.ReadAll(rc); string(content) != "data" {
+		t.Errorf("content = '%s' after rotation, want 'data'", content)
+	}
+
+	newKeys, _ := LoadLocalKeyProvider(newFile)
+	if _, err := New(mem, newKeys).GetObject("bucket", "key"); err == nil {
+		t.Error("GetObject without the old key succeeded")
+	}
+}
+
+func TestLocalKeyProviderErrors(t *testing.T) {
+	if _, err := NewLocalKeyProvider(make([]byte, 16)); err == nil {
+		t.Error("NewLocalKeyProvider accepted a 16 byte key")
+	}
+	if _, err := LoadLocalKeyProvider(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadLocalKeyProvider accepted a missing file")
+	}
+}
+
+// replacedAfterStat replaces the object with content after it is stat'ed, as a concurrent writer would
+type replacedAfterStat struct {
+	storage.Wrapper
+	replacement storage.Storage
+	content     string
+}
+
+func (r replacedAfterStat) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := r.Next.StatObject(bucketName, objectKey, opts...)
+	if err == nil {
+		err = r.replacement.PutObject(bucketName, objectKey, strings.NewReader(r.content))
+	}
+	return info, err
+}
+
+func TestReplacedObject(t *testing.T) {
+	keys := newKeys(t)
+	mem := memory.NewMemoryClient()
+	mem.CreateBucket("bucket")
+	encrypted := New(mem, keys)
+
+	for _, replaceWith := range []storage.Storage{encrypted, mem} {
+		replaced := replacedAfterStat{storage.Wrapper{Next: mem}, replaceWith, "replacement data"}
+		for _, opts := range [][]storage.GetOption{nil, {storage.WithRange(3, 4)}} {
+			if err := encrypted.PutObject("bucket", "key", strings.NewReader("original data")); err != nil {
+				t.Fatal(err)
+			}
+			// either object may be read, but decrypted with its own key
+			before, after := "original data", "replacement data"
+			if len(opts) > 0 {
+				before, after = before[3:7], after[3:7]
+			}
+
+			rc, err := New(replaced, keys, WithAllowUnencrypted()).GetObject("bucket", "key", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil || string(content) != before && string(content) != after {
+				t.Errorf("GetObject(%d options) = '%s', %v, want '%s' or '%s'", len(opts), content, err, before, after)
+			}
+		}
+	}
+}
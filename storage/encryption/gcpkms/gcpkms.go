@@ -0,0 +1,69 @@
+// Package gcpkms wraps data keys of the encryption package with a Google Cloud KMS key.
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/pbreedt/cloud-connect/storage/encryption"
+)
+
+// API is the part of *kms.KeyManagementClient used to wrap keys.
+type API interface {
+	Encrypt(ctx context.Context, req *kmspb.EncryptRequest, opts ...gax.CallOption) (*kmspb.EncryptResponse, error)
+	Decrypt(ctx context.Context, req *kmspb.DecryptRequest, opts ...gax.CallOption) (*kmspb.DecryptResponse, error)
+}
+
+// KeyProvider wraps data keys with a symmetric Cloud KMS key.
+type KeyProvider struct {
+	client  API
+	keyName string
+}
+
+var _ encryption.KeyProvider = &KeyProvider{}
+
+// New wraps data keys with keyName, as in projects/p/locations/l/keyRings/r/cryptoKeys/k, using client.
+func New(client API, keyName string) *KeyProvider {
+	return &KeyProvider{client: client, keyName: keyName}
+}
+
+// NewFromDefaultCredentials wraps data keys with keyName, using the default Google credentials.
+func NewFromDefaultCredentials(ctx context.Context, keyName string) (*KeyProvider, error) {
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, keyName), nil
+}
+
+// WrapKey returns the key version that encrypted as key id.
+func (p *KeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	resp, err := p.client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:      p.keyName,
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("WrapKey(%w)", err)
+	}
+	return resp.Ciphertext, resp.Name, nil
+}
+
+// UnwrapKey decrypts with the key of version keyId; Cloud KMS finds the version in the ciphertext.
+func (p *KeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyId string) ([]byte, error) {
+	keyName, _, _ := strings.Cut(keyId, "/cryptoKeyVersions/")
+	if keyName == "" {
+		keyName = p.keyName
+	}
+	resp, err := p.client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       keyName,
+		Ciphertext: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("UnwrapKey(%w)", err)
+	}
+	return resp.Plaintext, nil
+}
@@ -0,0 +1,56 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/googleapis/gax-go/v2"
+)
+
+const keyName = "projects/p/locations/europe/keyRings/r/cryptoKeys/backups"
+
+// fakeKMS "encrypts" by reversing the plaintext
+type fakeKMS struct{}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func (fakeKMS) Encrypt(ctx context.Context, req *kmspb.EncryptRequest, opts ...gax.CallOption) (*kmspb.EncryptResponse, error) {
+	return &kmspb.EncryptResponse{Ciphertext: reverse(req.Plaintext), Name: req.Name + "/cryptoKeyVersions/3"}, nil
+}
+
+func (fakeKMS) Decrypt(ctx context.Context, req *kmspb.DecryptRequest, opts ...gax.CallOption) (*kmspb.DecryptResponse, error) {
+	if req.Name != keyName {
+		return nil, fmt.Errorf("decrypt with '%s', want the crypto key", req.Name)
+	}
+	return &kmspb.DecryptResponse{Plaintext: reverse(req.Ciphertext)}, nil
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	p := New(fakeKMS{}, keyName)
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+
+	wrapped, keyId, err := p.WrapKey(context.Background(), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyId != keyName+"/cryptoKeyVersions/3" {
+		t.Errorf("key id = '%s', want the key version", keyId)
+	}
+
+	unwrapped, err := p.UnwrapKey(context.Background(), wrapped, keyId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Errorf("unwrapped key differs")
+	}
+}
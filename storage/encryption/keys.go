@@ -0,0 +1,126 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// KeyProvider wraps (encrypts) and unwraps the data keys of objects with a key encryption key,
+// typically held by a key management service.
+type KeyProvider interface {
+	// WrapKey encrypts dataKey, returning the wrapped key and the id of the key encryption key used
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyId string, err error)
+	// UnwrapKey decrypts a wrapped data key with key encryption key keyId
+	UnwrapKey(ctx context.Context, wrapped []byte, keyId string) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with AES-256-GCM keys held by the process, as loaded from a key file.
+// Keys are identified by a fingerprint; the first key wraps, all keys unwrap, so keys can be rotated.
+type LocalKeyProvider struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewLocalKeyProvider wraps with key and unwraps with key and the (rotated) previous keys, all 32 bytes long.
+func NewLocalKeyProvider(key []byte, previous ...[]byte) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: map[string][]byte{}}
+	for i, k := range append([][]byte{key}, previous...) {
+		if len(k) != 32 {
+			return nil, fmt.Errorf("NewLocalKeyProvider(key %d is %d bytes, must be 32)", i, len(k))
+		}
+		id := fingerprint(k)
+		if i == 0 {
+			p.primary = id
+		}
+		p.keys[id] = k
+	}
+	return p, nil
+}
+
+// LoadLocalKeyProvider loads the keys from key files created by GenerateKeyFile (or holding 32 raw, hex or base64 bytes),
+// the first wrapping new data keys.
+func LoadLocalKeyProvider(keyFile string, previousKeyFiles ...string) (*LocalKeyProvider, error) {
+	keys := [][]byte{}
+	for _, fileName := range append([]string{keyFile}, previousKeyFiles...) {
+		key, err := readKeyFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("LoadLocalKeyProvider(%w)", err)
+		}
+		keys = append(keys, key)
+	}
+	return NewLocalKeyProvider(keys[0], keys[1:]...)
+}
+
+// GenerateKeyFile writes a new random key to fileName, base64 encoded and only readable by the owner.
+func GenerateKeyFile(fileName string) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600)
+}
+
+// readKeyFile reads a 32 byte key, stored raw, hex or base64 encoded
+func readKeyFile(fileName string) ([]byte, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 32 {
+		return content, nil
+	}
+	text := string(bytes.TrimSpace(content))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key file '%s' must hold 32 bytes, raw, hex or base64 encoded", fileName)
+}
+
+// fingerprint identifies a key without revealing it
+func fingerprint(key []byte) string {
+	sum := sha256.Sum256(append([]byte("cloud-connect key id:"), key...))
+	return "local:" + hex.EncodeToString(sum[:8])
+}
+
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	gcm, err := newGCM(p.keys[p.primary])
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return gcm.Seal(nonce, nonce, dataKey, []byte(p.primary)), p.primary, nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyId string) ([]byte, error) {
+	key, ok := p.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("UnwrapKey(unknown key '%s')", keyId)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, fmt.Errorf("UnwrapKey(wrapped key too short)")
+	}
+	nonce, sealed := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+	dataKey, err := gcm.Open(nil, nonce, sealed, []byte(keyId))
+	if err != nil {
+		return nil, fmt.Errorf("UnwrapKey(%w)", err)
+	}
+	return dataKey, nil
+}
+
+var _ KeyProvider = &LocalKeyProvider{}
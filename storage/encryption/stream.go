@@ -0,0 +1,207 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+Objects are encrypted in chunks, so they can be streamed without holding them in memory:
+
+	header: magic "CCE1" | chunk size (uint32) | nonce prefix (7 random bytes)
+	chunks: AES-256-GCM(chunk of plaintext), each chunk size bytes except the last, plus a 16 byte tag
+
+The nonce of a chunk is the nonce prefix, the chunk number (uint32) and a byte marking the last chunk,
+so chunks can't be reordered, dropped or truncated without failing authentication.
+The header is authenticated as additional data of every chunk.
+*/
+
+const (
+	magic        = "CCE1"
+	prefixSize   = 7
+	headerSize   = len(magic) + 4 + prefixSize
+	tagSize      = 16
+	maxChunk     = 16 << 20
+	lastChunk    = 1
+	notLastChunk = 0
+)
+
+var errTruncated = errors.New("encrypted object is truncated")
+
+func newGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of chunk number i
+func chunkNonce(prefix []byte, i uint32, last byte) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, i)
+	return append(nonce, last)
+}
+
+// plaintextSize returns the size of the plaintext of ciphertextSize bytes in chunks of chunkSize, or -1 if invalid
+func plaintextSize(ciphertextSize int64, chunkSize int) int64 {
+	body := ciphertextSize - int64(headerSize)
+	if body < tagSize || chunkSize <= 0 {
+		return -1
+	}
+	sealed := int64(chunkSize + tagSize)
+	chunks := (body + sealed - 1) / sealed
+	return body - chunks*tagSize
+}
+
+// encryptingReader reads the encryption of a plaintext reader
+type encryptingReader struct {
+	src       io.Reader
+	gcm       cipher.AEAD
+	header    []byte
+	prefix    []byte
+	chunk     uint32
+	plaintext []byte
+	out       bytes.Buffer
+	done      bool
+}
+
+func newEncryptingReader(src io.Reader, dataKey []byte, chunkSize int) (*encryptingReader, error) {
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	header = append(header, prefix...)
+
+	r := &encryptingReader{src: src, gcm: gcm, header: header, prefix: prefix, plaintext: make([]byte, chunkSize)}
+	r.out.Write(header)
+	return r, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+	return r.out.Read(p)
+}
+
+// seal encrypts the next chunk of plaintext into out
+func (r *encryptingReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plaintext)
+	last := byte(notLastChunk)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = lastChunk
+		r.done = true
+	case err != nil:
+		return err
+	}
+
+	nonce := chunkNonce(r.prefix, r.chunk, last)
+	r.out.Write(r.gcm.Seal(nil, nonce, r.plaintext[:n], r.header))
+	r.chunk++
+	return nil
+}
+
+// decryptingReader reads the plaintext of an encrypted reader
+type decryptingReader struct {
+	src       io.ReadCloser
+	gcm       cipher.AEAD
+	header    []byte
+	prefix    []byte
+	chunk     uint32
+	sealed    []byte
+	plaintext []byte
+	done      bool
+	err       error
+}
+
+func newDecryptingReader(src io.ReadCloser, dataKey []byte) (*decryptingReader, error) {
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, errTruncated
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("unknown encryption format '%x'", header[:len(magic)])
+	}
+	chunkSize := binary.BigEndian.Uint32(header[len(magic):])
+	if chunkSize == 0 || chunkSize > maxChunk {
+		return nil, fmt.Errorf("invalid encryption chunk size %d", chunkSize)
+	}
+
+	return &decryptingReader{
+		src:    src,
+		gcm:    gcm,
+		header: header,
+		prefix: header[len(magic)+4:],
+		sealed: make([]byte, int(chunkSize)+tagSize),
+	}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.open()
+	}
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+// open decrypts the next chunk into plaintext; only the last chunk is shorter than a full chunk
+func (r *decryptingReader) open() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	last := byte(notLastChunk)
+	switch {
+	case err == io.EOF:
+		return errTruncated
+	case err == io.ErrUnexpectedEOF:
+		last = lastChunk
+		r.done = true
+	case err != nil:
+		return err
+	}
+
+	plaintext, err := r.gcm.Open(r.sealed[:0], chunkNonce(r.prefix, r.chunk, last), r.sealed[:n], r.header)
+	if err != nil {
+		// tampered with, or truncated at a chunk boundary (a full chunk is never the last)
+		return fmt.Errorf("decrypt chunk %d: %w", r.chunk, err)
+	}
+	r.plaintext = plaintext
+	r.chunk++
+	return nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.src.Close()
+}
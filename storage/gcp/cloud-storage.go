@@ -3,13 +3,17 @@ package gcp
 import (
 	"context"
 	"fmt"
-	"io"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 /*
@@ -30,17 +34,91 @@ type CloudStorageClient struct {
 	location  string
 }
 
+// Config holds the client settings.
+// ProjectId is only needed to create and list buckets; Location defaults to US-CENTRAL1.
+type Config struct {
+	ProjectId string
+	Location  string
+	// CredentialsFile replaces GOOGLE_APPLICATION_CREDENTIALS
+	CredentialsFile string
+	// CredentialsJSON is the content of a service account key file, replacing GOOGLE_APPLICATION_CREDENTIALS
+	CredentialsJSON []byte
+	// Timeout limits connecting and waiting for the response of each request (see common.NewTransport)
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts per request, including the first (GCS default: 3)
+	MaxAttempts int
+	// RetryPolicy replaces the GCS retry settings, with MaxAttempts as default attempts
+	RetryPolicy *common.RetryPolicy
+	// Endpoint replaces the GCS JSON API endpoint, e.g. for fake-gcs-server: http://localhost:4443/storage/v1/
+	// Requests to http:// endpoints are not authenticated.
+	Endpoint string
+	// InsecureSkipVerify disables TLS certificate verification, for local services only
+	InsecureSkipVerify bool
+}
+
+// Deprecated: use NewCloudStorageClientFromConfig, which returns an error instead of exiting the process.
 func NewCloudStorageClient(projectId string) *CloudStorageClient {
-	client, err := storage.NewClient(context.Background())
+	gcpClient, err := NewCloudStorageClientFromConfig(Config{ProjectId: projectId})
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	return gcpClient
+}
+
+func NewCloudStorageClientFromConfig(cfg Config) (*CloudStorageClient, error) {
+	ctx := context.Background()
+
+	clientOpts := []option.ClientOption{}
+	if cfg.CredentialsFile != "" {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	if len(cfg.CredentialsJSON) > 0 {
+		clientOpts = append(clientOpts, option.WithCredentialsJSON(cfg.CredentialsJSON))
+	}
+
+	anonymous := strings.HasPrefix(cfg.Endpoint, "http://")
+	if anonymous {
+		clientOpts = []option.ClientOption{option.WithoutAuthentication()}
+	}
+
+	if cfg.Timeout > 0 || cfg.InsecureSkipVerify {
+		var transport http.RoundTripper = common.NewTransport(cfg.Timeout, cfg.InsecureSkipVerify)
+		if !anonymous {
+			// a custom HTTP client replaces the authenticating default, so authenticate on top of our transport
+			var err error
+			transport, err = htransport.NewTransport(ctx, transport, append(clientOpts, option.WithScopes(storage.ScopeFullControl))...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		clientOpts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: transport})}
+	}
+
+	if cfg.Endpoint != "" {
+		clientOpts = append(clientOpts, option.WithEndpoint(cfg.Endpoint))
+	}
+
+	client, err := storage.NewClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RetryPolicy != nil {
+		client.SetRetry(retryOptions(cfg.RetryPolicy.WithDefaults(cfg.MaxAttempts))...)
+	} else if cfg.MaxAttempts > 0 {
+		client.SetRetry(storage.WithMaxAttempts(cfg.MaxAttempts))
+	}
+
+	location := cfg.Location
+	if location == "" {
+		location = "US-CENTRAL1"
+	}
+
 	return &CloudStorageClient{
 		Client:    client,
-		projectId: projectId,
-		location:  "US-CENTRAL1",
-	}
+		projectId: cfg.ProjectId,
+		location:  location,
+	}, nil
 }
 
 func (gcpClient *CloudStorageClient) WithDefaultLocation(location string) *CloudStorageClient {
@@ -51,13 +129,35 @@ func (gcpClient *CloudStorageClient) WithDefaultLocation(location string) *Cloud
 // ################
 // Bucket functions
 // ################
-func (gcpClient *CloudStorageClient) CreateBucket(bucketName string) error {
+func (gcpClient *CloudStorageClient) CreateBucket(bucketName string, opts ...common.CreateBucketOption) error {
+	o := common.NewCreateBucketOptions(opts...)
+	storageClass, err := toGCPStorageClass(o.StorageClass)
+	if err != nil {
+		return err
+	}
+	if len(o.Encryption.CustomerKey) > 0 {
+		return fmt.Errorf("CreateBucket(default customer key: %w)", common.ErrNotSupported)
+	}
+
 	bkt := gcpClient.Client.Bucket(bucketName)
-	attrLocation := &storage.BucketAttrs{
-		Location: gcpClient.location,
+	attrs := &storage.BucketAttrs{
+		Location:          gcpClient.location,
+		StorageClass:      storageClass,
+		Labels:            o.Labels,
+		VersioningEnabled: o.Versioning,
+	}
+	if o.Location != "" {
+		attrs.Location = o.Location
+	}
+	if o.BlockPublicAccess {
+		attrs.UniformBucketLevelAccess = storage.UniformBucketLevelAccess{Enabled: true}
+		attrs.PublicAccessPrevention = storage.PublicAccessPreventionEnforced
+	}
+	if o.Encryption.KMSKey != "" {
+		attrs.Encryption = &storage.BucketEncryption{DefaultKMSKeyName: o.Encryption.KMSKey}
 	}
 
-	err := bkt.Create(context.Background(), gcpClient.projectId, attrLocation)
+	err = bkt.Create(context.Background(), gcpClient.projectId, attrs)
 	if err != nil {
 		return err
 	}
@@ -102,7 +202,16 @@ func (gcpClient *CloudStorageClient) ListBucketContent(bucketName string) ([]str
 	return objects, nil
 }
 
-func (gcpClient *CloudStorageClient) DeleteBucket(bucketName string) error {
+// DeleteBucket deletes an empty bucket, or any bucket if WithForce() is provided.
+func (gcpClient *CloudStorageClient) DeleteBucket(bucketName string, opts ...common.DeleteBucketOption) error {
+	o := common.NewDeleteBucketOptions(opts...)
+	if o.Force {
+		err := gcpClient.emptyBucket(context.Background(), bucketName, o)
+		if err != nil {
+			return fmt.Errorf("DeleteBucket(%w)", err)
+		}
+	}
+
 	bkt := gcpClient.Client.Bucket(bucketName)
 
 	err := bkt.Delete(context.Background())
@@ -117,79 +226,25 @@ func (gcpClient *CloudStorageClient) DeleteBucket(bucketName string) error {
 // Bucket content functions
 // ########################
 
-// uploadFile uploads an object.
-func (gcpClient *CloudStorageClient) StoreObject(bucketName string, objectKey string, fileName string) error {
+func (gcpClient *CloudStorageClient) StoreObject(bucketName string, objectKey string, fileName string, opts ...common.PutOption) error {
 	f, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*50)
-	defer cancel()
-
-	o := gcpClient.Client.Bucket(bucketName).Object(objectKey)
-
-	// Optional: set a generation-match precondition to avoid potential race
-	// conditions and data corruptions. The request to upload is aborted if the
-	// object's generation number does not match your precondition.
-	// For an object that does not yet exist, set the DoesNotExist precondition.
-	// o = o.If(storage.Conditions{DoesNotExist: true})
-
-	// If the live object already exists in your bucket, set instead a
-	// generation-match precondition using the live object's generation number.
-	// attrs, err := o.Attrs(ctx)
-	// if err != nil {
-	//      return fmt.Errorf("object.Attrs: %w", err)
-	// }
-	// o = o.If(storage.Conditions{GenerationMatch: attrs.Generation})
-
-	// Upload an object with storage.Writer.
-	wc := o.NewWriter(ctx)
-	if _, err = io.Copy(wc, f); err != nil {
-		return err
-	}
-	if err := wc.Close(); err != nil {
-		return err
-	}
-
-	return nil
+	return gcpClient.PutObject(bucketName, objectKey, f, opts...)
 }
 
-// downloadFile downloads an object to a file.
-func (gcpClient *CloudStorageClient) RetrieveObject(bucketName string, objectKey string, fileName string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*50)
-	defer cancel()
-
-	f, err := os.Create(fileName)
+func (gcpClient *CloudStorageClient) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...common.GetOption) error {
+	rc, err := gcpClient.GetObject(bucketName, objectKey, opts...)
 	if err != nil {
 		return err
 	}
-
-	rc, err := gcpClient.Client.Bucket(bucketName).Object(objectKey).NewReader(ctx)
-	if err != nil {
-		return err
-	}
-	defer rc.Close()
-
-	if _, err := io.Copy(f, rc); err != nil {
-		return err
-	}
-
-	if err = f.Close(); err != nil {
-		return err
-	}
-
-	return nil
-
+	return common.WriteFile(rc, fileName)
 }
 
 func (gcpClient *CloudStorageClient) DeleteObject(bucketName string, objectKeys []string) error {
-	for _, objectKey := range objectKeys {
-		err := gcpClient.Client.Bucket(bucketName).Object(objectKey).Delete(context.Background())
-		if err != nil {
-			return fmt.Errorf("DeleteObject(%w)", err)
-		}
-	}
-	return nil
+	_, err := gcpClient.DeleteObjects(bucketName, objectKeys)
+	return err
 }
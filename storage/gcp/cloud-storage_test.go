@@ -1,10 +1,13 @@
 package gcp
 
 import (
+	"errors"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/pbreedt/cloud-connect/storage/common"
 )
 
 var (
@@ -37,6 +40,56 @@ func TestCSUpload(t *testing.T) {
 	t.Logf("Test data successfully uploaded to bucket '%s'.\n", bucketName)
 }
 
+func TestCSStatAndGet(t *testing.T) {
+	gcp := NewCloudStorageClient(projectId)
+
+	info, err := gcp.StatObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := os.ReadFile("../test_data/testfile.txt")
+	if info.Size != int64(len(expected)) {
+		t.Errorf("Size = %d, want %d", info.Size, len(expected))
+	}
+
+	body, err := gcp.GetObject(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(expected) {
+		t.Errorf("GetObject returned '%s', want '%s'", data, expected)
+	}
+
+	_, err = gcp.StatObject(bucketName, "no-such-object")
+	if !errors.Is(err, common.ErrNotFound) {
+		t.Errorf("StatObject of missing object: %v, want ErrNotFound", err)
+	}
+	t.Log("Test data successfully read back.")
+}
+
+func TestCSSetStorageClass(t *testing.T) {
+	gcp := NewCloudStorageClient(projectId)
+
+	err := gcp.SetStorageClass(bucketName, "test-object", common.StorageClassInfrequentAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := gcp.RestoreStatus(bucketName, "test-object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available {
+		t.Fatalf("Test object should be available after storage class change: %+v", status)
+	}
+	t.Log("Storage class of test data successfully changed.")
+}
+
 func TestCSListBuckets(t *testing.T) {
 	gcp := NewCloudStorageClient(projectId)
 	t.Logf("Buckets in project '%s':\n", projectId)
@@ -98,3 +151,31 @@ func TestCSDeleteBucket(t *testing.T) {
 	}
 	t.Logf("Bucket '%s' successfully deleted.\n", bucketName)
 }
+
+func TestCSForceDeleteBucket(t *testing.T) {
+	gcp := NewCloudStorageClient(projectId)
+	forceBucketName := uuid.New().String()
+
+	err := gcp.CreateBucket(forceBucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"test-object-1", "test-object-2"} {
+		err = gcp.StoreObject(forceBucketName, key, "../test_data/testfile.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := 0
+	err = gcp.DeleteBucket(forceBucketName, common.WithForce(), common.WithDeleteProgress(func(n int) {
+		deleted = n
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("Progress reported %d deleted objects, want 2", deleted)
+	}
+	t.Logf("Non-empty bucket '%s' successfully deleted.\n", forceBucketName)
+}
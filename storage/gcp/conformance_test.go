@@ -0,0 +1,28 @@
+package gcp
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+// TestCSConformance runs the conformance tests against fake-gcs-server:
+//
+//	docker run -p 4443:4443 fsouza/fake-gcs-server -scheme http -public-host localhost:4443
+//	CLOUDCONNECT_TEST_GCS_ENDPOINT=http://localhost:4443/storage/v1/ go test ./storage/gcp -run Conformance
+func TestCSConformance(t *testing.T) {
+	endpoint := os.Getenv("CLOUDCONNECT_TEST_GCS_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("CLOUDCONNECT_TEST_GCS_ENDPOINT not set")
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		gcpClient, err := NewCloudStorageClientFromConfig(Config{ProjectId: "test-project", Endpoint: endpoint})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gcpClient
+	})
+}
@@ -0,0 +1,31 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// DeleteObjects deletes the keys one request per key, common.DeleteConcurrency at a time: the Go client library
+// has no batch delete, so unlike the 1000-key S3 and 256-key Azure batches, deleting n keys takes n requests.
+// The returned error is non-nil if any key could not be deleted; the report holds the per-key results.
+func (gcpClient *CloudStorageClient) DeleteObjects(bucketName string, objectKeys []string) (*common.DeleteReport, error) {
+	report := common.NewDeleteReport(objectKeys)
+
+	ctx := context.Background()
+	bkt := gcpClient.Client.Bucket(bucketName)
+	g := errgroup.Group{}
+	g.SetLimit(common.DeleteConcurrency)
+
+	for i := range report.Results {
+		result := &report.Results[i]
+		g.Go(func() error {
+			result.Err = notFound(bkt.Object(result.Key).Delete(ctx))
+			return nil
+		})
+	}
+	g.Wait()
+
+	return report, report.Err()
+}
@@ -0,0 +1,42 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+)
+
+// emptyBucket deletes all objects, including noncurrent versions, in the bucket.
+func (gcpClient *CloudStorageClient) emptyBucket(ctx context.Context, bucketName string, o common.DeleteBucketOptions) error {
+	counter := common.NewDeleteCounter(o.Progress)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.Concurrency)
+
+	bkt := gcpClient.Client.Bucket(bucketName)
+	it := bkt.Objects(ctx, &storage.Query{Versions: true})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			g.Wait()
+			return err
+		}
+
+		name, generation := attrs.Name, attrs.Generation
+		g.Go(func() error {
+			err := bkt.Object(name).Generation(generation).Delete(ctx)
+			if err != nil && err != storage.ErrObjectNotExist {
+				return err
+			}
+			counter.Add(1)
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
@@ -0,0 +1,26 @@
+package gcp
+
+import (
+	"cloud.google.com/go/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// object returns the handle of the object, encrypted with the customer key of e if it has one
+func (gcpClient *CloudStorageClient) object(bucketName string, objectKey string, e common.EncryptionOptions) *storage.ObjectHandle {
+	o := gcpClient.Client.Bucket(bucketName).Object(objectKey)
+	if len(e.CustomerKey) > 0 {
+		o = o.Key(e.CustomerKey)
+	}
+	return o
+}
+
+func fromGCPEncryption(attrs *storage.ObjectAttrs) common.EncryptionInfo {
+	switch {
+	case attrs.CustomerKeySHA256 != "":
+		return common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: attrs.CustomerKeySHA256}
+	case attrs.KMSKeyName != "":
+		return common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: attrs.KMSKeyName}
+	default:
+		return common.EncryptionInfo{Type: common.EncryptionProviderManaged}
+	}
+}
@@ -0,0 +1,111 @@
+package gcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+// cmekServer records the last upload or rewrite request, and returns objects encrypted with the customer key of the
+// request, or the KMS key of the last upload
+func cmekServer(t *testing.T) (*CloudStorageClient, *http.Request) {
+	upload := &http.Request{}
+	kmsKey := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := map[string]any{"name": "key", "bucket": "bucket", "size": "4", "generation": "1"}
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			*upload = *r.Clone(r.Context())
+			kmsKey = r.URL.Query().Get("kmsKeyName")
+		}
+		rewrite := strings.Contains(r.URL.Path, "/rewriteTo/")
+		if rewrite {
+			*upload = *r.Clone(r.Context())
+		}
+		if sha := r.Header.Get("X-Goog-Encryption-Key-Sha256"); sha != "" {
+			attrs["customerEncryption"] = map[string]string{"encryptionAlgorithm": "AES256", "keySha256": sha}
+		} else if kmsKey != "" {
+			attrs["kmsKeyName"] = kmsKey + "/cryptoKeyVersions/1"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if rewrite {
+			json.NewEncoder(w).Encode(map[string]any{"done": true, "objectSize": "4", "totalBytesRewritten": "4", "resource": attrs})
+			return
+		}
+		json.NewEncoder(w).Encode(attrs)
+	}))
+	t.Cleanup(server.Close)
+
+	gcpClient, err := NewCloudStorageClientFromConfig(Config{
+		ProjectId: "project",
+		Endpoint:  server.URL + "/storage/v1/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gcpClient, upload
+}
+
+func TestCSCustomerKey(t *testing.T) {
+	gcpClient, upload := cmekServer(t)
+	encryption := common.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+
+	err := gcpClient.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(encryption))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Header.Get("X-Goog-Encryption-Key") != encryption.CustomerKeyBase64() {
+		t.Errorf("PutObject headers = %v, want customer key headers", upload.Header)
+	}
+
+	info, err := gcpClient.StatObject("bucket", "key", common.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionCustomerKey, CustomerKeyHash: encryption.CustomerKeySHA256()}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+}
+
+func TestCSKMSKey(t *testing.T) {
+	gcpClient, upload := cmekServer(t)
+	key := "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+
+	err := gcpClient.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(common.EncryptionOptions{KMSKey: key}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.URL.Query().Get("kmsKeyName") != key {
+		t.Errorf("PutObject URL = %s, want kmsKeyName %s", upload.URL, key)
+	}
+
+	info, err := gcpClient.StatObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.EncryptionInfo{Type: common.EncryptionKMS, KMSKey: key + "/cryptoKeyVersions/1"}
+	if info.Encryption != want {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, want)
+	}
+}
+
+func TestCSStorageClassKMSKey(t *testing.T) {
+	gcpClient, rewrite := cmekServer(t)
+	key := "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+
+	err := gcpClient.PutObject("bucket", "key", strings.NewReader("data"), common.WithEncryption(common.EncryptionOptions{KMSKey: key}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gcpClient.SetStorageClass("bucket", "key", common.StorageClassInfrequentAccess); err != nil {
+		t.Fatal(err)
+	}
+	if rewrite.URL.Query().Get("destinationKmsKeyName") != key {
+		t.Errorf("SetStorageClass rewrite URL = %s, want destinationKmsKeyName %s", rewrite.URL, key)
+	}
+}
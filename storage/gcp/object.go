@@ -0,0 +1,138 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func notFound(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %w", common.ErrNotFound, err)
+	}
+	return err
+}
+
+func (gcpClient *CloudStorageClient) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...common.PutOption) error {
+	po := common.NewPutOptions(opts...)
+	storageClass, err := toGCPStorageClass(po.StorageClass)
+	if err != nil {
+		return err
+	}
+	if err := po.Encryption.Validate(); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	o := gcpClient.object(bucketName, objectKey, po.Encryption)
+
+	// Optional: set a generation-match precondition to avoid potential race
+	// conditions and data corruptions. The request to upload is aborted if the
+	// object's generation number does not match your precondition.
+	// For an object that does not yet exist, set the DoesNotExist precondition.
+	// o = o.If(storage.Conditions{DoesNotExist: true})
+
+	// Upload an object with storage.Writer.
+	wc := o.NewWriter(ctx)
+	wc.StorageClass = storageClass
+	wc.ContentType = po.ContentType
+	wc.ContentEncoding = po.ContentEncoding
+	wc.Metadata = po.Metadata
+	wc.KMSKeyName = po.Encryption.KMSKey
+
+	if po.Progress != nil {
+		reader = common.NewProgressReader(reader, -1, po.Progress)
+	}
+	if _, err = io.Copy(wc, reader); err != nil {
+		// cancelling the context aborts the upload
+		return err
+	}
+
+	return wc.Close()
+}
+
+// GetObject returns a reader for the object content, which must be closed by the caller.
+// Objects stored with Content-Encoding gzip are decompressed by GCS while read, unless read with
+// common.WithRawContent.
+func (gcpClient *CloudStorageClient) GetObject(bucketName string, objectKey string, opts ...common.GetOption) (io.ReadCloser, error) {
+	o := common.NewGetOptions(opts...)
+	ctx := context.Background()
+	obj := gcpClient.object(bucketName, objectKey, o.Encryption)
+
+	// reads can't be conditional on the ETag: the metadata is read first, then the generation it describes
+	if o.IfNoneMatch != "" || o.Info != nil {
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			return nil, notFound(err)
+		}
+		if o.IfNoneMatch != "" && attrs.Etag == o.IfNoneMatch {
+			return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, common.ErrNotModified)
+		}
+		if o.Info != nil {
+			*o.Info = objectInfo(attrs)
+		}
+		obj = obj.Generation(attrs.Generation)
+	}
+
+	length := int64(-1)
+	if o.Length > 0 {
+		length = o.Length
+	}
+	rc, err := obj.ReadCompressed(o.RawContent).NewRangeReader(ctx, o.Offset, length)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if o.Progress != nil {
+		return common.NewProgressReadCloser(rc, rc.Remain(), o.Progress), nil
+	}
+	return rc, nil
+}
+
+func (gcpClient *CloudStorageClient) StatObject(bucketName string, objectKey string, opts ...common.GetOption) (common.ObjectInfo, error) {
+	o := common.NewGetOptions(opts...)
+	attrs, err := gcpClient.object(bucketName, objectKey, o.Encryption).Attrs(context.Background())
+	if err != nil {
+		return common.ObjectInfo{}, notFound(err)
+	}
+
+	return objectInfo(attrs), nil
+}
+
+func objectInfo(attrs *storage.ObjectAttrs) common.ObjectInfo {
+	return common.ObjectInfo{
+		Key:             attrs.Name,
+		Size:            attrs.Size,
+		LastModified:    attrs.Updated,
+		ETag:            attrs.Etag,
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		StorageClass:    fromGCPStorageClass(attrs.StorageClass),
+		Metadata:        attrs.Metadata,
+		Encryption:      fromGCPEncryption(attrs),
+	}
+}
+
+// PresignURL returns a V4 signed URL granting method (GET, PUT or DELETE) access to the object until it expires.
+// Signing requires service account credentials (GOOGLE_APPLICATION_CREDENTIALS).
+func (gcpClient *CloudStorageClient) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+	default:
+		return "", fmt.Errorf("PresignURL(method %s: %w)", method, common.ErrNotSupported)
+	}
+
+	return gcpClient.Client.Bucket(bucketName).SignedURL(objectKey, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(expiry),
+	})
+}
@@ -0,0 +1,59 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func init() {
+	storage.Register(storage.TypeGCP, newStorage)
+	storage.RegisterScheme("gs", openURL)
+}
+
+func newStorage(opts storage.Options) (storage.Storage, error) {
+	gcpClient, err := NewCloudStorageClientFromConfig(Config{
+		ProjectId:          opts.GCP_ProjectId,
+		Location:           opts.Location,
+		CredentialsFile:    opts.CredentialsFile,
+		CredentialsJSON:    opts.Credentials.GCP_ServiceAccountJSON,
+		Timeout:            opts.Timeout,
+		MaxAttempts:        opts.MaxAttempts,
+		RetryPolicy:        opts.RetryPolicy,
+		Endpoint:           opts.Endpoint,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gcpClient, nil
+}
+
+// openURL opens gs://bucket?project=my-project&location=EU, with the project defaulting to $GOOGLE_CLOUD_PROJECT.
+// An endpoint query parameter replaces the GCS endpoint.
+func openURL(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+	if err := storage.CheckQuery(u, "project", "location", "endpoint"); err != nil {
+		return nil, "", err
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("bucket name must be provided, as in gs://bucket")
+	}
+
+	project := u.Query().Get("project")
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	gcpClient, err := NewCloudStorageClientFromConfig(Config{
+		ProjectId: project,
+		Location:  u.Query().Get("location"),
+		Endpoint:  u.Query().Get("endpoint"),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return gcpClient, u.Host, nil
+}
@@ -0,0 +1,45 @@
+package gcp
+
+import (
+	"errors"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"google.golang.org/api/googleapi"
+)
+
+// retryOptions maps the policy to GCS retry options. GCS applies its own jitter and
+// only retries idempotent operations (see storage.RetryIdempotent).
+func retryOptions(policy common.RetryPolicy) []storage.RetryOption {
+	return []storage.RetryOption{
+		storage.WithMaxAttempts(policy.MaxAttempts),
+		storage.WithBackoff(gax.Backoff{
+			Initial:    policy.BaseBackoff,
+			Max:        policy.MaxBackoff,
+			Multiplier: 2,
+		}),
+		storage.WithErrorFunc(func(err error) bool {
+			if err == nil {
+				return false
+			}
+			retry := storage.ShouldRetry(err)
+			if policy.Retryable != nil {
+				retry = policy.Retryable(err, statusCode(err))
+			}
+			if retry && policy.OnRetry != nil {
+				policy.OnRetry(common.RetryEvent{Err: err})
+			}
+			return retry
+		}),
+	}
+}
+
+// statusCode returns the HTTP status of the response that caused err, 0 if there was none
+func statusCode(err error) int {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
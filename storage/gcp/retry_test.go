@@ -0,0 +1,51 @@
+package gcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+func TestCSRetryPolicy(t *testing.T) {
+	requests := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind": "storage#buckets", "items": [{"name": "bucket"}]}`))
+	}))
+	defer server.Close()
+
+	statusCodes := []int{}
+	gcpClient, err := NewCloudStorageClientFromConfig(Config{
+		ProjectId: "project",
+		Endpoint:  server.URL + "/storage/v1/",
+		RetryPolicy: &common.RetryPolicy{
+			MaxAttempts: 3,
+			BaseBackoff: time.Millisecond,
+			OnRetry: func(event common.RetryEvent) {
+				statusCodes = append(statusCodes, statusCode(event.Err))
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := gcpClient.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || requests.Load() != 3 {
+		t.Errorf("ListBuckets() = %v after %d requests, want [bucket] after 3", buckets, requests.Load())
+	}
+	if len(statusCodes) != 2 || statusCodes[0] != http.StatusServiceUnavailable {
+		t.Errorf("retried status codes = %v, want two 503s", statusCodes)
+	}
+}
@@ -0,0 +1,48 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"github.com/pbreedt/cloud-connect/storage/common"
+	"google.golang.org/api/iterator"
+)
+
+// WalkBucketContent calls fn for every object in the bucket, one listing page at a time.
+func (gcpClient *CloudStorageClient) WalkBucketContent(bucketName string, fn common.WalkFunc, opts ...common.ListOption) error {
+	o := common.NewListOptions(opts...)
+
+	it := gcpClient.Client.Bucket(bucketName).Objects(context.Background(), &storage.Query{
+		Prefix:    o.Prefix,
+		Delimiter: o.Delimiter,
+	})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// a common prefix of a listing with a delimiter
+		if attrs.Prefix != "" {
+			err = fn(common.ObjectInfo{Key: attrs.Prefix, IsPrefix: true})
+		} else {
+			err = fn(common.ObjectInfo{
+				Key:          attrs.Name,
+				Size:         attrs.Size,
+				LastModified: attrs.Updated,
+				ETag:         attrs.Etag,
+			})
+		}
+		if err == common.StopWalk {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
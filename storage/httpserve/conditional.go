@@ -0,0 +1,134 @@
+package httpserve
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errUnsatisfiableRange = errors.New("range not satisfiable")
+
+// checkPreconditions evaluates the conditional headers of a GET or HEAD request for an object with etag and modTime,
+// in the order of RFC 9110 section 13.2.2. It returns the status to answer with, or 0 to serve the object.
+func checkPreconditions(r *http.Request, etag string, modTime time.Time) int {
+	// HTTP dates have seconds precision
+	modTime = modTime.Truncate(time.Second)
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := httpDate(r.Header.Get("If-Unmodified-Since")); ok && !modTime.IsZero() && modTime.After(t) {
+		return http.StatusPreconditionFailed
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			return http.StatusNotModified
+		}
+	} else if t, ok := httpDate(r.Header.Get("If-Modified-Since")); ok && !modTime.IsZero() && !modTime.After(t) {
+		return http.StatusNotModified
+	}
+	return 0
+}
+
+// matchETag reports whether the list of entity tags of an If-Match or If-None-Match header matches etag, with the
+// weak comparison of If-None-Match or the strong one of If-Match
+func matchETag(list string, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// httpDate parses the date of a conditional header, ok is false if it is missing or invalid
+func httpDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+// requestRange returns the range of an object of size to serve, from the Range header: the whole object (ranged is
+// false) without one, if the If-Range condition fails, or if the header can't be served as a single range (several
+// ranges, other units, invalid syntax), as servers may ignore Range. An error is returned for ranges past the end.
+func requestRange(r *http.Request, etag string, modTime time.Time, size int64) (start int64, length int64, ranged bool, err error) {
+	header := r.Header.Get("Range")
+	if header == "" || size == 0 || !ifRange(r.Header.Get("If-Range"), etag, modTime) {
+		return 0, size, false, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, false, nil
+	}
+
+	if first == "" {
+		// the last bytes
+		n, ok := parseBytePos(last)
+		if !ok {
+			return 0, size, false, nil
+		}
+		if n == 0 {
+			return 0, 0, false, errUnsatisfiableRange
+		}
+		n = min(n, size)
+		return size - n, n, true, nil
+	}
+
+	start, ok = parseBytePos(first)
+	if !ok {
+		return 0, size, false, nil
+	}
+	end := size - 1
+	if last != "" {
+		end, ok = parseBytePos(last)
+		if !ok || end < start {
+			return 0, size, false, nil
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, false, errUnsatisfiableRange
+	}
+	return start, end - start + 1, true, nil
+}
+
+// parseBytePos parses a byte position of a range, digits only
+func parseBytePos(value string) (int64, bool) {
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n, err == nil
+}
+
+// ifRange reports whether the If-Range condition holds, an entity tag must match strongly and a date exactly
+func ifRange(value string, etag string, modTime time.Time) bool {
+	if value == "" {
+		return true
+	}
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "W/") {
+		return matchETag(value, etag, false)
+	}
+	t, ok := httpDate(value)
+	return ok && !modTime.IsZero() && modTime.Truncate(time.Second).Equal(t)
+}
@@ -0,0 +1,247 @@
+// Package httpserve serves the objects of a bucket over HTTP, from any storage.Storage.
+//
+//	http.Handle("/files/", http.StripPrefix("/files", httpserve.New(cloudStorage, "website",
+//		httpserve.WithDirectoryListing())))
+//
+// Request paths are object keys, below the prefix of WithPrefix. Objects are served with their Content-Type
+// (from the file extension if none was stored), Content-Encoding, ETag and Last-Modified, answering conditional
+// requests (If-None-Match, If-Modified-Since, If-Match, If-Unmodified-Since) and single range requests (Range,
+// If-Range) with ranged reads of the object. Requests for several ranges are answered with the whole object.
+//
+// With WithPresignRedirect, GET requests are redirected to presigned URLs of the objects, so the content is
+// downloaded from the provider directly rather than through the handler.
+//
+// Only GET and HEAD requests are served. Failing storage operations are answered with 500 Internal Server Error,
+// without details; wrap the storage with storage.Logging to log them.
+package httpserve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+type options struct {
+	prefix        string
+	listing       bool
+	presignExpiry time.Duration
+}
+
+type Option func(*options)
+
+// WithPrefix serves the objects below prefix, e.g. "public/": the request path /index.html serves public/index.html.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithDirectoryListing serves an HTML listing of the objects and prefixes ("directories") for request paths ending
+// with "/", and redirects paths of prefixes without an object to the path with "/". Without it, such requests are
+// answered with 404 Not Found.
+func WithDirectoryListing() Option {
+	return func(o *options) {
+		o.listing = true
+	}
+}
+
+// WithPresignRedirect redirects GET requests for objects to presigned URLs valid for expiry (see
+// Storage.PresignURL), with 307 Temporary Redirect. Conditional requests answered with 304 Not Modified aren't
+// redirected, neither are HEAD requests. Objects are served by the handler if the storage doesn't support presigned
+// URLs (storage.ErrNotSupported), as for encrypted storage.
+func WithPresignRedirect(expiry time.Duration) Option {
+	return func(o *options) {
+		o.presignExpiry = expiry
+	}
+}
+
+// Handler is an http.Handler serving the objects of a bucket.
+type Handler struct {
+	storage    storage.Storage
+	bucketName string
+	options    options
+}
+
+var _ http.Handler = &Handler{}
+
+// New returns a handler serving the objects in the bucket.
+func New(cloudStorage storage.Storage, bucketName string, opts ...Option) *Handler {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Handler{storage: cloudStorage, bucketName: bucketName, options: o}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	key := h.options.prefix + strings.TrimPrefix(name, "/")
+	if name == "/" || strings.HasSuffix(r.URL.Path, "/") {
+		if !h.options.listing {
+			http.NotFound(w, r)
+			return
+		}
+		if name != "/" {
+			name, key = name+"/", key+"/"
+		}
+		h.serveListing(w, r, name, key)
+		return
+	}
+	h.serveObject(w, r, key)
+}
+
+// serveError answers a request whose storage operation failed
+func serveError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (h *Handler) serveObject(w http.ResponseWriter, r *http.Request, key string) {
+	info, err := h.storage.StatObject(h.bucketName, key)
+	if errors.Is(err, storage.ErrNotFound) && h.options.listing {
+		found, err := h.hasPrefix(key + "/")
+		if err != nil {
+			serveError(w, r, err)
+			return
+		}
+		if found {
+			redirectToDir(w, r)
+			return
+		}
+	}
+	if err != nil {
+		serveError(w, r, err)
+		return
+	}
+
+	header := w.Header()
+	etag := quoteETag(info.ETag)
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !info.LastModified.IsZero() {
+		header.Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if status := checkPreconditions(r, etag, info.LastModified); status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	if h.options.presignExpiry > 0 && r.Method == http.MethodGet {
+		url, err := h.storage.PresignURL(h.bucketName, key, http.MethodGet, h.options.presignExpiry)
+		if err == nil {
+			http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+			return
+		}
+		if !errors.Is(err, storage.ErrNotSupported) {
+			serveError(w, r, err)
+			return
+		}
+	}
+
+	start, length, ranged, err := requestRange(r, etag, info.LastModified, info.Size)
+	if err != nil {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	status := http.StatusOK
+	if ranged {
+		status = http.StatusPartialContent
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, info.Size))
+	}
+
+	var content io.ReadCloser
+	contentLength := strconv.FormatInt(length, 10)
+	if r.Method == http.MethodGet {
+		// the whole content is served with the size of the read itself, a wrapper's stat'ed size may differ
+		read := storage.ObjectInfo{}
+		// served as stored, with its Content-Encoding
+		opts := []storage.GetOption{storage.WithObjectInfo(&read), storage.WithRawContent()}
+		if ranged {
+			opts = []storage.GetOption{storage.WithRange(start, length), storage.WithRawContent()}
+		}
+		content, err = h.storage.GetObject(h.bucketName, key, opts...)
+		if err != nil {
+			header.Del("Content-Range")
+			serveError(w, r, err)
+			return
+		}
+		defer content.Close()
+		if !ranged {
+			// storage that doesn't fill in the info is served without a length
+			contentLength = ""
+			if read.ETag != "" {
+				contentLength = strconv.FormatInt(read.Size, 10)
+				header.Set("ETag", quoteETag(read.ETag))
+			}
+		}
+	}
+
+	header.Set("Content-Type", contentType(key, info.ContentType))
+	header.Set("X-Content-Type-Options", "nosniff")
+	if info.ContentEncoding != "" {
+		header.Set("Content-Encoding", info.ContentEncoding)
+	}
+	header.Set("Accept-Ranges", "bytes")
+	if contentLength != "" {
+		header.Set("Content-Length", contentLength)
+	}
+	w.WriteHeader(status)
+	if content == nil {
+		return
+	}
+	if ranged {
+		// the response is cut short if reading fails, or if the object was replaced by a shorter one since stat'ed
+		io.CopyN(w, content, length)
+		return
+	}
+	io.Copy(w, content)
+}
+
+// redirectToDir redirects to the request path with a "/", relative to the request path so that the handler may be
+// mounted with http.StripPrefix
+func redirectToDir(w http.ResponseWriter, r *http.Request) {
+	location := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// quoteETag returns etag as an entity tag of the ETag header, providers don't all return it quoted
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// contentType returns the stored content type of an object, or the type of its file extension
+func contentType(key string, stored string) string {
+	if stored != "" {
+		return stored
+	}
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,279 @@
+package httpserve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+)
+
+// rangeRecorder records the ranges of the objects read, and presigns URLs if presign is set
+type rangeRecorder struct {
+	storage.Wrapper
+	ranges  []string
+	presign bool
+}
+
+func (r *rangeRecorder) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	o := storage.NewGetOptions(opts...)
+	r.ranges = append(r.ranges, fmt.Sprintf("%d+%d", o.Offset, o.Length))
+	return r.Next.GetObject(bucketName, objectKey, opts...)
+}
+
+func (r *rangeRecorder) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	if !r.presign {
+		return r.Next.PresignURL(bucketName, objectKey, method, expiry)
+	}
+	return fmt.Sprintf("https://storage.example.com/%s/%s?method=%s&expiry=%s", bucketName, objectKey, method, expiry), nil
+}
+
+func newStorage(t *testing.T, objects map[string]string) *rangeRecorder {
+	t.Helper()
+	m := memory.NewMemoryClient()
+	if err := m.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for key, content := range objects {
+		if err := m.PutObject("bucket", key, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &rangeRecorder{Wrapper: storage.Wrapper{Next: m}}
+}
+
+func serve(h http.Handler, method string, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestServeObject(t *testing.T) {
+	s := newStorage(t, map[string]string{"site/index.html": "<h1>hello</h1>", "site/data": "0123456789"})
+	h := New(s, "bucket", WithPrefix("site/"))
+
+	w := serve(h, http.MethodGet, "/index.html")
+	if w.Code != http.StatusOK || w.Body.String() != "<h1>hello</h1>" {
+		t.Fatalf("GET /index.html = %d '%s'", w.Code, w.Body)
+	}
+	info, _ := s.StatObject("bucket", "site/index.html")
+	header := w.Result().Header
+	if header.Get("Content-Type") != "text/html; charset=utf-8" || header.Get("ETag") != info.ETag ||
+		header.Get("Content-Length") != "14" || header.Get("Accept-Ranges") != "bytes" ||
+		header.Get("Last-Modified") != info.LastModified.UTC().Format(http.TimeFormat) {
+		t.Errorf("GET /index.html headers %v", header)
+	}
+
+	w = serve(h, http.MethodHead, "/data")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Result().Header.Get("Content-Type") != "application/octet-stream" ||
+		w.Result().Header.Get("Content-Length") != "10" {
+		t.Errorf("HEAD /data = %d, %v", w.Code, w.Result().Header)
+	}
+	if w := serve(h, http.MethodGet, "/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want 404", w.Code)
+	}
+	if w := serve(h, http.MethodPut, "/data"); w.Code != http.StatusMethodNotAllowed || w.Result().Header.Get("Allow") != "GET, HEAD" {
+		t.Errorf("PUT /data = %d, want 405", w.Code)
+	}
+	// without a listing, directories aren't served
+	if w := serve(h, http.MethodGet, "/"); w.Code != http.StatusNotFound {
+		t.Errorf("GET / = %d, want 404", w.Code)
+	}
+	// paths can't leave the prefix
+	if w := serve(h, http.MethodGet, "/../data"); w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("GET /../data = %d '%s'", w.Code, w.Body)
+	}
+}
+
+// misreportedSize stats objects with a wrong size, as a wrapper may
+type misreportedSize struct {
+	storage.Wrapper
+}
+
+func (s misreportedSize) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	info, err := s.Next.StatObject(bucketName, objectKey, opts...)
+	info.Size = 3
+	return info, err
+}
+
+func TestServeReadSize(t *testing.T) {
+	s := newStorage(t, map[string]string{"object": "0123456789"})
+	h := New(misreportedSize{storage.Wrapper{Next: s}}, "bucket")
+
+	w := serve(h, http.MethodGet, "/object")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" || w.Result().Header.Get("Content-Length") != "10" {
+		t.Errorf("GET /object = %d '%s', Content-Length %s, want the whole content with its length", w.Code, w.Body, w.Result().Header.Get("Content-Length"))
+	}
+}
+
+func TestConditional(t *testing.T) {
+	s := newStorage(t, map[string]string{"object": "content"})
+	h := New(s, "bucket")
+	info, _ := s.StatObject("bucket", "object")
+	modified := info.LastModified.UTC().Format(http.TimeFormat)
+	before := info.LastModified.Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	for _, test := range []struct {
+		header []string
+		status int
+	}{
+		{[]string{"If-None-Match", info.ETag}, http.StatusNotModified},
+		{[]string{"If-None-Match", `"other", W/` + info.ETag}, http.StatusNotModified},
+		{[]string{"If-None-Match", "*"}, http.StatusNotModified},
+		{[]string{"If-None-Match", `"other"`}, http.StatusOK},
+		{[]string{"If-Modified-Since", modified}, http.StatusNotModified},
+		{[]string{"If-Modified-Since", before}, http.StatusOK},
+		// If-None-Match takes precedence
+		{[]string{"If-None-Match", `"other"`, "If-Modified-Since", modified}, http.StatusOK},
+		{[]string{"If-Match", info.ETag}, http.StatusOK},
+		{[]string{"If-Match", `"other"`}, http.StatusPreconditionFailed},
+		{[]string{"If-Unmodified-Since", before}, http.StatusPreconditionFailed},
+		{[]string{"If-Unmodified-Since", modified}, http.StatusOK},
+	} {
+		w := serve(h, http.MethodGet, "/object", test.header...)
+		if w.Code != test.status {
+			t.Errorf("GET with %q = %d, want %d", test.header, w.Code, test.status)
+		}
+		if w.Code == http.StatusNotModified && (w.Body.Len() != 0 || w.Result().Header.Get("ETag") != info.ETag) {
+			t.Errorf("GET with %q: 304 with body '%s' and ETag %s", test.header, w.Body, w.Result().Header.Get("ETag"))
+		}
+	}
+	if len(s.ranges) != 5 {
+		t.Errorf("%d objects read, want only the 5 served", len(s.ranges))
+	}
+}
+
+func TestRange(t *testing.T) {
+	s := newStorage(t, map[string]string{"object": "0123456789"})
+	h := New(s, "bucket")
+	info, _ := s.StatObject("bucket", "object")
+
+	for _, test := range []struct {
+		header       []string
+		status       int
+		body         string
+		contentRange string
+		read         string
+	}{
+		{[]string{"Range", "bytes=2-4"}, http.StatusPartialContent, "234", "bytes 2-4/10", "2+3"},
+		{[]string{"Range", "bytes=7-"}, http.StatusPartialContent, "789", "bytes 7-9/10", "7+3"},
+		{[]string{"Range", "bytes=-3"}, http.StatusPartialContent, "789", "bytes 7-9/10", "7+3"},
+		{[]string{"Range", "bytes=8-20"}, http.StatusPartialContent, "89", "bytes 8-9/10", "8+2"},
+		{[]string{"Range", "bytes=-20"}, http.StatusPartialContent, "0123456789", "bytes 0-9/10", "0+10"},
+		{[]string{"Range", "bytes=10-"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */10", ""},
+		{[]string{"Range", "bytes=-0"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */10", ""},
+		// served whole
+		{[]string{"Range", "bytes=0-1,4-5"}, http.StatusOK, "0123456789", "", "0+0"},
+		{[]string{"Range", "bytes=5-2"}, http.StatusOK, "0123456789", "", "0+0"},
+		{[]string{"Range", "lines=1-2"}, http.StatusOK, "0123456789", "", "0+0"},
+		{[]string{"Range", "bytes=2-4", "If-Range", info.ETag}, http.StatusPartialContent, "234", "bytes 2-4/10", "2+3"},
+		{[]string{"Range", "bytes=2-4", "If-Range", `"stale"`}, http.StatusOK, "0123456789", "", "0+0"},
+		{[]string{"Range", "bytes=2-4", "If-Range", info.LastModified.Add(-time.Hour).UTC().Format(http.TimeFormat)}, http.StatusOK, "0123456789", "", "0+0"},
+	} {
+		s.ranges = nil
+		w := serve(h, http.MethodGet, "/object", test.header...)
+		if w.Code != test.status || w.Result().Header.Get("Content-Range") != test.contentRange {
+			t.Errorf("GET with %q = %d, Content-Range '%s', want %d, '%s'", test.header, w.Code, w.Result().Header.Get("Content-Range"), test.status, test.contentRange)
+		}
+		if test.status != http.StatusRequestedRangeNotSatisfiable && w.Body.String() != test.body {
+			t.Errorf("GET with %q = '%s', want '%s'", test.header, w.Body, test.body)
+		}
+		if read := strings.Join(s.ranges, ","); read != test.read {
+			t.Errorf("GET with %q read range %s, want %s", test.header, read, test.read)
+		}
+	}
+
+	w := serve(h, http.MethodHead, "/object", "Range", "bytes=2-4")
+	if w.Code != http.StatusPartialContent || w.Result().Header.Get("Content-Length") != "3" || w.Body.Len() != 0 {
+		t.Errorf("HEAD with Range = %d, %v", w.Code, w.Result().Header)
+	}
+}
+
+func TestDirectoryListing(t *testing.T) {
+	s := newStorage(t, map[string]string{
+		"index.html":      "index",
+		"docs/guide.txt":  "guide",
+		"docs/a/1.txt":    "1",
+		"docs/<b>&c.txt":  "escaped",
+		"docs/with space": "space",
+	})
+	h := New(s, "bucket", WithDirectoryListing())
+
+	w := serve(h, http.MethodGet, "/")
+	if w.Code != http.StatusOK || w.Result().Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("GET / = %d, %v", w.Code, w.Result().Header)
+	}
+	for _, want := range []string{`<a href="./docs/">docs/</a>`, `<a href="./index.html">index.html</a>`, "<td>5</td>"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("listing of / doesn't contain %s:\n%s", want, w.Body)
+		}
+	}
+	if strings.Contains(w.Body.String(), `href="../"`) {
+		t.Errorf("listing of / links to its parent:\n%s", w.Body)
+	}
+
+	w = serve(h, http.MethodGet, "/docs/")
+	for _, want := range []string{
+		`<a href="../">../</a>`,
+		`<a href="./a/">a/</a>`,
+		`<a href="./guide.txt">guide.txt</a>`,
+		`<a href="./%3Cb%3E&amp;c.txt">&lt;b&gt;&amp;c.txt</a>`,
+		`<a href="./with%20space">with space</a>`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("listing of /docs/ doesn't contain %s:\n%s", want, w.Body)
+		}
+	}
+	if strings.Index(w.Body.String(), "./a/") > strings.Index(w.Body.String(), "./guide.txt") {
+		t.Errorf("listing of /docs/ isn't sorted:\n%s", w.Body)
+	}
+
+	if w := serve(h, http.MethodGet, "/docs?x=1"); w.Code != http.StatusMovedPermanently || w.Result().Header.Get("Location") != "docs/?x=1" {
+		t.Errorf("GET /docs = %d, Location %s, want a redirect to docs/", w.Code, w.Result().Header.Get("Location"))
+	}
+	if w := serve(h, http.MethodGet, "/missing/"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing/ = %d, want 404", w.Code)
+	}
+	if w := serve(h, http.MethodGet, "/docs/guide.txt"); w.Code != http.StatusOK || w.Body.String() != "guide" {
+		t.Errorf("GET /docs/guide.txt = %d '%s'", w.Code, w.Body)
+	}
+}
+
+func TestPresignRedirect(t *testing.T) {
+	s := newStorage(t, map[string]string{"object": "content"})
+	s.presign = true
+	h := New(s, "bucket", WithPresignRedirect(time.Minute))
+
+	w := serve(h, http.MethodGet, "/object")
+	if w.Code != http.StatusTemporaryRedirect || w.Result().Header.Get("Location") != "https://storage.example.com/bucket/object?method=GET&expiry=1m0s" {
+		t.Errorf("GET /object = %d, Location %s, want a redirect to the presigned URL", w.Code, w.Result().Header.Get("Location"))
+	}
+	info, _ := s.StatObject("bucket", "object")
+	if w := serve(h, http.MethodGet, "/object", "If-None-Match", info.ETag); w.Code != http.StatusNotModified {
+		t.Errorf("GET /object with current ETag = %d, want 304", w.Code)
+	}
+	if w := serve(h, http.MethodHead, "/object"); w.Code != http.StatusOK {
+		t.Errorf("HEAD /object = %d, want 200", w.Code)
+	}
+	if w := serve(h, http.MethodGet, "/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want 404", w.Code)
+	}
+	if len(s.ranges) != 0 {
+		t.Errorf("objects read through the handler: %v", s.ranges)
+	}
+
+	// storage without presigned URLs serves the content
+	s.presign = false
+	if w := serve(h, http.MethodGet, "/object"); w.Code != http.StatusOK || w.Body.String() != "content" {
+		t.Errorf("GET /object without presigned URLs = %d '%s'", w.Code, w.Body)
+	}
+}
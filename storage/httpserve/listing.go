@@ -0,0 +1,88 @@
+package httpserve
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{.Path}}</title>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Last modified</th></tr>
+{{if ne .Path "/"}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td>{{if .IsDir}}<td></td><td></td>{{else}}<td>{{.Size}}</td><td>{{.Modified.UTC.Format "2006-01-02 15:04:05"}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// listingEntry is an object or prefix of a directory listing
+type listingEntry struct {
+	Name     string
+	Href     string
+	IsDir    bool
+	Size     int64
+	Modified time.Time
+}
+
+// serveListing serves the listing of the objects and prefixes below prefix, for the request path name
+func (h *Handler) serveListing(w http.ResponseWriter, r *http.Request, name string, prefix string) {
+	entries := []listingEntry{}
+	found := false
+	err := h.storage.WalkBucketContent(h.bucketName, func(object storage.ObjectInfo) error {
+		found = true
+		entryName := strings.TrimSuffix(strings.TrimPrefix(object.Key, prefix), "/")
+		// the directory marker, and keys with empty or dot path segments that have no request path
+		if entryName == "" || entryName == "." || entryName == ".." || strings.Contains(entryName, "/") {
+			return nil
+		}
+		entry := listingEntry{Name: entryName, Href: "./" + url.PathEscape(entryName)}
+		if object.IsPrefix {
+			entry.Name, entry.Href, entry.IsDir = entry.Name+"/", entry.Href+"/", true
+		} else {
+			entry.Size, entry.Modified = object.Size, object.LastModified
+		}
+		entries = append(entries, entry)
+		return nil
+	}, storage.WithPrefix(prefix), storage.WithDelimiter("/"))
+	if err != nil {
+		serveError(w, r, err)
+		return
+	}
+	if !found && name != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	listingTemplate.Execute(w, struct {
+		Path    string
+		Entries []listingEntry
+	}{name, entries})
+}
+
+// hasPrefix reports whether there are objects below prefix
+func (h *Handler) hasPrefix(prefix string) (bool, error) {
+	found := false
+	err := h.storage.WalkBucketContent(h.bucketName, func(object storage.ObjectInfo) error {
+		found = true
+		return storage.StopWalk
+	}, storage.WithPrefix(prefix))
+	return found, err
+}
@@ -0,0 +1,522 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+/*
+A file system implementation of the storage interface, for tests and local development.
+
+Buckets are directories in the root directory and objects are files in their bucket directory,
+with '/' in object keys mapped to sub-directories. Content type and encoding, metadata and storage class are
+kept in a "<file>.attrs" side file, so object keys can't end in ".attrs".
+
+Bucket options (location, labels, ...) are accepted and ignored, presigned URLs are not supported
+and restores complete immediately. Encryption options are recorded but not applied, objects stored
+with a customer key can only be read and stat'ed with the same key.
+*/
+
+const (
+	attrsSuffix  = ".attrs"
+	uploadPrefix = ".upload-"
+)
+
+type attributes struct {
+	ContentType     string                 `json:"content_type,omitempty"`
+	ContentEncoding string                 `json:"content_encoding,omitempty"`
+	StorageClass    common.StorageClass    `json:"storage_class,omitempty"`
+	Metadata        map[string]string      `json:"metadata,omitempty"`
+	Encryption      *common.EncryptionInfo `json:"encryption,omitempty"`
+}
+
+type FileClient struct {
+	rootDir string
+}
+
+func NewFileClient(rootDir string) *FileClient {
+	return &FileClient{
+		rootDir: rootDir,
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", common.ErrNotFound, err)
+	}
+	return err
+}
+
+func (fc *FileClient) bucketDir(bucketName string) (string, error) {
+	if bucketName == "" || bucketName == "." || bucketName == ".." || strings.ContainsAny(bucketName, `/\`) {
+		return "", fmt.Errorf("invalid bucket name '%s'", bucketName)
+	}
+	return filepath.Join(fc.rootDir, bucketName), nil
+}
+
+// objectPath returns the file path of an object in an existing bucket
+func (fc *FileClient) objectPath(bucketName string, objectKey string) (string, error) {
+	dir, err := fc.bucketDir(bucketName)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", notFound(err)
+	}
+
+	if objectKey == "" || strings.HasSuffix(objectKey, attrsSuffix) || strings.HasSuffix(objectKey, "/") || strings.Contains(objectKey, `\`) {
+		return "", fmt.Errorf("invalid object key '%s'", objectKey)
+	}
+	for _, segment := range strings.Split(objectKey, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid object key '%s'", objectKey)
+		}
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(objectKey)), nil
+}
+
+func readAttributes(path string) (attributes, error) {
+	attrs := attributes{}
+
+	data, err := os.ReadFile(path + attrsSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return attrs, nil
+	}
+	if err != nil {
+		return attrs, err
+	}
+
+	err = json.Unmarshal(data, &attrs)
+	return attrs, err
+}
+
+// writeAttributes replaces the side file of the object at path
+func writeAttributes(path string, attrs attributes) error {
+	tmpName, err := tempAttributes(path, attrs)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpName)
+	return os.Rename(tmpName, path+attrsSuffix)
+}
+
+// tempAttributes writes attrs to a temporary file next to the object at path, to be renamed to its side file
+func tempAttributes(path string, attrs attributes) (string, error) {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), uploadPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func objectInfo(objectKey string, info fs.FileInfo) common.ObjectInfo {
+	return common.ObjectInfo{
+		Key:          objectKey,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ETag:         fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}
+}
+
+// ################
+// Bucket functions
+// ################
+func (fc *FileClient) CreateBucket(bucketName string, opts ...common.CreateBucketOption) error {
+	dir, err := fc.bucketDir(bucketName)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(fc.rootDir, 0o755)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(dir, 0o755)
+}
+
+func (fc *FileClient) ListBuckets() ([]string, error) {
+	buckets := []string{}
+
+	entries, err := os.ReadDir(fc.rootDir)
+	if err != nil {
+		return buckets, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			buckets = append(buckets, entry.Name())
+		}
+	}
+
+	return buckets, nil
+}
+
+func (fc *FileClient) ListBucketContent(bucketName string) ([]string, error) {
+	objects := []string{}
+	err := fc.WalkBucketContent(bucketName, func(object common.ObjectInfo) error {
+		objects = append(objects, object.Key)
+		return nil
+	})
+	return objects, err
+}
+
+// WalkBucketContent calls fn for the objects in key order.
+func (fc *FileClient) WalkBucketContent(bucketName string, fn common.WalkFunc, opts ...common.ListOption) error {
+	o := common.NewListOptions(opts...)
+
+	dir, err := fc.bucketDir(bucketName)
+	if err != nil {
+		return err
+	}
+
+	infos := []common.ObjectInfo{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return notFound(err)
+		}
+		if d.IsDir() || strings.HasSuffix(path, attrsSuffix) || strings.HasPrefix(d.Name(), uploadPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, o.Prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		infos = append(infos, objectInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// directory order differs from key order: "a/b" < "a.txt" in a directory walk
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range common.Delimit(infos, o.Prefix, o.Delimiter) {
+		err := fn(info)
+		if err == common.StopWalk {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fc *FileClient) DeleteBucket(bucketName string, opts ...common.DeleteBucketOption) error {
+	o := common.NewDeleteBucketOptions(opts...)
+
+	dir, err := fc.bucketDir(bucketName)
+	if err != nil {
+		return err
+	}
+
+	if !o.Force {
+		return notFound(os.Remove(dir))
+	}
+
+	objects, err := fc.ListBucketContent(bucketName)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return err
+	}
+	common.NewDeleteCounter(o.Progress).Add(len(objects))
+
+	return nil
+}
+
+// ########################
+// Bucket content functions
+// ########################
+func (fc *FileClient) StoreObject(bucketName string, objectKey string, fileName string, opts ...common.PutOption) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return fc.PutObject(bucketName, objectKey, f, opts...)
+}
+
+func (fc *FileClient) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...common.GetOption) error {
+	rc, err := fc.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(rc, fileName)
+}
+
+// PutObject writes to a temporary file that replaces the object once complete,
+// so readers never see a partial object.
+func (fc *FileClient) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...common.PutOption) error {
+	o := common.NewPutOptions(opts...)
+	if err := o.Encryption.Validate(); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), uploadPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if o.Progress != nil {
+		reader = common.NewProgressReader(reader, -1, o.Progress)
+	}
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	storageClass := o.StorageClass
+	if storageClass == "" {
+		storageClass = common.StorageClassStandard
+	}
+	attrs := attributes{
+		ContentType:     o.ContentType,
+		ContentEncoding: o.ContentEncoding,
+		StorageClass:    storageClass,
+		Metadata:        o.Metadata,
+	}
+	if !o.Encryption.IsZero() {
+		encryption := o.Encryption.Info()
+		attrs.Encryption = &encryption
+	}
+	attrsName, err := tempAttributes(path, attrs)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(attrsName)
+
+	// the side file is replaced after the object, so that the attributes of a failed upload aren't kept
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return os.Rename(attrsName, path+attrsSuffix)
+}
+
+func (fc *FileClient) GetObject(bucketName string, objectKey string, opts ...common.GetOption) (io.ReadCloser, error) {
+	o := common.NewGetOptions(opts...)
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	fileInfo, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	info, err := fileObjectInfo(path, objectKey, fileInfo, o.Encryption)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("GetObject(%w)", err)
+	}
+	if o.IfNoneMatch != "" && info.ETag == o.IfNoneMatch {
+		f.Close()
+		return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, common.ErrNotModified)
+	}
+	if o.Info != nil {
+		*o.Info = info
+	}
+	if o.Ranged() {
+		if o.Offset > 0 && o.Offset >= fileInfo.Size() {
+			f.Close()
+			return nil, fmt.Errorf("GetObject(%s/%s: range offset %d past the end of the object)", bucketName, objectKey, o.Offset)
+		}
+		if _, err := f.Seek(o.Offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+		rc := common.NewRangeReadCloser(f, 0, o.Length)
+		if o.Progress != nil {
+			return common.NewProgressReadCloser(rc, o.RangeSize(fileInfo.Size()), o.Progress), nil
+		}
+		return rc, nil
+	}
+	if o.Progress != nil {
+		return common.NewProgressReadCloser(f, fileInfo.Size(), o.Progress), nil
+	}
+	return f, nil
+}
+
+func (fc *FileClient) StatObject(bucketName string, objectKey string, opts ...common.GetOption) (common.ObjectInfo, error) {
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return common.ObjectInfo{}, err
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return common.ObjectInfo{}, notFound(err)
+	}
+	info, err := fileObjectInfo(path, objectKey, fileInfo, common.NewGetOptions(opts...).Encryption)
+	if err != nil {
+		return common.ObjectInfo{}, fmt.Errorf("StatObject(%w)", err)
+	}
+	return info, nil
+}
+
+// fileObjectInfo returns the details of the object stored at path, if it may be read with the customer key of e
+func fileObjectInfo(path string, objectKey string, fileInfo fs.FileInfo, e common.EncryptionOptions) (common.ObjectInfo, error) {
+	attrs, err := readAttributes(path)
+	if err != nil {
+		return common.ObjectInfo{}, err
+	}
+
+	info := objectInfo(objectKey, fileInfo)
+	if attrs.Encryption != nil {
+		if err := attrs.Encryption.CheckCustomerKey(e); err != nil {
+			return common.ObjectInfo{}, err
+		}
+		info.Encryption = *attrs.Encryption
+	}
+	info.ContentType = attrs.ContentType
+	info.ContentEncoding = attrs.ContentEncoding
+	info.StorageClass = attrs.StorageClass
+	info.Metadata = attrs.Metadata
+	if info.Metadata == nil {
+		info.Metadata = map[string]string{}
+	}
+	return info, nil
+}
+
+func (fc *FileClient) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	return "", fmt.Errorf("PresignURL(%w)", common.ErrNotSupported)
+}
+
+func (fc *FileClient) DeleteObject(bucketName string, objectKeys []string) error {
+	_, err := fc.DeleteObjects(bucketName, objectKeys)
+	return err
+}
+
+// DeleteObjects removes the object files, and any directories left empty by it.
+func (fc *FileClient) DeleteObjects(bucketName string, objectKeys []string) (*common.DeleteReport, error) {
+	report := common.NewDeleteReport(objectKeys)
+
+	for i, key := range objectKeys {
+		report.Results[i].Err = fc.deleteObject(bucketName, key)
+	}
+
+	return report, report.Err()
+}
+
+func (fc *FileClient) deleteObject(bucketName string, objectKey string) error {
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return notFound(err)
+	}
+	err = os.Remove(path + attrsSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	bucketDir, _ := fc.bucketDir(bucketName)
+	for dir := filepath.Dir(path); dir != bucketDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // not empty
+		}
+	}
+
+	return nil
+}
+
+func (fc *FileClient) SetStorageClass(bucketName string, objectKey string, class common.StorageClass, opts ...common.GetOption) error {
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return notFound(err)
+	}
+
+	attrs, err := readAttributes(path)
+	if err != nil {
+		return err
+	}
+	if attrs.Encryption != nil {
+		if err := attrs.Encryption.CheckCustomerKey(common.NewGetOptions(opts...).Encryption); err != nil {
+			return err
+		}
+	}
+	attrs.StorageClass = class
+	return writeAttributes(path, attrs)
+}
+
+// RestoreObject is a no-op, objects are always available.
+func (fc *FileClient) RestoreObject(bucketName string, objectKey string, opts ...common.RestoreOption) error {
+	return fc.exists(bucketName, objectKey)
+}
+
+func (fc *FileClient) RestoreStatus(bucketName string, objectKey string) (common.RestoreStatus, error) {
+	err := fc.exists(bucketName, objectKey)
+	if err != nil {
+		return common.RestoreStatus{}, err
+	}
+	return common.RestoreStatus{Available: true}, nil
+}
+
+func (fc *FileClient) exists(bucketName string, objectKey string) error {
+	path, err := fc.objectPath(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(path)
+	return notFound(err)
+}
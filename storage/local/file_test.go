@@ -0,0 +1,76 @@
+package local_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/local"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return local.NewFileClient(t.TempDir())
+	})
+}
+
+func TestCustomerKey(t *testing.T) {
+	s := local.NewFileClient(t.TempDir())
+	if err := s.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	encryption := storage.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+	err := s.PutObject("bucket", "object", strings.NewReader("data"), storage.WithEncryption(encryption))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetObject("bucket", "object"); !errors.Is(err, storage.ErrCustomerKey) {
+		t.Errorf("GetObject() without key = %v, want ErrCustomerKey", err)
+	}
+	if _, err := s.StatObject("bucket", "object", storage.WithCustomerKey(bytes.Repeat([]byte{8}, 32))); !errors.Is(err, storage.ErrCustomerKey) {
+		t.Errorf("StatObject() with another key = %v, want ErrCustomerKey", err)
+	}
+
+	info, err := s.StatObject("bucket", "object", storage.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Encryption != encryption.Info() {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, encryption.Info())
+	}
+	rc, err := s.GetObject("bucket", "object", storage.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+}
+
+func TestDownloadProgress(t *testing.T) {
+	s := local.NewFileClient(t.TempDir())
+	if err := s.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutObject("bucket", "object", strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+
+	last := storage.Progress{}
+	rc, err := s.GetObject("bucket", "object", storage.WithDownloadProgress(func(p storage.Progress) {
+		last = p
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	if last.Bytes != 10 || last.Total != 10 {
+		t.Errorf("last progress = %+v, want 10 of 10 bytes", last)
+	}
+}
@@ -0,0 +1,36 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func init() {
+	storage.Register(storage.TypeLocal, newStorage)
+	storage.RegisterScheme("file", openURL)
+}
+
+func newStorage(opts storage.Options) (storage.Storage, error) {
+	return NewFileClient(opts.Local_RootDir), nil
+}
+
+// openURL opens file:///path/to/bucket, the bucket directory in root directory /path/to
+func openURL(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+	if err := storage.CheckQuery(u); err != nil {
+		return nil, "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, "", fmt.Errorf("file URLs must be local, as in file:///path/to/bucket")
+	}
+
+	dir := filepath.Clean(filepath.FromSlash(u.Path))
+	root, bucketName := filepath.Split(dir)
+	if bucketName == "" {
+		return nil, "", fmt.Errorf("bucket directory must be provided, as in file:///path/to/bucket")
+	}
+	return NewFileClient(root), bucketName, nil
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+type loggingOptions struct {
+	levels     map[Operation]slog.Level
+	level      slog.Level
+	errorLevel slog.Level
+}
+
+type LogOption func(*loggingOptions)
+
+// WithLogLevel logs successful calls of ops (all operations if none are given) at level, by default slog.LevelDebug.
+func WithLogLevel(level slog.Level, ops ...Operation) LogOption {
+	return func(o *loggingOptions) {
+		if len(ops) == 0 {
+			o.level = level
+		}
+		for _, op := range ops {
+			o.levels[op] = level
+		}
+	}
+}
+
+// WithErrorLogLevel logs failed calls at level, by default slog.LevelError.
+func WithErrorLogLevel(level slog.Level) LogOption {
+	return func(o *loggingOptions) {
+		o.errorLevel = level
+	}
+}
+
+// Logging returns a Middleware logging every call to logger, with the operation (as message), provider, bucket, key,
+// duration, bytes transferred and error. Signatures and credentials in presigned URLs and errors are redacted.
+// Options.Logger adds it to created storages with the default levels.
+func Logging(logger *slog.Logger, opts ...LogOption) Middleware {
+	o := &loggingOptions{
+		levels:     map[Operation]slog.Level{},
+		level:      slog.LevelDebug,
+		errorLevel: slog.LevelError,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next Storage) Storage {
+		return &loggingStorage{
+			Wrapper: Wrapper{Next: next},
+			logger:  logger.With(slog.String("provider", Provider(next))),
+			options: o,
+		}
+	}
+}
+
+// logRetry returns a RetryPolicy.OnRetry function logging retries at warning level
+func logRetry(logger *slog.Logger) func(RetryEvent) {
+	return func(e RetryEvent) {
+		logger.Warn("retry", slog.Int("attempt", e.Attempt), slog.Duration("delay", e.Delay),
+			slog.String("error", Redact(e.Err.Error())))
+	}
+}
+
+// secretParams matches the values of signatures and credentials in URLs and connection strings
+var secretParams = regexp.MustCompile(`(?i)\b(X-Amz-Signature|X-Amz-Credential|X-Amz-Security-Token|X-Goog-Signature|X-Goog-Credential|sig|AccountKey|SharedAccessSignature)=[^&;\s"']+`)
+
+// Redact replaces the values of signatures and credentials in s (a presigned URL, connection string or error message)
+// with REDACTED.
+func Redact(s string) string {
+	return secretParams.ReplaceAllString(s, "${1}=REDACTED")
+}
+
+// loggingStorage logs the calls to the wrapped storage
+type loggingStorage struct {
+	Wrapper
+	logger  *slog.Logger
+	options *loggingOptions
+}
+
+// log logs a call of op started at start, with the bytes transferred if not negative
+func (l *loggingStorage) log(op Operation, start time.Time, err error, bytes int64, attrs ...slog.Attr) {
+	level, ok := l.options.levels[op]
+	if !ok {
+		level = l.options.level
+	}
+	// unchanged objects of conditional reads are an expected outcome
+	if err != nil && !errors.Is(err, ErrNotModified) {
+		level = l.options.errorLevel
+	}
+
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	if bytes >= 0 {
+		attrs = append(attrs, slog.Int64("bytes", bytes))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", Redact(err.Error())))
+	}
+	l.logger.LogAttrs(ctx, level, string(op), attrs...)
+}
+
+func bucketAttr(bucketName string) slog.Attr {
+	return slog.String("bucket", bucketName)
+}
+
+func keyAttr(objectKey string) slog.Attr {
+	return slog.String("key", objectKey)
+}
+
+func (l *loggingStorage) CreateBucket(bucketName string, opts ...CreateBucketOption) error {
+	start := time.Now()
+	err := l.Next.CreateBucket(bucketName, opts...)
+	l.log(OpCreateBucket, start, err, -1, bucketAttr(bucketName))
+	return err
+}
+
+func (l *loggingStorage) ListBuckets() ([]string, error) {
+	start := time.Now()
+	buckets, err := l.Next.ListBuckets()
+	l.log(OpListBuckets, start, err, -1, slog.Int("count", len(buckets)))
+	return buckets, err
+}
+
+func (l *loggingStorage) ListBucketContent(bucketName string) ([]string, error) {
+	start := time.Now()
+	objects, err := l.Next.ListBucketContent(bucketName)
+	l.log(OpListBucketContent, start, err, -1, bucketAttr(bucketName), slog.Int("count", len(objects)))
+	return objects, err
+}
+
+func (l *loggingStorage) WalkBucketContent(bucketName string, fn WalkFunc, opts ...ListOption) error {
+	start := time.Now()
+	err := l.Next.WalkBucketContent(bucketName, fn, opts...)
+	l.log(OpWalkBucketContent, start, err, -1, bucketAttr(bucketName))
+	return err
+}
+
+func (l *loggingStorage) DeleteBucket(bucketName string, opts ...DeleteBucketOption) error {
+	start := time.Now()
+	err := l.Next.DeleteBucket(bucketName, opts...)
+	l.log(OpDeleteBucket, start, err, -1, bucketAttr(bucketName))
+	return err
+}
+
+func (l *loggingStorage) StoreObject(bucketName string, objectKey string, fileName string, opts ...PutOption) error {
+	start := time.Now()
+	err := l.Next.StoreObject(bucketName, objectKey, fileName, opts...)
+	l.log(OpStoreObject, start, err, FileTransferSize(err, fileName), bucketAttr(bucketName), keyAttr(objectKey), slog.String("file", fileName))
+	return err
+}
+
+func (l *loggingStorage) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...GetOption) error {
+	start := time.Now()
+	err := l.Next.RetrieveObject(bucketName, objectKey, fileName, opts...)
+	l.log(OpRetrieveObject, start, err, FileTransferSize(err, fileName), bucketAttr(bucketName), keyAttr(objectKey), slog.String("file", fileName))
+	return err
+}
+
+func (l *loggingStorage) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...PutOption) error {
+	start := time.Now()
+	reader, count := CountReader(reader)
+	err := l.Next.PutObject(bucketName, objectKey, reader, opts...)
+	l.log(OpPutObject, start, err, count(), bucketAttr(bucketName), keyAttr(objectKey))
+	return err
+}
+
+// GetObject is logged when the returned reader is closed, with the bytes read.
+func (l *loggingStorage) GetObject(bucketName string, objectKey string, opts ...GetOption) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := l.Next.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		l.log(OpGetObject, start, err, -1, bucketAttr(bucketName), keyAttr(objectKey))
+		return nil, err
+	}
+	return &loggingReader{ReadCloser: rc, done: func(n int64, err error) {
+		l.log(OpGetObject, start, err, n, bucketAttr(bucketName), keyAttr(objectKey))
+	}}, nil
+}
+
+func (l *loggingStorage) StatObject(bucketName string, objectKey string, opts ...GetOption) (ObjectInfo, error) {
+	start := time.Now()
+	info, err := l.Next.StatObject(bucketName, objectKey, opts...)
+	l.log(OpStatObject, start, err, -1, bucketAttr(bucketName), keyAttr(objectKey))
+	return info, err
+}
+
+// PresignURL logs the URL without its signature.
+func (l *loggingStorage) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	start := time.Now()
+	presigned, err := l.Next.PresignURL(bucketName, objectKey, method, expiry)
+	attrs := []slog.Attr{bucketAttr(bucketName), keyAttr(objectKey), slog.String("method", method), slog.Duration("expiry", expiry)}
+	if err == nil {
+		attrs = append(attrs, slog.String("url", redactURL(presigned)))
+	}
+	l.log(OpPresignURL, start, err, -1, attrs...)
+	return presigned, err
+}
+
+func (l *loggingStorage) DeleteObject(bucketName string, objectKeys []string) error {
+	start := time.Now()
+	err := l.Next.DeleteObject(bucketName, objectKeys)
+	l.log(OpDeleteObject, start, err, -1, bucketAttr(bucketName), slog.Int("keys", len(objectKeys)))
+	return err
+}
+
+// DeleteObjects logs keys that failed to delete as an error of the call.
+func (l *loggingStorage) DeleteObjects(bucketName string, objectKeys []string) (*DeleteReport, error) {
+	start := time.Now()
+	report, err := l.Next.DeleteObjects(bucketName, objectKeys)
+	logErr := err
+	if err == nil && report != nil {
+		logErr = report.Err()
+	}
+	l.log(OpDeleteObjects, start, logErr, -1, bucketAttr(bucketName), slog.Int("keys", len(objectKeys)))
+	return report, err
+}
+
+func (l *loggingStorage) SetStorageClass(bucketName string, objectKey string, class StorageClass, opts ...GetOption) error {
+	start := time.Now()
+	err := l.Next.SetStorageClass(bucketName, objectKey, class, opts...)
+	l.log(OpSetStorageClass, start, err, -1, bucketAttr(bucketName), keyAttr(objectKey), slog.String("class", string(class)))
+	return err
+}
+
+func (l *loggingStorage) RestoreObject(bucketName string, objectKey string, opts ...RestoreOption) error {
+	start := time.Now()
+	err := l.Next.RestoreObject(bucketName, objectKey, opts...)
+	l.log(OpRestoreObject, start, err, -1, bucketAttr(bucketName), keyAttr(objectKey))
+	return err
+}
+
+func (l *loggingStorage) RestoreStatus(bucketName string, objectKey string) (RestoreStatus, error) {
+	start := time.Now()
+	status, err := l.Next.RestoreStatus(bucketName, objectKey)
+	l.log(OpRestoreStatus, start, err, -1, bucketAttr(bucketName), keyAttr(objectKey))
+	return status, err
+}
+
+// redactURL drops the query of a presigned URL, which holds the signature (and for SAS tokens, the credential)
+func redactURL(presigned string) string {
+	u, err := url.Parse(presigned)
+	if err != nil {
+		return "REDACTED"
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "REDACTED"
+	}
+	u.User = nil
+	return u.String()
+}
+
+// loggingReader calls done when closed, with the bytes read and the first read error
+type loggingReader struct {
+	io.ReadCloser
+	done func(n int64, err error)
+	n    int64
+	err  error
+	once sync.Once
+}
+
+func (r *loggingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *loggingReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.done(r.n, r.err)
+	})
+	return err
+}
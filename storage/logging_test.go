@@ -0,0 +1,154 @@
+package storage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+)
+
+// presigning returns a fixed presigned URL
+type presigning struct {
+	storage.Storage
+}
+
+func (presigning) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	return "https://bucket.s3.amazonaws.com/key?X-Amz-Credential=AKIA%2F&X-Amz-Signature=abc123", nil
+}
+
+// logLines decodes the JSON log lines in buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	lines := []map[string]any{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		line := map[string]any{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s, err := storage.New(storage.Options{StorageType: storage.TypeMemory, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.CreateBucket("bucket")
+	s.PutObject("bucket", "key", strings.NewReader("data"))
+	rc, _ := s.GetObject("bucket", "key")
+	io.Copy(io.Discard, rc)
+	rc.Close()
+	s.StatObject("bucket", "missing")
+
+	lines := logLines(t, buf)
+	if len(lines) != 4 {
+		t.Fatalf("%d lines logged, want 4: %v", len(lines), lines)
+	}
+
+	put := lines[1]
+	if put["msg"] != "PutObject" || put["level"] != "DEBUG" || put["provider"] != "memory" || put["bucket"] != "bucket" ||
+		put["key"] != "key" || put["bytes"] != 4.0 || put["duration"] == nil {
+		t.Errorf("PutObject logged as %v", put)
+	}
+	if get := lines[2]; get["msg"] != "GetObject" || get["bytes"] != 4.0 {
+		t.Errorf("GetObject logged as %v", get)
+	}
+	if stat := lines[3]; stat["level"] != "ERROR" || !strings.Contains(stat["error"].(string), "not found") {
+		t.Errorf("StatObject logged as %v", stat)
+	}
+}
+
+// sizeRecording records the size of the readers uploaded, as providers see it
+type sizeRecording struct {
+	storage.Wrapper
+	size int64
+}
+
+func (s *sizeRecording) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	s.size = storage.ReaderSize(reader)
+	return s.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func TestLoggingReaderSize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	recording := &sizeRecording{Wrapper: storage.Wrapper{Next: memory.NewMemoryClient()}}
+	s := storage.Logging(slog.New(slog.NewJSONHandler(buf, nil)), storage.WithLogLevel(slog.LevelInfo))(recording)
+
+	s.CreateBucket("bucket")
+	s.PutObject("bucket", "key", strings.NewReader("data"))
+	if recording.size != 4 {
+		t.Errorf("uploaded reader size = %d, want the 4 bytes of the reader", recording.size)
+	}
+	if lines := logLines(t, buf); len(lines) != 2 || lines[1]["bytes"] != 4.0 {
+		t.Errorf("logged %v, want PutObject with 4 bytes", lines)
+	}
+}
+
+func TestLoggingLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	s := storage.Logging(logger,
+		storage.WithLogLevel(slog.LevelInfo, storage.OpPutObject),
+		storage.WithErrorLogLevel(slog.LevelWarn),
+	)(memory.NewMemoryClient())
+
+	s.CreateBucket("bucket")
+	s.PutObject("bucket", "key", strings.NewReader("data"))
+	s.StatObject("bucket", "missing")
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 || lines[0]["msg"] != "PutObject" || lines[1]["level"] != "WARN" {
+		t.Errorf("logged %v, want PutObject at info and StatObject at warning level", lines)
+	}
+}
+
+func TestLoggingRedaction(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	s := storage.Logging(logger, storage.WithLogLevel(slog.LevelInfo))(presigning{})
+
+	url, err := s.PresignURL("bucket", "key", "GET", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(url, "abc123") {
+		t.Errorf("PresignURL = '%s', want the signed URL", url)
+	}
+
+	logger.Info("options", "credentials", storage.Credentials{AWS_SecretAccessKey: "secret"})
+	logged := buf.String()
+	if strings.Contains(logged, "abc123") || strings.Contains(logged, "AKIA") || strings.Contains(logged, "secret\"") {
+		t.Errorf("secrets logged: %s", logged)
+	}
+	if !strings.Contains(logged, "https://bucket.s3.amazonaws.com/key?REDACTED") {
+		t.Errorf("presigned URL not logged redacted: %s", logged)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"https://a.blob.core.windows.net/c/k?sv=2020&sig=abc%3D&se=2024", "https://a.blob.core.windows.net/c/k?sv=2020&sig=REDACTED&se=2024"},
+		{"DefaultEndpointsProtocol=https;AccountName=a;AccountKey=abc==;EndpointSuffix=core", "DefaultEndpointsProtocol=https;AccountName=a;AccountKey=REDACTED;EndpointSuffix=core"},
+		{`Get "https://b.storage.googleapis.com/k?X-Goog-Signature=abc": EOF`, `Get "https://b.storage.googleapis.com/k?X-Goog-Signature=REDACTED": EOF`},
+		{"object not found", "object not found"},
+	}
+	for _, test := range tests {
+		if got := storage.Redact(test.s); got != test.want {
+			t.Errorf("Redact('%s') = '%s', want '%s'", test.s, got, test.want)
+		}
+	}
+}
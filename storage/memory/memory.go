@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage/common"
+)
+
+/*
+An in-memory implementation of the storage interface, for tests and local development.
+Content is lost when the process exits.
+
+Bucket options (location, labels, ...) are accepted and ignored, presigned URLs are not supported
+and restores complete immediately. Encryption options are recorded but not applied, objects stored
+with a customer key can only be read and stat'ed with the same key.
+*/
+
+type object struct {
+	data []byte
+	info common.ObjectInfo
+}
+
+type MemoryClient struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]*object
+}
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		buckets: map[string]map[string]*object{},
+	}
+}
+
+func bucketNotFound(bucketName string) error {
+	return fmt.Errorf("bucket '%s': %w", bucketName, common.ErrNotFound)
+}
+
+func objectNotFound(bucketName string, objectKey string) error {
+	return fmt.Errorf("object '%s/%s': %w", bucketName, objectKey, common.ErrNotFound)
+}
+
+// bucket returns the objects of an existing bucket; the caller must hold the lock
+func (m *MemoryClient) bucket(bucketName string) (map[string]*object, error) {
+	objects, ok := m.buckets[bucketName]
+	if !ok {
+		return nil, bucketNotFound(bucketName)
+	}
+	return objects, nil
+}
+
+// object returns an existing object; the caller must hold the lock
+func (m *MemoryClient) object(bucketName string, objectKey string) (*object, error) {
+	objects, err := m.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := objects[objectKey]
+	if !ok {
+		return nil, objectNotFound(bucketName, objectKey)
+	}
+	return obj, nil
+}
+
+// ################
+// Bucket functions
+// ################
+func (m *MemoryClient) CreateBucket(bucketName string, opts ...common.CreateBucketOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.buckets[bucketName]; ok {
+		return fmt.Errorf("bucket '%s' already exists", bucketName)
+	}
+	m.buckets[bucketName] = map[string]*object{}
+	return nil
+}
+
+func (m *MemoryClient) ListBuckets() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	buckets := []string{}
+	for name := range m.buckets {
+		buckets = append(buckets, name)
+	}
+	sort.Strings(buckets)
+
+	return buckets, nil
+}
+
+func (m *MemoryClient) ListBucketContent(bucketName string) ([]string, error) {
+	objects := []string{}
+	err := m.WalkBucketContent(bucketName, func(object common.ObjectInfo) error {
+		objects = append(objects, object.Key)
+		return nil
+	})
+	return objects, err
+}
+
+// WalkBucketContent calls fn for the objects in key order, on a snapshot of the bucket content.
+func (m *MemoryClient) WalkBucketContent(bucketName string, fn common.WalkFunc, opts ...common.ListOption) error {
+	o := common.NewListOptions(opts...)
+
+	m.mu.RLock()
+	objects, err := m.bucket(bucketName)
+	if err != nil {
+		m.mu.RUnlock()
+		return err
+	}
+	infos := []common.ObjectInfo{}
+	for key, obj := range objects {
+		if strings.HasPrefix(key, o.Prefix) {
+			infos = append(infos, obj.info)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range common.Delimit(infos, o.Prefix, o.Delimiter) {
+		err := fn(info)
+		if err == common.StopWalk {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryClient) DeleteBucket(bucketName string, opts ...common.DeleteBucketOption) error {
+	o := common.NewDeleteBucketOptions(opts...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if len(objects) > 0 && !o.Force {
+		return fmt.Errorf("bucket '%s' is not empty", bucketName)
+	}
+
+	common.NewDeleteCounter(o.Progress).Add(len(objects))
+	delete(m.buckets, bucketName)
+	return nil
+}
+
+// ########################
+// Bucket content functions
+// ########################
+func (m *MemoryClient) StoreObject(bucketName string, objectKey string, fileName string, opts ...common.PutOption) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return m.PutObject(bucketName, objectKey, f, opts...)
+}
+
+func (m *MemoryClient) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...common.GetOption) error {
+	rc, err := m.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		return err
+	}
+	return common.WriteFile(rc, fileName)
+}
+
+func (m *MemoryClient) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...common.PutOption) error {
+	o := common.NewPutOptions(opts...)
+	if err := o.Encryption.Validate(); err != nil {
+		return fmt.Errorf("PutObject(%w)", err)
+	}
+	if o.Progress != nil {
+		reader = common.NewProgressReader(reader, -1, o.Progress)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	sum := md5.Sum(data)
+	obj := &object{
+		data: data,
+		info: common.ObjectInfo{
+			Key:             objectKey,
+			Size:            int64(len(data)),
+			LastModified:    time.Now(),
+			ETag:            `"` + hex.EncodeToString(sum[:]) + `"`,
+			ContentType:     o.ContentType,
+			ContentEncoding: o.ContentEncoding,
+			StorageClass:    o.StorageClass,
+			Metadata:        o.Metadata,
+			Encryption:      o.Encryption.Info(),
+		},
+	}
+	if obj.info.StorageClass == "" {
+		obj.info.StorageClass = common.StorageClassStandard
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objects, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	objects[objectKey] = obj
+	return nil
+}
+
+func (m *MemoryClient) GetObject(bucketName string, objectKey string, opts ...common.GetOption) (io.ReadCloser, error) {
+	o := common.NewGetOptions(opts...)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, err := m.object(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := obj.info.Encryption.CheckCustomerKey(o.Encryption); err != nil {
+		return nil, fmt.Errorf("GetObject(%w)", err)
+	}
+	if o.IfNoneMatch != "" && obj.info.ETag == o.IfNoneMatch {
+		return nil, fmt.Errorf("GetObject(%s/%s: %w)", bucketName, objectKey, common.ErrNotModified)
+	}
+	if o.Info != nil {
+		*o.Info = obj.info
+		o.Info.Metadata = copyMetadata(obj.info.Metadata)
+	}
+
+	data := obj.data
+	if o.Ranged() {
+		if o.Offset > 0 && o.Offset >= int64(len(data)) {
+			return nil, fmt.Errorf("GetObject(%s/%s: range offset %d past the end of the object)", bucketName, objectKey, o.Offset)
+		}
+		data = data[o.Offset : o.Offset+o.RangeSize(int64(len(data)))]
+	}
+
+	// data is never modified in place, a new object replaces it
+	rc := io.NopCloser(bytes.NewReader(data))
+	if o.Progress != nil {
+		return common.NewProgressReadCloser(rc, int64(len(data)), o.Progress), nil
+	}
+	return rc, nil
+}
+
+func (m *MemoryClient) StatObject(bucketName string, objectKey string, opts ...common.GetOption) (common.ObjectInfo, error) {
+	o := common.NewGetOptions(opts...)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, err := m.object(bucketName, objectKey)
+	if err != nil {
+		return common.ObjectInfo{}, err
+	}
+	if err := obj.info.Encryption.CheckCustomerKey(o.Encryption); err != nil {
+		return common.ObjectInfo{}, fmt.Errorf("StatObject(%w)", err)
+	}
+
+	info := obj.info
+	info.Metadata = copyMetadata(obj.info.Metadata)
+	return info, nil
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	copied := map[string]string{}
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (m *MemoryClient) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	return "", fmt.Errorf("PresignURL(%w)", common.ErrNotSupported)
+}
+
+func (m *MemoryClient) DeleteObject(bucketName string, objectKeys []string) error {
+	_, err := m.DeleteObjects(bucketName, objectKeys)
+	return err
+}
+
+func (m *MemoryClient) DeleteObjects(bucketName string, objectKeys []string) (*common.DeleteReport, error) {
+	report := common.NewDeleteReport(objectKeys)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, key := range objectKeys {
+		_, err := m.object(bucketName, key)
+		if err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		delete(m.buckets[bucketName], key)
+	}
+
+	return report, report.Err()
+}
+
+func (m *MemoryClient) SetStorageClass(bucketName string, objectKey string, class common.StorageClass, opts ...common.GetOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, err := m.object(bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	if err := obj.info.Encryption.CheckCustomerKey(common.NewGetOptions(opts...).Encryption); err != nil {
+		return err
+	}
+	obj.info.StorageClass = class
+	return nil
+}
+
+// RestoreObject is a no-op, objects are always available.
+func (m *MemoryClient) RestoreObject(bucketName string, objectKey string, opts ...common.RestoreOption) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, err := m.object(bucketName, objectKey)
+	return err
+}
+
+func (m *MemoryClient) RestoreStatus(bucketName string, objectKey string) (common.RestoreStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, err := m.object(bucketName, objectKey)
+	if err != nil {
+		return common.RestoreStatus{}, err
+	}
+	return common.RestoreStatus{Available: true}, nil
+}
@@ -0,0 +1,50 @@
+package memory_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return memory.NewMemoryClient()
+	})
+}
+
+func TestCustomerKey(t *testing.T) {
+	s := memory.NewMemoryClient()
+	if err := s.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	encryption := storage.EncryptionOptions{CustomerKey: bytes.Repeat([]byte{7}, 32)}
+	err := s.PutObject("bucket", "object", strings.NewReader("data"), storage.WithEncryption(encryption))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetObject("bucket", "object"); !errors.Is(err, storage.ErrCustomerKey) {
+		t.Errorf("GetObject() without key = %v, want ErrCustomerKey", err)
+	}
+	if _, err := s.StatObject("bucket", "object", storage.WithCustomerKey(bytes.Repeat([]byte{8}, 32))); !errors.Is(err, storage.ErrCustomerKey) {
+		t.Errorf("StatObject() with another key = %v, want ErrCustomerKey", err)
+	}
+
+	info, err := s.StatObject("bucket", "object", storage.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Encryption != encryption.Info() {
+		t.Errorf("StatObject().Encryption = %+v, want %+v", info.Encryption, encryption.Info())
+	}
+	rc, err := s.GetObject("bucket", "object", storage.WithCustomerKey(encryption.CustomerKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func init() {
+	storage.Register(storage.TypeMemory, newStorage)
+	storage.RegisterScheme("mem", openURL)
+}
+
+func newStorage(opts storage.Options) (storage.Storage, error) {
+	return NewMemoryClient(), nil
+}
+
+// openURL opens mem://bucket, new in-memory storage with the bucket (default "default") already created
+func openURL(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+	if err := storage.CheckQuery(u); err != nil {
+		return nil, "", err
+	}
+
+	bucketName := u.Host
+	if bucketName == "" {
+		bucketName = "default"
+	}
+
+	m := NewMemoryClient()
+	if err := m.CreateBucket(bucketName); err != nil {
+		return nil, "", err
+	}
+	return m, bucketName, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"io"
+	"path"
+	"reflect"
+	"time"
+)
+
+// Middleware adds behavior (logging, metrics, retries, caching, encryption, ...) to the calls of a Storage,
+// by returning a Storage that wraps it.
+type Middleware func(Storage) Storage
+
+// Chain wraps s in the middlewares, the first being the outermost: with Chain(s, a, b),
+// calls pass through a, then b, then reach s.
+func Chain(s Storage, middlewares ...Middleware) Storage {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
+
+// Unwrap returns the Storage wrapped by s, or nil if s doesn't wrap another Storage.
+func Unwrap(s Storage) Storage {
+	if w, ok := s.(interface{ Unwrap() Storage }); ok {
+		return w.Unwrap()
+	}
+	return nil
+}
+
+// Provider returns the package name of the innermost storage wrapped by s (aws, gcp, azure, local, memory),
+// for wrappers that label calls by provider.
+func Provider(s Storage) string {
+	for inner := Unwrap(s); inner != nil; inner = Unwrap(s) {
+		s = inner
+	}
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return "unknown"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
+}
+
+// Wrapper forwards all calls to Next. Middlewares embed it and override only the methods they change:
+//
+//	type counting struct {
+//		storage.Wrapper
+//		puts atomic.Int64
+//	}
+//
+//	func (c *counting) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+//		c.puts.Add(1)
+//		return c.Next.PutObject(bucketName, objectKey, reader, opts...)
+//	}
+type Wrapper struct {
+	Next Storage
+}
+
+var _ Storage = Wrapper{}
+
+// Unwrap returns the wrapped Storage.
+func (w Wrapper) Unwrap() Storage {
+	return w.Next
+}
+
+func (w Wrapper) CreateBucket(bucketName string, opts ...CreateBucketOption) error {
+	return w.Next.CreateBucket(bucketName, opts...)
+}
+
+func (w Wrapper) ListBuckets() ([]string, error) {
+	return w.Next.ListBuckets()
+}
+
+func (w Wrapper) ListBucketContent(bucketName string) ([]string, error) {
+	return w.Next.ListBucketContent(bucketName)
+}
+
+func (w Wrapper) WalkBucketContent(bucketName string, fn WalkFunc, opts ...ListOption) error {
+	return w.Next.WalkBucketContent(bucketName, fn, opts...)
+}
+
+func (w Wrapper) DeleteBucket(bucketName string, opts ...DeleteBucketOption) error {
+	return w.Next.DeleteBucket(bucketName, opts...)
+}
+
+func (w Wrapper) StoreObject(bucketName string, objectKey string, fileName string, opts ...PutOption) error {
+	return w.Next.StoreObject(bucketName, objectKey, fileName, opts...)
+}
+
+func (w Wrapper) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...GetOption) error {
+	return w.Next.RetrieveObject(bucketName, objectKey, fileName, opts...)
+}
+
+func (w Wrapper) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...PutOption) error {
+	return w.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func (w Wrapper) GetObject(bucketName string, objectKey string, opts ...GetOption) (io.ReadCloser, error) {
+	return w.Next.GetObject(bucketName, objectKey, opts...)
+}
+
+func (w Wrapper) StatObject(bucketName string, objectKey string, opts ...GetOption) (ObjectInfo, error) {
+	return w.Next.StatObject(bucketName, objectKey, opts...)
+}
+
+func (w Wrapper) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	return w.Next.PresignURL(bucketName, objectKey, method, expiry)
+}
+
+func (w Wrapper) DeleteObject(bucketName string, objectKeys []string) error {
+	return w.Next.DeleteObject(bucketName, objectKeys)
+}
+
+func (w Wrapper) DeleteObjects(bucketName string, objectKeys []string) (*DeleteReport, error) {
+	return w.Next.DeleteObjects(bucketName, objectKeys)
+}
+
+func (w Wrapper) SetStorageClass(bucketName string, objectKey string, class StorageClass, opts ...GetOption) error {
+	return w.Next.SetStorageClass(bucketName, objectKey, class, opts...)
+}
+
+func (w Wrapper) RestoreObject(bucketName string, objectKey string, opts ...RestoreOption) error {
+	return w.Next.RestoreObject(bucketName, objectKey, opts...)
+}
+
+func (w Wrapper) RestoreStatus(bucketName string, objectKey string) (RestoreStatus, error) {
+	return w.Next.RestoreStatus(bucketName, objectKey)
+}
@@ -0,0 +1,84 @@
+package storage_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+// tracing records the order in which middlewares see PutObject calls
+type tracing struct {
+	storage.Wrapper
+	name  string
+	calls *[]string
+}
+
+func (t tracing) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	*t.calls = append(*t.calls, t.name)
+	return t.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func trace(name string, calls *[]string) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return tracing{Wrapper: storage.Wrapper{Next: next}, name: name, calls: calls}
+	}
+}
+
+func TestWrapperConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.Wrapper{Next: memory.NewMemoryClient()}
+	})
+}
+
+func TestChain(t *testing.T) {
+	calls := []string{}
+	mem := memory.NewMemoryClient()
+	s := storage.Chain(mem, trace("a", &calls), trace("b", &calls))
+
+	if err := mem.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutObject("bucket", "key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(calls, ",") != "a,b" {
+		t.Errorf("calls = %v, want [a b]", calls)
+	}
+
+	if inner := storage.Unwrap(storage.Unwrap(s)); inner != mem {
+		t.Errorf("Unwrap twice = %T, want the memory storage", inner)
+	}
+	if storage.Unwrap(mem) != nil {
+		t.Error("Unwrap of an unwrapped storage is not nil")
+	}
+	if provider := storage.Provider(s); provider != "memory" {
+		t.Errorf("Provider = '%s', want 'memory'", provider)
+	}
+	if storage.Chain(mem) != mem {
+		t.Error("Chain without middlewares doesn't return the storage")
+	}
+}
+
+func TestNewWithMiddlewares(t *testing.T) {
+	calls := []string{}
+	s, err := storage.New(storage.Options{
+		StorageType: storage.TypeMemory,
+		Middlewares: []storage.Middleware{trace("a", &calls)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutObject("bucket", "key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("calls = %v, want [a]", calls)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// URLOpener creates the storage for a URL and returns it with the name of the bucket the URL refers to.
+type URLOpener func(ctx context.Context, u *url.URL) (Storage, string, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]URLOpener{}
+)
+
+// RegisterScheme makes a URL scheme available to Open. It panics if the scheme is already registered.
+func RegisterScheme(scheme string, opener URLOpener) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if _, ok := schemes[scheme]; ok {
+		panic(fmt.Sprintf("storage: scheme '%s' registered twice", scheme))
+	}
+	schemes[scheme] = opener
+}
+
+// Schemes returns the registered URL schemes.
+func Schemes() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+
+	names := []string{}
+	for scheme := range schemes {
+		names = append(names, scheme)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns the bucket a connection string refers to. Schemes are registered by the provider packages,
+// which must be imported (see New). Built-in schemes:
+//
+//	s3://bucket?region=eu-west-1
+//	gs://bucket?project=my-project&location=EU     (project defaults to $GOOGLE_CLOUD_PROJECT)
+//	azblob://account/container                     (or az://account/container)
+//	file:///path/to/bucket                         (bucket directory in a local root directory)
+//	mem://bucket                                   (new, empty in-memory storage)
+func Open(ctx context.Context, rawURL string) (*Bucket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	schemesMu.RLock()
+	opener, ok := schemes[strings.ToLower(u.Scheme)]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("open '%s': no storage registered for scheme '%s'", rawURL, u.Scheme)
+	}
+
+	s, bucketName, err := opener(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("open '%s': %w", rawURL, err)
+	}
+
+	return &Bucket{Storage: s, Name: bucketName}, nil
+}
+
+// CheckQuery returns an error for query parameters other than allowed, to catch typos.
+// URLOpeners use it to validate their URLs.
+func CheckQuery(u *url.URL, allowed ...string) error {
+	for param := range u.Query() {
+		known := false
+		for _, a := range allowed {
+			known = known || param == a
+		}
+		if !known {
+			return fmt.Errorf("unknown query parameter '%s'", param)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	_ "github.com/pbreedt/cloud-connect/storage/all"
+)
+
+func TestOpenMemory(t *testing.T) {
+	bucket, err := storage.Open(context.Background(), "mem://artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket.Name != "artifacts" {
+		t.Errorf("Name = '%s', want 'artifacts'", bucket.Name)
+	}
+
+	if err := bucket.PutObject("key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := bucket.GetObject("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "data" {
+		t.Errorf("GetObject = '%s', want 'data'", data)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	root := t.TempDir()
+	bucket, err := storage.Open(context.Background(), "file://"+filepath.ToSlash(filepath.Join(root, "bucket")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket.Name != "bucket" {
+		t.Errorf("Name = '%s', want 'bucket'", bucket.Name)
+	}
+
+	if err := bucket.Storage.CreateBucket(bucket.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.PutObject("dir/key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := bucket.ListContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "dir/key" {
+		t.Errorf("ListContent = %v, want [dir/key]", keys)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	for _, rawURL := range []string{
+		"ftp://bucket",
+		"s3://",
+		"s3://bucket?regoin=eu-west-1",
+		"s3://bucket?use_path_style=maybe",
+		"azblob://account",
+		"azblob://account/container/extra",
+		"file:///",
+		"mem://bucket?size=1",
+	} {
+		if _, err := storage.Open(context.Background(), rawURL); err == nil {
+			t.Errorf("Open(%s) should fail", rawURL)
+		}
+	}
+}
+
+func TestRegisterScheme(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering 'mem' twice should panic")
+		}
+	}()
+	storage.RegisterScheme("mem", func(ctx context.Context, u *url.URL) (storage.Storage, string, error) {
+		return nil, "", nil
+	})
+}
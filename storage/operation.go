@@ -0,0 +1,32 @@
+package storage
+
+// Operation names a Storage method, for wrappers that treat calls by kind (rate limits, logs, metrics).
+type Operation string
+
+const (
+	OpCreateBucket      Operation = "CreateBucket"
+	OpListBuckets       Operation = "ListBuckets"
+	OpListBucketContent Operation = "ListBucketContent"
+	OpWalkBucketContent Operation = "WalkBucketContent"
+	OpDeleteBucket      Operation = "DeleteBucket"
+	OpStoreObject       Operation = "StoreObject"
+	OpRetrieveObject    Operation = "RetrieveObject"
+	OpPutObject         Operation = "PutObject"
+	OpGetObject         Operation = "GetObject"
+	OpStatObject        Operation = "StatObject"
+	OpPresignURL        Operation = "PresignURL"
+	OpDeleteObject      Operation = "DeleteObject"
+	OpDeleteObjects     Operation = "DeleteObjects"
+	OpSetStorageClass   Operation = "SetStorageClass"
+	OpRestoreObject     Operation = "RestoreObject"
+	OpRestoreStatus     Operation = "RestoreStatus"
+)
+
+var (
+	// ReadOperations read buckets or objects, like S3 GET/HEAD requests.
+	ReadOperations = []Operation{OpListBuckets, OpListBucketContent, OpWalkBucketContent, OpRetrieveObject,
+		OpGetObject, OpStatObject, OpRestoreStatus}
+	// WriteOperations change buckets or objects, like S3 PUT/COPY/POST/DELETE requests.
+	WriteOperations = []Operation{OpCreateBucket, OpDeleteBucket, OpStoreObject, OpPutObject, OpDeleteObject,
+		OpDeleteObjects, OpSetStorageClass, OpRestoreObject}
+)
@@ -0,0 +1,161 @@
+package otelstorage
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func (s *Instrumented) CreateBucket(bucketName string, opts ...storage.CreateBucketOption) error {
+	c := s.begin(storage.OpCreateBucket, bucketName)
+	err := s.next.CreateBucket(bucketName, opts...)
+	c.end(err, -1)
+	return err
+}
+
+func (s *Instrumented) ListBuckets() ([]string, error) {
+	c := s.begin(storage.OpListBuckets, "")
+	buckets, err := s.next.ListBuckets()
+	c.end(err, -1)
+	return buckets, err
+}
+
+func (s *Instrumented) ListBucketContent(bucketName string) ([]string, error) {
+	c := s.begin(storage.OpListBucketContent, bucketName)
+	objects, err := s.next.ListBucketContent(bucketName)
+	c.end(err, -1)
+	return objects, err
+}
+
+// WalkBucketContent spans the whole walk, including the calls to fn.
+func (s *Instrumented) WalkBucketContent(bucketName string, fn storage.WalkFunc, opts ...storage.ListOption) error {
+	c := s.begin(storage.OpWalkBucketContent, bucketName)
+	err := s.next.WalkBucketContent(bucketName, fn, opts...)
+	c.end(err, -1)
+	return err
+}
+
+func (s *Instrumented) DeleteBucket(bucketName string, opts ...storage.DeleteBucketOption) error {
+	c := s.begin(storage.OpDeleteBucket, bucketName)
+	err := s.next.DeleteBucket(bucketName, opts...)
+	c.end(err, -1)
+	return err
+}
+
+// StoreObject counts the size of the file as uploaded bytes.
+func (s *Instrumented) StoreObject(bucketName string, objectKey string, fileName string, opts ...storage.PutOption) error {
+	c := s.begin(storage.OpStoreObject, bucketName, ObjectKey.String(objectKey))
+	err := s.next.StoreObject(bucketName, objectKey, fileName, opts...)
+	c.end(err, storage.FileTransferSize(err, fileName))
+	return err
+}
+
+// RetrieveObject counts the size of the file as downloaded bytes.
+func (s *Instrumented) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...storage.GetOption) error {
+	c := s.begin(storage.OpRetrieveObject, bucketName, ObjectKey.String(objectKey))
+	err := s.next.RetrieveObject(bucketName, objectKey, fileName, opts...)
+	c.end(err, storage.FileTransferSize(err, fileName))
+	return err
+}
+
+func (s *Instrumented) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	c := s.begin(storage.OpPutObject, bucketName, ObjectKey.String(objectKey))
+	reader, count := storage.CountReader(reader)
+	err := s.next.PutObject(bucketName, objectKey, reader, opts...)
+	c.end(err, count())
+	return err
+}
+
+// GetObject ends its span when the returned reader is closed, so the duration includes reading the content.
+func (s *Instrumented) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	c := s.begin(storage.OpGetObject, bucketName, ObjectKey.String(objectKey))
+	rc, err := s.next.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		c.end(err, -1)
+		return nil, err
+	}
+	return &endingReader{ReadCloser: rc, call: c}, nil
+}
+
+func (s *Instrumented) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	c := s.begin(storage.OpStatObject, bucketName, ObjectKey.String(objectKey))
+	info, err := s.next.StatObject(bucketName, objectKey, opts...)
+	c.end(err, -1)
+	return info, err
+}
+
+func (s *Instrumented) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	c := s.begin(storage.OpPresignURL, bucketName, ObjectKey.String(objectKey), attribute.String("http.request.method", method))
+	url, err := s.next.PresignURL(bucketName, objectKey, method, expiry)
+	c.end(err, -1)
+	return url, err
+}
+
+func (s *Instrumented) DeleteObject(bucketName string, objectKeys []string) error {
+	c := s.begin(storage.OpDeleteObject, bucketName, ObjectCountKey.Int(len(objectKeys)))
+	err := s.next.DeleteObject(bucketName, objectKeys)
+	c.end(err, -1)
+	return err
+}
+
+// DeleteObjects records keys that failed to delete as an error of the call.
+func (s *Instrumented) DeleteObjects(bucketName string, objectKeys []string) (*storage.DeleteReport, error) {
+	c := s.begin(storage.OpDeleteObjects, bucketName, ObjectCountKey.Int(len(objectKeys)))
+	report, err := s.next.DeleteObjects(bucketName, objectKeys)
+	if err == nil && report != nil {
+		c.end(report.Err(), -1)
+	} else {
+		c.end(err, -1)
+	}
+	return report, err
+}
+
+func (s *Instrumented) SetStorageClass(bucketName string, objectKey string, class storage.StorageClass, opts ...storage.GetOption) error {
+	c := s.begin(storage.OpSetStorageClass, bucketName, ObjectKey.String(objectKey))
+	err := s.next.SetStorageClass(bucketName, objectKey, class, opts...)
+	c.end(err, -1)
+	return err
+}
+
+func (s *Instrumented) RestoreObject(bucketName string, objectKey string, opts ...storage.RestoreOption) error {
+	c := s.begin(storage.OpRestoreObject, bucketName, ObjectKey.String(objectKey))
+	err := s.next.RestoreObject(bucketName, objectKey, opts...)
+	c.end(err, -1)
+	return err
+}
+
+func (s *Instrumented) RestoreStatus(bucketName string, objectKey string) (storage.RestoreStatus, error) {
+	c := s.begin(storage.OpRestoreStatus, bucketName, ObjectKey.String(objectKey))
+	status, err := s.next.RestoreStatus(bucketName, objectKey)
+	c.end(err, -1)
+	return status, err
+}
+
+// endingReader ends the call of GetObject when closed, with the bytes read and the first read error
+type endingReader struct {
+	io.ReadCloser
+	call *call
+	n    int64
+	err  error
+	once sync.Once
+}
+
+func (r *endingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *endingReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.call.end(r.err, r.n)
+	})
+	return err
+}
@@ -0,0 +1,193 @@
+// Package otelstorage instruments a storage.Storage with OpenTelemetry spans and metrics.
+//
+//	instrumented := otelstorage.New(cloudStorage,
+//		otelstorage.WithTracerProvider(tracerProvider),
+//		otelstorage.WithMeterProvider(meterProvider),
+//	)
+//
+// Every call gets a span with the provider, operation, bucket, object, bytes and status, and is counted in
+//   - storage.client.operation.duration: call latency in seconds (histogram)
+//   - storage.client.bytes: bytes uploaded and downloaded by object operations
+//   - storage.client.errors: failed calls
+//
+// with the provider, operation and status as metric attributes.
+// Storage methods don't take a context, so spans are started as new traces.
+package otelstorage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/pbreedt/cloud-connect/storage/otelstorage"
+
+// Attribute keys of spans and metrics
+const (
+	ProviderKey    = attribute.Key("storage.provider")
+	OperationKey   = attribute.Key("storage.operation")
+	BucketKey      = attribute.Key("storage.bucket")
+	ObjectKey      = attribute.Key("storage.object")
+	ObjectCountKey = attribute.Key("storage.object_count")
+	BytesKey       = attribute.Key("storage.bytes")
+	StatusKey      = attribute.Key("storage.status")
+)
+
+// Values of StatusKey
+const (
+	StatusOK           = "ok"
+	StatusNotFound     = "not_found"
+	StatusNotModified  = "not_modified"
+	StatusNotSupported = "not_supported"
+	StatusError        = "error"
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	provider       string
+}
+
+type Option func(*options)
+
+// WithTracerProvider replaces the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider replaces the global meter provider.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = mp
+	}
+}
+
+// WithProvider sets the storage.provider attribute, by default the package name
+// of the innermost storage (aws, gcp, azure, local, memory).
+func WithProvider(name string) Option {
+	return func(o *options) {
+		o.provider = name
+	}
+}
+
+// Instrumented is a storage.Storage emitting spans and metrics for the calls to the storage it wraps.
+type Instrumented struct {
+	next     storage.Storage
+	tracer   trace.Tracer
+	provider attribute.KeyValue
+
+	duration metric.Float64Histogram
+	bytes    metric.Int64Counter
+	errors   metric.Int64Counter
+}
+
+var _ storage.Storage = &Instrumented{}
+
+// New instruments next. Instruments that can't be created are reported to otel.Handle and left out.
+func New(next storage.Storage, opts ...Option) *Instrumented {
+	o := &options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		provider:       storage.Provider(next),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	meter := o.meterProvider.Meter(instrumentationName)
+	s := &Instrumented{
+		next:     next,
+		tracer:   o.tracerProvider.Tracer(instrumentationName),
+		provider: ProviderKey.String(o.provider),
+	}
+
+	var err error
+	s.duration, err = meter.Float64Histogram("storage.client.operation.duration",
+		metric.WithDescription("Duration of storage calls"), metric.WithUnit("s"))
+	otel.Handle(err)
+	s.bytes, err = meter.Int64Counter("storage.client.bytes",
+		metric.WithDescription("Bytes uploaded and downloaded by storage calls"), metric.WithUnit("By"))
+	otel.Handle(err)
+	s.errors, err = meter.Int64Counter("storage.client.errors",
+		metric.WithDescription("Failed storage calls"), metric.WithUnit("{error}"))
+	otel.Handle(err)
+
+	return s
+}
+
+// Middleware returns a storage.Middleware instrumenting the wrapped storage with opts.
+func Middleware(opts ...Option) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return New(next, opts...)
+	}
+}
+
+// Unwrap returns the instrumented storage.
+func (s *Instrumented) Unwrap() storage.Storage {
+	return s.next
+}
+
+// status returns the StatusKey value of err
+func status(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.Is(err, storage.ErrNotFound):
+		return StatusNotFound
+	case errors.Is(err, storage.ErrNotModified):
+		return StatusNotModified
+	case errors.Is(err, storage.ErrNotSupported):
+		return StatusNotSupported
+	default:
+		return StatusError
+	}
+}
+
+// call is a storage call in progress
+type call struct {
+	s     *Instrumented
+	op    attribute.KeyValue
+	span  trace.Span
+	start time.Time
+}
+
+// begin starts the span of a call of op on bucketName (if any) with extra attributes
+func (s *Instrumented) begin(op storage.Operation, bucketName string, attrs ...attribute.KeyValue) *call {
+	c := &call{s: s, op: OperationKey.String(string(op)), start: time.Now()}
+	attrs = append(attrs, s.provider, c.op)
+	if bucketName != "" {
+		attrs = append(attrs, BucketKey.String(bucketName))
+	}
+	_, c.span = s.tracer.Start(context.Background(), "storage."+string(op),
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return c
+}
+
+// end records the outcome of the call, with the bytes transferred if not negative
+func (c *call) end(err error, bytes int64) {
+	ctx := context.Background()
+	st := StatusKey.String(status(err))
+	metricAttrs := metric.WithAttributes(c.s.provider, c.op, st)
+
+	c.span.SetAttributes(st)
+	if bytes >= 0 {
+		c.span.SetAttributes(BytesKey.Int64(bytes))
+		c.s.bytes.Add(ctx, bytes, metricAttrs)
+	}
+	if err != nil && !errors.Is(err, storage.ErrNotModified) {
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+		c.s.errors.Add(ctx, 1, metricAttrs)
+	}
+	c.s.duration.Record(ctx, time.Since(c.start).Seconds(), metricAttrs)
+	c.span.End()
+}
@@ -0,0 +1,179 @@
+package otelstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newInstrumented returns a memory storage instrumented with in-process exporters
+func newInstrumented(t *testing.T) (*Instrumented, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+	s := New(memory.NewMemoryClient(),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics))),
+	)
+	return s, spans, metrics
+}
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, _, _ := newInstrumented(t)
+		return s
+	})
+}
+
+func TestSpans(t *testing.T) {
+	s, spans, _ := newInstrumented(t)
+	if err := s.CreateBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutObject("bucket", "key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := s.GetObject("bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans.Ended()) != 2 {
+		t.Errorf("%d spans ended before closing the GetObject reader, want 2", len(spans.Ended()))
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+	rc.Close()
+	s.StatObject("bucket", "missing")
+
+	ended := spans.Ended()
+	if len(ended) != 4 {
+		t.Fatalf("%d spans ended, want 4", len(ended))
+	}
+
+	put := ended[1]
+	if put.Name() != "storage.PutObject" {
+		t.Errorf("span name = '%s', want 'storage.PutObject'", put.Name())
+	}
+	a := attrs(put.Attributes())
+	if a[ProviderKey].AsString() != "memory" || a[OperationKey].AsString() != "PutObject" || a[BucketKey].AsString() != "bucket" ||
+		a[ObjectKey].AsString() != "key" || a[BytesKey].AsInt64() != 4 || a[StatusKey].AsString() != StatusOK {
+		t.Errorf("PutObject span attributes = %v", put.Attributes())
+	}
+
+	get := attrs(ended[2].Attributes())
+	if get[BytesKey].AsInt64() != 4 || get[StatusKey].AsString() != StatusOK {
+		t.Errorf("GetObject span attributes = %v", ended[2].Attributes())
+	}
+
+	stat := ended[3]
+	if attrs(stat.Attributes())[StatusKey].AsString() != StatusNotFound || stat.Status().Code != codes.Error {
+		t.Errorf("StatObject span attributes = %v, status = %v", stat.Attributes(), stat.Status())
+	}
+	if len(stat.Events()) != 1 || stat.Events()[0].Name != "exception" {
+		t.Errorf("StatObject span events = %v, want the recorded error", stat.Events())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s, _, reader := newInstrumented(t)
+	s.CreateBucket("bucket")
+	s.PutObject("bucket", "a", strings.NewReader("data"))
+	s.PutObject("bucket", "b", strings.NewReader("more data"))
+	s.StatObject("bucket", "missing")
+
+	rm := metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+
+	duration, ok := found["storage.client.operation.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("no duration histogram in %v", found)
+	}
+	counts := map[string]uint64{}
+	for _, dp := range duration.DataPoints {
+		op, _ := dp.Attributes.Value(OperationKey)
+		counts[op.AsString()] += dp.Count
+	}
+	if counts["CreateBucket"] != 1 || counts["PutObject"] != 2 || counts["StatObject"] != 1 {
+		t.Errorf("duration counts = %v", counts)
+	}
+
+	bytes, ok := found["storage.client.bytes"].(metricdata.Sum[int64])
+	if !ok || len(bytes.DataPoints) != 1 || bytes.DataPoints[0].Value != 13 {
+		t.Errorf("bytes = %+v, want 13 bytes put", found["storage.client.bytes"])
+	}
+
+	errs, ok := found["storage.client.errors"].(metricdata.Sum[int64])
+	if !ok || len(errs.DataPoints) != 1 || errs.DataPoints[0].Value != 1 {
+		t.Fatalf("errors = %+v, want 1", found["storage.client.errors"])
+	}
+	if st, _ := errs.DataPoints[0].Attributes.Value(StatusKey); st.AsString() != StatusNotFound {
+		t.Errorf("error status = '%s', want '%s'", st.AsString(), StatusNotFound)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, StatusOK},
+		{storage.ErrNotFound, StatusNotFound},
+		{errors.Join(errors.New("SetStorageClass"), storage.ErrNotSupported), StatusNotSupported},
+		{errors.New("denied"), StatusError},
+	}
+	for _, test := range tests {
+		if got := status(test.err); got != test.want {
+			t.Errorf("status(%v) = '%s', want '%s'", test.err, got, test.want)
+		}
+	}
+}
+
+// sizeRecording records the size of the readers uploaded, as providers see it
+type sizeRecording struct {
+	storage.Wrapper
+	size int64
+}
+
+func (s *sizeRecording) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	s.size = storage.ReaderSize(reader)
+	return s.Next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+func TestReaderSize(t *testing.T) {
+	recording := &sizeRecording{Wrapper: storage.Wrapper{Next: memory.NewMemoryClient()}}
+	s := New(recording)
+	s.CreateBucket("bucket")
+	if err := s.PutObject("bucket", "key", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if recording.size != 4 {
+		t.Errorf("uploaded reader size = %d, want the 4 bytes of the reader", recording.size)
+	}
+}
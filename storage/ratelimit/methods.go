@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+func (lim *Limiter) CreateBucket(bucketName string, opts ...storage.CreateBucketOption) error {
+	defer lim.acquire(storage.OpCreateBucket, bucketName, 1)()
+	return lim.next.CreateBucket(bucketName, opts...)
+}
+
+func (lim *Limiter) ListBuckets() ([]string, error) {
+	defer lim.acquire(storage.OpListBuckets, "", 1)()
+	return lim.next.ListBuckets()
+}
+
+func (lim *Limiter) ListBucketContent(bucketName string) ([]string, error) {
+	defer lim.acquire(storage.OpListBucketContent, bucketName, 1)()
+	return lim.next.ListBucketContent(bucketName)
+}
+
+// WalkBucketContent releases its concurrency slots while fn runs, so fn may call the Limiter without deadlocking.
+func (lim *Limiter) WalkBucketContent(bucketName string, fn storage.WalkFunc, opts ...storage.ListOption) error {
+	release := lim.acquire(storage.OpWalkBucketContent, bucketName, 1)
+	defer func() { release() }()
+	return lim.next.WalkBucketContent(bucketName, func(object storage.ObjectInfo) error {
+		release()
+		err := fn(object)
+		release = lim.acquireSlots(storage.OpWalkBucketContent)
+		return err
+	}, opts...)
+}
+
+// DeleteBucket counts as a single call, also when forced: the provider deletes the content in batches.
+func (lim *Limiter) DeleteBucket(bucketName string, opts ...storage.DeleteBucketOption) error {
+	defer lim.acquire(storage.OpDeleteBucket, bucketName, 1)()
+	return lim.next.DeleteBucket(bucketName, opts...)
+}
+
+func (lim *Limiter) StoreObject(bucketName string, objectKey string, fileName string, opts ...storage.PutOption) error {
+	defer lim.acquire(storage.OpStoreObject, bucketName, 1)()
+	return lim.next.StoreObject(bucketName, objectKey, fileName, opts...)
+}
+
+func (lim *Limiter) RetrieveObject(bucketName string, objectKey string, fileName string, opts ...storage.GetOption) error {
+	defer lim.acquire(storage.OpRetrieveObject, bucketName, 1)()
+	return lim.next.RetrieveObject(bucketName, objectKey, fileName, opts...)
+}
+
+func (lim *Limiter) PutObject(bucketName string, objectKey string, reader io.Reader, opts ...storage.PutOption) error {
+	defer lim.acquire(storage.OpPutObject, bucketName, 1)()
+	return lim.next.PutObject(bucketName, objectKey, reader, opts...)
+}
+
+// GetObject holds its concurrency slots until the returned reader is closed.
+func (lim *Limiter) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	release := lim.acquire(storage.OpGetObject, bucketName, 1)
+	rc, err := lim.next.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releasingReader{ReadCloser: rc, release: release}, nil
+}
+
+func (lim *Limiter) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	defer lim.acquire(storage.OpStatObject, bucketName, 1)()
+	return lim.next.StatObject(bucketName, objectKey, opts...)
+}
+
+func (lim *Limiter) PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error) {
+	defer lim.acquire(storage.OpPresignURL, bucketName, 1)()
+	return lim.next.PresignURL(bucketName, objectKey, method, expiry)
+}
+
+// DeleteObject takes a token per key, as providers count deletes per object.
+func (lim *Limiter) DeleteObject(bucketName string, objectKeys []string) error {
+	defer lim.acquire(storage.OpDeleteObject, bucketName, len(objectKeys))()
+	return lim.next.DeleteObject(bucketName, objectKeys)
+}
+
+// DeleteObjects takes a token per key, as providers count deletes per object.
+func (lim *Limiter) DeleteObjects(bucketName string, objectKeys []string) (*storage.DeleteReport, error) {
+	defer lim.acquire(storage.OpDeleteObjects, bucketName, len(objectKeys))()
+	return lim.next.DeleteObjects(bucketName, objectKeys)
+}
+
+func (lim *Limiter) SetStorageClass(bucketName string, objectKey string, class storage.StorageClass, opts ...storage.GetOption) error {
+	defer lim.acquire(storage.OpSetStorageClass, bucketName, 1)()
+	return lim.next.SetStorageClass(bucketName, objectKey, class, opts...)
+}
+
+func (lim *Limiter) RestoreObject(bucketName string, objectKey string, opts ...storage.RestoreOption) error {
+	defer lim.acquire(storage.OpRestoreObject, bucketName, 1)()
+	return lim.next.RestoreObject(bucketName, objectKey, opts...)
+}
+
+func (lim *Limiter) RestoreStatus(bucketName string, objectKey string) (storage.RestoreStatus, error) {
+	defer lim.acquire(storage.OpRestoreStatus, bucketName, 1)()
+	return lim.next.RestoreStatus(bucketName, objectKey)
+}
+
+// releasingReader releases the concurrency slots of a GetObject call when closed
+type releasingReader struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releasingReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,199 @@
+// Package ratelimit limits the request rate and concurrency of a storage.Storage on the client side,
+// so bulk jobs wait for capacity instead of getting throttled by the provider
+// (e.g. S3 allows 3,500 PUT/COPY/POST/DELETE and 5,500 GET/HEAD requests per second per prefix).
+//
+//	limited := ratelimit.New(cloudStorage,
+//		ratelimit.WithBucketLimit(3000, 100, storage.WriteOperations...),
+//		ratelimit.WithBucketLimit(5000, 100, storage.ReadOperations...),
+//		ratelimit.WithConcurrency(64),
+//	)
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/time/rate"
+)
+
+type limit struct {
+	rate  rate.Limit
+	burst int
+}
+
+type options struct {
+	operationLimits map[storage.Operation][]*rate.Limiter
+	bucketLimits    map[storage.Operation][]*limit
+	concurrency     map[storage.Operation][]*semaphore.Weighted
+	// err is the first invalid option, New panics with it
+	err error
+}
+
+type Option func(*options)
+
+// allOperations returns ops, or all operations if there are none
+func allOperations(ops []storage.Operation) []storage.Operation {
+	if len(ops) > 0 {
+		return ops
+	}
+	return append(append([]storage.Operation{storage.OpPresignURL}, storage.ReadOperations...), storage.WriteOperations...)
+}
+
+// validRate records an error in o if requestsPerSecond isn't a positive rate
+func (o *options) validRate(option string, requestsPerSecond float64) bool {
+	if requestsPerSecond > 0 {
+		return true
+	}
+	if o.err == nil {
+		o.err = fmt.Errorf("ratelimit: %s(%v): requests per second must be positive", option, requestsPerSecond)
+	}
+	return false
+}
+
+// WithOperationLimit allows requestsPerSecond calls, with bursts of up to burst calls, shared by ops
+// (all operations if none are given) over all buckets. requestsPerSecond must be positive.
+func WithOperationLimit(requestsPerSecond float64, burst int, ops ...storage.Operation) Option {
+	return func(o *options) {
+		if !o.validRate("WithOperationLimit", requestsPerSecond) {
+			return
+		}
+		limiter := rate.NewLimiter(rate.Limit(requestsPerSecond), max(burst, 1))
+		for _, op := range allOperations(ops) {
+			o.operationLimits[op] = append(o.operationLimits[op], limiter)
+		}
+	}
+}
+
+// WithBucketLimit allows requestsPerSecond calls, with bursts of up to burst calls, shared by ops
+// (all operations on buckets if none are given) for each bucket separately. requestsPerSecond must be positive.
+func WithBucketLimit(requestsPerSecond float64, burst int, ops ...storage.Operation) Option {
+	return func(o *options) {
+		if !o.validRate("WithBucketLimit", requestsPerSecond) {
+			return
+		}
+		l := &limit{rate: rate.Limit(requestsPerSecond), burst: max(burst, 1)}
+		for _, op := range allOperations(ops) {
+			if op != storage.OpListBuckets {
+				o.bucketLimits[op] = append(o.bucketLimits[op], l)
+			}
+		}
+	}
+}
+
+// WithConcurrency allows at most n calls of ops (all operations if none are given) in flight at the same time.
+// A GetObject call stays in flight until its reader is closed. A WalkBucketContent call is in flight while it lists,
+// not while its WalkFunc runs, so the WalkFunc may call the Limiter (as storage.DeleteByPrefix does).
+func WithConcurrency(n int, ops ...storage.Operation) Option {
+	return func(o *options) {
+		sem := semaphore.NewWeighted(int64(max(n, 1)))
+		for _, op := range allOperations(ops) {
+			o.concurrency[op] = append(o.concurrency[op], sem)
+		}
+	}
+}
+
+// bucketKey identifies the token bucket of a bucket limit for a bucket
+type bucketKey struct {
+	limit  *limit
+	bucket string
+}
+
+// Limiter is a storage.Storage that waits for its limits before each call of the wrapped storage.
+type Limiter struct {
+	next storage.Storage
+	opts options
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*rate.Limiter
+}
+
+var _ storage.Storage = (*Limiter)(nil)
+
+// New returns next limited by opts. It panics if an option is invalid (a rate that isn't positive), as the
+// limits are fixed by the program.
+func New(next storage.Storage, opts ...Option) *Limiter {
+	o := options{
+		operationLimits: map[storage.Operation][]*rate.Limiter{},
+		bucketLimits:    map[storage.Operation][]*limit{},
+		concurrency:     map[storage.Operation][]*semaphore.Weighted{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		panic(o.err)
+	}
+
+	return &Limiter{
+		next:    next,
+		opts:    o,
+		buckets: map[bucketKey]*rate.Limiter{},
+	}
+}
+
+// Middleware returns a storage.Middleware limiting the wrapped storage by opts.
+func Middleware(opts ...Option) storage.Middleware {
+	return func(next storage.Storage) storage.Storage {
+		return New(next, opts...)
+	}
+}
+
+// Unwrap returns the limited storage.
+func (lim *Limiter) Unwrap() storage.Storage {
+	return lim.next
+}
+
+// bucketLimiter returns the token bucket of l for bucketName, creating it on first use
+func (lim *Limiter) bucketLimiter(l *limit, bucketName string) *rate.Limiter {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	key := bucketKey{limit: l, bucket: bucketName}
+	limiter, ok := lim.buckets[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.rate, l.burst)
+		lim.buckets[key] = limiter
+	}
+	return limiter
+}
+
+// acquire waits until op on bucketName may proceed, taking n tokens (as for n deleted keys) from
+// the rate limits, and returns the function releasing its concurrency slots
+func (lim *Limiter) acquire(op storage.Operation, bucketName string, n int) func() {
+	limiters := append([]*rate.Limiter{}, lim.opts.operationLimits[op]...)
+	for _, l := range lim.opts.bucketLimits[op] {
+		limiters = append(limiters, lim.bucketLimiter(l, bucketName))
+	}
+	for _, limiter := range limiters {
+		wait(limiter, max(n, 1))
+	}
+	return lim.acquireSlots(op)
+}
+
+// acquireSlots waits for the concurrency slots of op and returns the function releasing them.
+// Acquiring one slot without a deadline can't fail.
+func (lim *Limiter) acquireSlots(op storage.Operation) func() {
+	sems := lim.opts.concurrency[op]
+	for _, sem := range sems {
+		sem.Acquire(context.Background(), 1)
+	}
+	return func() {
+		for _, sem := range sems {
+			sem.Release(1)
+		}
+	}
+}
+
+// wait takes n tokens from limiter, in steps of at most its burst size. With a positive rate (checked by New),
+// steps within the burst and no deadline, WaitN can't fail.
+func wait(limiter *rate.Limiter, n int) {
+	burst := max(limiter.Burst(), 1)
+	for n > 0 {
+		step := min(n, burst)
+		limiter.WaitN(context.Background(), step)
+		n -= step
+	}
+}
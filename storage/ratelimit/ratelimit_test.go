@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pbreedt/cloud-connect/storage"
+	"github.com/pbreedt/cloud-connect/storage/memory"
+	"github.com/pbreedt/cloud-connect/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New(memory.NewMemoryClient(),
+			WithOperationLimit(10_000, 100),
+			WithBucketLimit(10_000, 100, storage.WriteOperations...),
+			WithConcurrency(4),
+		)
+	})
+}
+
+func newBucket(t *testing.T, s storage.Storage, bucketName string) {
+	t.Helper()
+	if err := s.CreateBucket(bucketName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOperationLimit(t *testing.T) {
+	s := New(memory.NewMemoryClient(), WithOperationLimit(100, 1, storage.OpPutObject))
+	newBucket(t, s, "a")
+	newBucket(t, s, "b")
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		bucketName := []string{"a", "b"}[i%2]
+		if err := s.PutObject(bucketName, "key", strings.NewReader("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("6 puts at 100/s took %v, want at least 50ms", elapsed)
+	}
+
+	// other operations are not limited
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		s.StatObject("a", "key")
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("6 unlimited stats took %v", elapsed)
+	}
+}
+
+func TestBucketLimit(t *testing.T) {
+	next := memory.NewMemoryClient()
+	newBucket(t, next, "a")
+	newBucket(t, next, "b")
+	s := New(next, WithBucketLimit(10, 1))
+
+	start := time.Now()
+	s.StatObject("a", "key")
+	s.StatObject("b", "key")
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("first calls on different buckets took %v, should not wait", elapsed)
+	}
+
+	s.StatObject("a", "key")
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("second call on bucket a after %v, want to wait for 10/s limit", elapsed)
+	}
+}
+
+func TestDeleteObjectsTokens(t *testing.T) {
+	s := New(memory.NewMemoryClient(), WithOperationLimit(100, 2, storage.OpDeleteObjects))
+	newBucket(t, s, "a")
+	keys := []string{"1", "2", "3", "4", "5"}
+	for _, key := range keys {
+		s.PutObject("a", key, strings.NewReader(key))
+	}
+
+	start := time.Now()
+	if _, err := s.DeleteObjects("a", keys); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("deleting 5 keys at 100/s with burst 2 took %v, want at least 30ms", elapsed)
+	}
+}
+
+// blockingStorage counts the calls in flight, which block until unblocked
+type blockingStorage struct {
+	storage.Storage
+	inFlight, maxInFlight atomic.Int32
+	unblock               chan struct{}
+}
+
+func (b *blockingStorage) StatObject(bucketName string, objectKey string, opts ...storage.GetOption) (storage.ObjectInfo, error) {
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	for {
+		m := b.maxInFlight.Load()
+		if n <= m || b.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	<-b.unblock
+	return storage.ObjectInfo{}, nil
+}
+
+func (b *blockingStorage) GetObject(bucketName string, objectKey string, opts ...storage.GetOption) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("data")), nil
+}
+
+func TestConcurrency(t *testing.T) {
+	next := &blockingStorage{unblock: make(chan struct{})}
+	s := New(next, WithConcurrency(2))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.StatObject("a", "key")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(next.unblock)
+	wg.Wait()
+
+	if max := next.maxInFlight.Load(); max != 2 {
+		t.Errorf("%d calls in flight, want at most 2", max)
+	}
+}
+
+func TestConcurrencyGetObject(t *testing.T) {
+	s := New(&blockingStorage{}, WithConcurrency(1, storage.OpGetObject))
+
+	rc, err := s.GetObject("a", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := make(chan struct{})
+	go func() {
+		rc, _ := s.GetObject("a", "key")
+		rc.Close()
+		close(second)
+	}()
+
+	select {
+	case <-second:
+		t.Fatal("second GetObject should wait until the first reader is closed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	rc.Close()
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("second GetObject still waiting after the first reader was closed")
+	}
+}
+
+func TestConcurrencyWalk(t *testing.T) {
+	s := New(memory.NewMemoryClient(), WithConcurrency(1))
+	newBucket(t, s, "a")
+	for _, key := range []string{"logs/1", "logs/2", "logs/3"} {
+		if err := s.PutObject("a", key, strings.NewReader("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the walk callback deletes through the same Limiter
+	done := make(chan error)
+	go func() {
+		_, err := storage.DeleteByPrefix(s, "a", "logs/")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("DeleteByPrefix deadlocked on the concurrency limit")
+	}
+	if keys, _ := s.ListBucketContent("a"); len(keys) != 0 {
+		t.Errorf("%v left after DeleteByPrefix", keys)
+	}
+}
+
+func TestInvalidRate(t *testing.T) {
+	for _, opt := range []Option{WithOperationLimit(0, 10), WithBucketLimit(-1, 10)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("New with a rate that isn't positive should panic")
+				}
+			}()
+			New(memory.NewMemoryClient(), opt)
+		}()
+	}
+}
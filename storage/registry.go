@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a Storage from Options. Providers register one per StorageType with Register.
+type Factory func(opts Options) (Storage, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[StorageType]Factory{}
+)
+
+// Register makes a storage type available to New and NewStorage, typically from the init function of
+// the provider package. It panics if the storage type is already registered or factory is nil.
+func Register(storageType StorageType, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("storage: nil factory registered for type '%s'", storageType))
+	}
+	if _, ok := registry[storageType]; ok {
+		panic(fmt.Sprintf("storage: type '%s' registered twice", storageType))
+	}
+	registry[storageType] = factory
+}
+
+// Types returns the registered storage types.
+func Types() []StorageType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := []StorageType{}
+	for storageType := range registry {
+		types = append(types, storageType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"testing"
+)
+
+type fakeStorage struct {
+	Storage
+	opts Options
+}
+
+func TestRegister(t *testing.T) {
+	const typeFake StorageType = "FAKE"
+	Register(typeFake, func(opts Options) (Storage, error) {
+		return &fakeStorage{opts: opts}, nil
+	})
+
+	s, err := New(Options{StorageType: typeFake, Location: "somewhere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := s.(*fakeStorage); !ok || f.opts.Location != "somewhere" {
+		t.Errorf("New() = %#v, want fake storage created with options", s)
+	}
+
+	found := false
+	for _, storageType := range Types() {
+		found = found || storageType == typeFake
+	}
+	if !found {
+		t.Errorf("Types() = %v, should contain '%s'", Types(), typeFake)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a type twice should panic")
+		}
+	}()
+	Register(typeFake, func(opts Options) (Storage, error) { return nil, nil })
+}
+
+func TestNewUnknownType(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("New() without StorageType should fail")
+	}
+	if _, err := New(Options{StorageType: "UNKNOWN"}); err == nil {
+		t.Error("New() with unregistered StorageType should fail")
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// WaitForRestore polls RestoreStatus every interval until the object is available,
+// or ctx is done. Restoring from deep archive tiers can take up to 48 hours.
+func WaitForRestore(ctx context.Context, cloudStorage Storage, bucketName string, objectKey string, interval time.Duration) (RestoreStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := cloudStorage.RestoreStatus(bucketName, objectKey)
+		if err != nil {
+			return status, err
+		}
+		if status.Available {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type restoringStorage struct {
+	Storage
+	polls int
+}
+
+func (r *restoringStorage) RestoreStatus(bucketName string, objectKey string) (RestoreStatus, error) {
+	r.polls++
+	if r.polls < 3 {
+		return RestoreStatus{Archived: true, InProgress: true}, nil
+	}
+	return RestoreStatus{Archived: true, Available: true}, nil
+}
+
+func TestWaitForRestore(t *testing.T) {
+	s := &restoringStorage{}
+
+	status, err := WaitForRestore(context.Background(), s, "bucket", "key", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available || s.polls != 3 {
+		t.Fatalf("status = %+v after %d polls, want available after 3", status, s.polls)
+	}
+}
+
+func TestWaitForRestoreCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := WaitForRestore(ctx, &restoringStorage{polls: -1000}, "bucket", "key", time.Millisecond)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
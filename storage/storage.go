@@ -1,59 +1,118 @@
 package storage
 
 import (
+	"fmt"
+	"io"
 	"log"
-
-	"github.com/pbreedt/cloud-connect/storage/aws"
-	"github.com/pbreedt/cloud-connect/storage/azure"
-	"github.com/pbreedt/cloud-connect/storage/gcp"
+	"log/slog"
+	"time"
 )
 
 type Storage interface {
-	CreateBucket(bucketName string) error
+	CreateBucket(bucketName string, opts ...CreateBucketOption) error
 	ListBuckets() ([]string, error)
 	ListBucketContent(bucketName string) ([]string, error)
-	DeleteBucket(bucketName string) error
+	WalkBucketContent(bucketName string, fn WalkFunc, opts ...ListOption) error
+	DeleteBucket(bucketName string, opts ...DeleteBucketOption) error
 
-	StoreObject(bucketName string, objectKey string, fileName string) error
-	RetrieveObject(bucketName string, objectKey string, fileName string) error
+	StoreObject(bucketName string, objectKey string, fileName string, opts ...PutOption) error
+	RetrieveObject(bucketName string, objectKey string, fileName string, opts ...GetOption) error
+	PutObject(bucketName string, objectKey string, reader io.Reader, opts ...PutOption) error
+	GetObject(bucketName string, objectKey string, opts ...GetOption) (io.ReadCloser, error)
+	StatObject(bucketName string, objectKey string, opts ...GetOption) (ObjectInfo, error)
+	PresignURL(bucketName string, objectKey string, method string, expiry time.Duration) (string, error)
 	DeleteObject(bucketName string, objectKeys []string) error
+	DeleteObjects(bucketName string, objectKeys []string) (*DeleteReport, error)
+
+	SetStorageClass(bucketName string, objectKey string, class StorageClass, opts ...GetOption) error
+	RestoreObject(bucketName string, objectKey string, opts ...RestoreOption) error
+	RestoreStatus(bucketName string, objectKey string) (RestoreStatus, error)
 }
 
 type StorageType string
 
 const (
-	TypeS3    StorageType = "S3"
-	TypeGCP   StorageType = "GCP"
-	TypeAzure StorageType = "AZURE"
+	TypeS3     StorageType = "S3"
+	TypeGCP    StorageType = "GCP"
+	TypeAzure  StorageType = "AZURE"
+	TypeLocal  StorageType = "LOCAL"
+	TypeMemory StorageType = "MEMORY"
 )
 
 type Options struct {
 	StorageType          StorageType
 	GCP_ProjectId        string
 	Azure_StorageAccount string
+	Local_RootDir        string
 	Location             string
+
+	// CredentialsFile replaces the provider's default credentials file (AWS shared credentials, GCP service account JSON)
+	CredentialsFile string
+	// Credentials replace the provider's default credentials
+	Credentials Credentials
+	// Timeout limits connecting and waiting for the response of each request, 0 for the provider default
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts per request, including the first, 0 for the provider default
+	MaxAttempts int
+	// RetryPolicy replaces the provider's retry settings, so all providers retry alike;
+	// MaxAttempts applies if the policy doesn't set it
+	RetryPolicy *RetryPolicy
+
+	// Endpoint replaces the provider's service URL, for S3 compatible services and emulators
+	// (MinIO, Ceph, Wasabi, R2, Azurite, fake-gcs-server)
+	Endpoint string
+	// UsePathStyle addresses S3 buckets as endpoint/bucket instead of bucket.endpoint
+	UsePathStyle bool
+	// InsecureSkipVerify disables TLS certificate verification, for local services only
+	InsecureSkipVerify bool
+
+	// Middlewares wrap the created storage, the first being the outermost (see Chain)
+	Middlewares []Middleware
+	// Logger logs every call to the provider (see Logging) and retries of RetryPolicy, nil for no logging
+	Logger *slog.Logger
 }
 
+// NewStorage is like New, but exits the process if the storage can't be created.
+//
+// Deprecated: use New and handle the error.
 func NewStorage(opts Options) Storage {
-	if opts.StorageType == "" {
-		log.Fatalf("Options.StorageType must be provided")
+	s, err := New(opts)
+	if err != nil {
+		log.Fatal(err)
 	}
+	return s
+}
 
-	switch opts.StorageType {
-	case TypeS3:
-		// AWS Location/Region retrieved from ~/.aws/config or env var AWS_DEFAULT_REGION
-		return aws.NewS3Client()
-	case TypeGCP:
-		if opts.GCP_ProjectId == "" {
-			log.Fatalf("Options.GCP_ProjectId must be provided")
-		}
-		return gcp.NewCloudStorageClient(opts.GCP_ProjectId)
-	case TypeAzure:
-		if opts.Azure_StorageAccount == "" {
-			log.Fatalf("Options.Azure_StorageAccount must be provided")
-		}
-		return azure.NewBlobStorageClient(opts.Azure_StorageAccount)
-	default:
-		return nil
+// New creates the storage registered for opts.StorageType.
+// The provider package must be imported for its type to be registered, as in:
+//
+//	import _ "github.com/pbreedt/cloud-connect/storage/aws"
+//
+// or import "github.com/pbreedt/cloud-connect/storage/all" for all built-in providers.
+func New(opts Options) (Storage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[opts.StorageType]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown Options.StorageType '%s' (is the provider package imported?)", opts.StorageType)
+	}
+
+	if opts.Logger != nil && opts.RetryPolicy != nil && opts.RetryPolicy.OnRetry == nil {
+		policy := *opts.RetryPolicy
+		policy.OnRetry = logRetry(opts.Logger)
+		opts.RetryPolicy = &policy
+	}
+
+	s, err := factory(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Logger != nil {
+		s = Logging(opts.Logger)(s)
 	}
+	return Chain(s, opts.Middlewares...), nil
 }
@@ -0,0 +1,504 @@
+// Package storagetest provides a conformance test suite for storage.Storage implementations.
+package storagetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pbreedt/cloud-connect/storage"
+)
+
+// Run runs the conformance tests against the storage returned by newStorage.
+// Every test creates its own uniquely named bucket, which is deleted (forced) afterwards.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage, bucketName string)
+	}{
+		{"Buckets", testBuckets},
+		{"PutGetStat", testPutGetStat},
+		{"StoreRetrieve", testStoreRetrieve},
+		{"ContentEncoding", testContentEncoding},
+		{"ConditionalGet", testConditionalGet},
+		{"Range", testRange},
+		{"Progress", testProgress},
+		{"NotFound", testNotFound},
+		{"Walk", testWalk},
+		{"WalkDelimiter", testWalkDelimiter},
+		{"DeleteObjects", testDeleteObjects},
+		{"DeleteByPrefix", testDeleteByPrefix},
+		{"ForceDeleteBucket", testForceDeleteBucket},
+		{"StorageClass", testStorageClass},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStorage(t)
+			bucketName := NewBucketName()
+
+			err := s.CreateBucket(bucketName)
+			if err != nil {
+				t.Fatalf("CreateBucket(%s): %v", bucketName, err)
+			}
+			t.Cleanup(func() {
+				s.DeleteBucket(bucketName, storage.WithForce())
+			})
+
+			test.fn(t, s, bucketName)
+		})
+	}
+}
+
+// NewBucketName returns a unique bucket name that is valid for all providers.
+func NewBucketName() string {
+	return "cc-test-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:20]
+}
+
+// put stores content under objectKey, failing the test on error
+func put(t *testing.T, s storage.Storage, bucketName string, objectKey string, content string, opts ...storage.PutOption) {
+	t.Helper()
+	err := s.PutObject(bucketName, objectKey, strings.NewReader(content), opts...)
+	if err != nil {
+		t.Fatalf("PutObject(%s): %v", objectKey, err)
+	}
+}
+
+// get returns the content of objectKey, failing the test on error
+func get(t *testing.T, s storage.Storage, bucketName string, objectKey string, opts ...storage.GetOption) string {
+	t.Helper()
+	rc, err := s.GetObject(bucketName, objectKey, opts...)
+	if err != nil {
+		t.Fatalf("GetObject(%s): %v", objectKey, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("GetObject(%s) read: %v", objectKey, err)
+	}
+	return string(data)
+}
+
+func keys(t *testing.T, s storage.Storage, bucketName string, opts ...storage.ListOption) []string {
+	t.Helper()
+	found := []string{}
+	err := s.WalkBucketContent(bucketName, func(object storage.ObjectInfo) error {
+		found = append(found, object.Key)
+		return nil
+	}, opts...)
+	if err != nil {
+		t.Fatalf("WalkBucketContent: %v", err)
+	}
+	return found
+}
+
+func testBuckets(t *testing.T, s storage.Storage, bucketName string) {
+	buckets, err := s.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(buckets, bucketName) {
+		t.Errorf("ListBuckets() = %v, should contain '%s'", buckets, bucketName)
+	}
+
+	other := NewBucketName()
+	if err := s.CreateBucket(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteBucket(other); err != nil {
+		t.Fatal(err)
+	}
+	buckets, err = s.ListBuckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contains(buckets, other) {
+		t.Errorf("ListBuckets() = %v, should not contain deleted '%s'", buckets, other)
+	}
+}
+
+func testPutGetStat(t *testing.T, s storage.Storage, bucketName string) {
+	put(t, s, bucketName, "dir/object.json", `{"some":"data"}`,
+		storage.WithContentType("application/json"),
+		storage.WithMetadata(map[string]string{"owner": "test"}),
+	)
+
+	if content := get(t, s, bucketName, "dir/object.json"); content != `{"some":"data"}` {
+		t.Errorf("GetObject = '%s', want stored content", content)
+	}
+
+	info, err := s.StatObject(bucketName, "dir/object.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Key != "dir/object.json" || info.Size != 15 || info.ETag == "" || info.LastModified.IsZero() {
+		t.Errorf("StatObject = %+v, want key, size 15, ETag and modification time", info)
+	}
+	if info.ContentType != "application/json" {
+		t.Errorf("ContentType = '%s', want 'application/json'", info.ContentType)
+	}
+	if info.Metadata["owner"] != "test" {
+		t.Errorf("Metadata = %v, want owner=test", info.Metadata)
+	}
+
+	// overwrite
+	put(t, s, bucketName, "dir/object.json", "replaced")
+	if content := get(t, s, bucketName, "dir/object.json"); content != "replaced" {
+		t.Errorf("GetObject = '%s' after overwrite, want 'replaced'", content)
+	}
+}
+
+func testStoreRetrieve(t *testing.T, s storage.Storage, bucketName string) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.bin"), filepath.Join(dir, "dst.bin")
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.StoreObject(bucketName, "object.bin", src); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RetrieveObject(bucketName, "object.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	retrieved, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(retrieved, data) {
+		t.Errorf("retrieved %d bytes differ from the %d stored", len(retrieved), len(data))
+	}
+}
+
+// testContentEncoding checks that encoded content is read back as stored with WithRawContent, not decoded by the
+// provider or transport
+func testContentEncoding(t *testing.T, s storage.Storage, bucketName string) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(strings.Repeat("compressible ", 100)))
+	zw.Close()
+
+	put(t, s, bucketName, "object.txt", compressed.String(),
+		storage.WithContentType("text/plain"),
+		storage.WithContentEncoding("gzip"),
+	)
+	if content := get(t, s, bucketName, "object.txt", storage.WithRawContent()); content != compressed.String() {
+		t.Errorf("GetObject = %d bytes, want the %d stored gzip bytes", len(content), compressed.Len())
+	}
+
+	info, err := s.StatObject(bucketName, "object.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentEncoding != "gzip" || info.Size != int64(compressed.Len()) {
+		t.Errorf("StatObject = %+v, want content encoding 'gzip' and size %d", info, compressed.Len())
+	}
+}
+
+func testConditionalGet(t *testing.T, s storage.Storage, bucketName string) {
+	put(t, s, bucketName, "object", "data")
+	stat, err := s.StatObject(bucketName, "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.GetObject(bucketName, "object", storage.WithIfNoneMatch(stat.ETag))
+	if !errors.Is(err, storage.ErrNotModified) {
+		t.Errorf("GetObject(IfNoneMatch current ETag) = %v, want ErrNotModified", err)
+	}
+
+	put(t, s, bucketName, "object", "changed")
+	info := storage.ObjectInfo{}
+	rc, err := s.GetObject(bucketName, "object", storage.WithIfNoneMatch(stat.ETag), storage.WithObjectInfo(&info))
+	if err != nil {
+		t.Fatalf("GetObject(IfNoneMatch previous ETag): %v", err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil || string(content) != "changed" {
+		t.Errorf("GetObject(IfNoneMatch previous ETag) = '%s', %v, want 'changed'", content, err)
+	}
+
+	changed, err := s.StatObject(bucketName, "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ETag != changed.ETag || info.Size != 7 {
+		t.Errorf("ObjectInfo = %+v, want ETag %s and size 7", info, changed.ETag)
+	}
+}
+
+func testRange(t *testing.T, s storage.Storage, bucketName string) {
+	put(t, s, bucketName, "object", "0123456789")
+
+	for _, test := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{2, 3, "234"},
+		{7, 0, "789"},
+		{0, 4, "0123"},
+		{8, 10, "89"},
+		{0, 0, "0123456789"},
+	} {
+		info := storage.ObjectInfo{}
+		rc, err := s.GetObject(bucketName, "object", storage.WithRange(test.offset, test.length), storage.WithObjectInfo(&info))
+		if err != nil {
+			t.Errorf("GetObject(range %d, %d): %v", test.offset, test.length, err)
+			continue
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(content) != test.want {
+			t.Errorf("GetObject(range %d, %d) = '%s', %v, want '%s'", test.offset, test.length, content, err, test.want)
+		}
+		if info.Size != 10 {
+			t.Errorf("GetObject(range %d, %d) ObjectInfo size %d, want the object size 10", test.offset, test.length, info.Size)
+		}
+	}
+}
+
+// progressRecorder records the reports of a transfer
+type progressRecorder struct {
+	mu      sync.Mutex
+	reports []storage.Progress
+}
+
+func (r *progressRecorder) report(p storage.Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, p)
+}
+
+// check fails the test if the reports don't end with all size bytes transferred, or go backwards
+func (r *progressRecorder) check(t *testing.T, transfer string, size int64) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.reports) == 0 {
+		t.Errorf("%s: no progress reported", transfer)
+		return
+	}
+	for i := 1; i < len(r.reports); i++ {
+		if r.reports[i].Bytes < r.reports[i-1].Bytes {
+			t.Errorf("%s: progress went back from %d to %d bytes", transfer, r.reports[i-1].Bytes, r.reports[i].Bytes)
+		}
+	}
+	if last := r.reports[len(r.reports)-1]; last.Bytes != size || last.Total != size {
+		t.Errorf("%s: last progress %+v, want %d of %d bytes", transfer, last, size, size)
+	}
+}
+
+func testProgress(t *testing.T, s storage.Storage, bucketName string) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.bin"), filepath.Join(dir, "dst.bin")
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(data))
+
+	upload := &progressRecorder{}
+	if err := s.StoreObject(bucketName, "object.bin", src, storage.WithUploadProgress(upload.report)); err != nil {
+		t.Fatal(err)
+	}
+	upload.check(t, "StoreObject", size)
+
+	download := &progressRecorder{}
+	if err := s.RetrieveObject(bucketName, "object.bin", dst, storage.WithDownloadProgress(download.report)); err != nil {
+		t.Fatal(err)
+	}
+	download.check(t, "RetrieveObject", size)
+
+	get := &progressRecorder{}
+	rc, err := s.GetObject(bucketName, "object.bin", storage.WithDownloadProgress(get.report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+	get.check(t, "GetObject", size)
+}
+
+func testNotFound(t *testing.T, s storage.Storage, bucketName string) {
+	if _, err := s.StatObject(bucketName, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("StatObject(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetObject(bucketName, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetObject(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := s.RestoreStatus(bucketName, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("RestoreStatus(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func testWalk(t *testing.T, s storage.Storage, bucketName string) {
+	for _, key := range []string{"b/1", "a/2", "a/1", "c"} {
+		put(t, s, bucketName, key, key)
+	}
+
+	if found := keys(t, s, bucketName); strings.Join(found, ",") != "a/1,a/2,b/1,c" {
+		t.Errorf("walk = %v, want all keys in order", found)
+	}
+	if found := keys(t, s, bucketName, storage.WithPrefix("a/")); strings.Join(found, ",") != "a/1,a/2" {
+		t.Errorf("walk with prefix a/ = %v, want [a/1 a/2]", found)
+	}
+
+	visited := 0
+	err := s.WalkBucketContent(bucketName, func(object storage.ObjectInfo) error {
+		visited++
+		return storage.StopWalk
+	})
+	if err != nil || visited != 1 {
+		t.Errorf("walk stopped after %d objects with %v, want 1 and no error", visited, err)
+	}
+
+	listed, err := s.ListBucketContent(bucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 4 {
+		t.Errorf("ListBucketContent = %v, want 4 keys", listed)
+	}
+}
+
+func testWalkDelimiter(t *testing.T, s storage.Storage, bucketName string) {
+	for _, key := range []string{"a/1", "a/b/2", "a/b/3", "a/c/4", "d"} {
+		put(t, s, bucketName, key, key)
+	}
+
+	// the order of objects and prefixes differs between providers
+	delimited := func(opts ...storage.ListOption) string {
+		found := []string{}
+		err := s.WalkBucketContent(bucketName, func(object storage.ObjectInfo) error {
+			if object.IsPrefix {
+				found = append(found, object.Key+" (prefix)")
+			} else {
+				found = append(found, object.Key)
+			}
+			return nil
+		}, append(opts, storage.WithDelimiter("/"))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(found)
+		return strings.Join(found, ",")
+	}
+
+	if found, want := delimited(), "a/ (prefix),d"; found != want {
+		t.Errorf("walk with delimiter = %s, want %s", found, want)
+	}
+	if found, want := delimited(storage.WithPrefix("a/")), "a/1,a/b/ (prefix),a/c/ (prefix)"; found != want {
+		t.Errorf("walk with prefix a/ and delimiter = %s, want %s", found, want)
+	}
+}
+
+func testDeleteObjects(t *testing.T, s storage.Storage, bucketName string) {
+	put(t, s, bucketName, "a", "a")
+	put(t, s, bucketName, "b", "b")
+
+	report, err := s.DeleteObjects(bucketName, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Deleted()) != 2 {
+		t.Errorf("Deleted() = %v, want [a b]", report.Deleted())
+	}
+	if found := keys(t, s, bucketName); len(found) != 0 {
+		t.Errorf("bucket still holds %v", found)
+	}
+}
+
+func testDeleteByPrefix(t *testing.T, s storage.Storage, bucketName string) {
+	for _, key := range []string{"run-1/a", "run-1/b/c", "run-2/a"} {
+		put(t, s, bucketName, key, key)
+	}
+
+	report, err := storage.DeleteByPrefix(s, bucketName, "run-1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Deleted()) != 2 {
+		t.Errorf("Deleted() = %v, want the run-1 keys", report.Deleted())
+	}
+	if found := keys(t, s, bucketName); strings.Join(found, ",") != "run-2/a" {
+		t.Errorf("remaining keys = %v, want [run-2/a]", found)
+	}
+}
+
+func testForceDeleteBucket(t *testing.T, s storage.Storage, bucketName string) {
+	put(t, s, bucketName, "a", "a")
+	put(t, s, bucketName, "dir/b", "b")
+
+	if err := s.DeleteBucket(bucketName); err == nil {
+		t.Fatal("DeleteBucket of non-empty bucket should fail without WithForce")
+	}
+
+	deleted := 0
+	err := s.DeleteBucket(bucketName, storage.WithForce(), storage.WithDeleteProgress(func(n int) {
+		deleted = n
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("progress reported %d deleted objects, want 2", deleted)
+	}
+}
+
+func testStorageClass(t *testing.T, s storage.Storage, bucketName string) {
+	err := s.PutObject(bucketName, "object", strings.NewReader("data"), storage.WithStorageClass(storage.StorageClassInfrequentAccess))
+	if errors.Is(err, storage.ErrNotSupported) {
+		t.Skip("storage classes not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := s.StatObject(bucketName, "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.StorageClass != storage.StorageClassInfrequentAccess {
+		t.Errorf("StorageClass = '%s', want '%s'", info.StorageClass, storage.StorageClassInfrequentAccess)
+	}
+
+	if err := s.SetStorageClass(bucketName, "object", storage.StorageClassStandard); err != nil {
+		t.Fatal(err)
+	}
+	info, err = s.StatObject(bucketName, "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.StorageClass != storage.StorageClassStandard {
+		t.Errorf("StorageClass = '%s' after SetStorageClass, want '%s'", info.StorageClass, storage.StorageClassStandard)
+	}
+
+	status, err := s.RestoreStatus(bucketName, "object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Available {
+		t.Errorf("RestoreStatus = %+v, want available", status)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/google/uuid"
 	"github.com/pbreedt/cloud-connect/storage"
+	_ "github.com/pbreedt/cloud-connect/storage/all"
 )
 
 func main() {
@@ -38,9 +40,26 @@ func UseStorage(cloudStorage storage.Storage, bucketName string) {
 	}
 	log.Printf("Successfully created bucket\n")
 
+	// Remove the bucket and anything left in it, even if one of the steps below fails
+	defer func() {
+		err = cloudStorage.DeleteBucket(bucketName, storage.WithForce())
+		if err != nil {
+			log.Printf("Error deleting bucket(%s): %v\n", bucketName, err)
+			return
+		}
+		log.Printf("Successfully deleted bucket")
+	}()
+
+	err = useBucket(cloudStorage, bucketName)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+}
+
+func useBucket(cloudStorage storage.Storage, bucketName string) error {
 	buckets, err := cloudStorage.ListBuckets()
 	if err != nil {
-		log.Fatalf("Error listing buckets: %v\n", err)
+		return fmt.Errorf("listing buckets: %w", err)
 	}
 	for _, bucket := range buckets {
 		if bucket == bucketName {
@@ -50,13 +69,13 @@ func UseStorage(cloudStorage storage.Storage, bucketName string) {
 
 	err = cloudStorage.StoreObject(bucketName, "test-object-name", "../storage/test_data/testfile.txt")
 	if err != nil {
-		log.Fatalf("Error storing test object: %v\n", err)
+		return fmt.Errorf("storing test object: %w", err)
 	}
 	log.Printf("Successfully stored test object")
 
 	objects, err := cloudStorage.ListBucketContent(bucketName)
 	if err != nil {
-		log.Fatalf("Error listing bucket(%s) content: %v\n", bucketName, err)
+		return fmt.Errorf("listing bucket(%s) content: %w", bucketName, err)
 	}
 	for _, object := range objects {
 		if object == "test-object-name" {
@@ -66,14 +85,9 @@ func UseStorage(cloudStorage storage.Storage, bucketName string) {
 
 	err = cloudStorage.DeleteObject(bucketName, []string{"test-object-name"})
 	if err != nil {
-		log.Fatalf("Error deleting test object: %v\n", err)
+		return fmt.Errorf("deleting test object: %w", err)
 	}
 	log.Printf("Successfully deleted test object")
 
-	err = cloudStorage.DeleteBucket(bucketName)
-	if err != nil {
-		log.Fatalf("Error deleting bucket(%s): %v\n", bucketName, err)
-	}
-	log.Printf("Successfully deleted bucket")
-
+	return nil
 }